@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 
 	"github.com/gin-gonic/gin"
 
 	"convenienceStore/internal/handler"
 	"convenienceStore/internal/service"
+	"convenienceStore/pkg/auth"
 	"convenienceStore/pkg/config"
 	"convenienceStore/pkg/database"
 	"convenienceStore/pkg/logger"
 	"convenienceStore/pkg/payment"
+	"convenienceStore/pkg/region"
+	"convenienceStore/pkg/sms"
+	"convenienceStore/pkg/wechat"
 	"convenienceStore/routes"
 )
 
@@ -28,28 +34,140 @@ func main() {
 	}
 	defer db.Close()
 
-	paymentClient := payment.NewWeChatClient(cfg.Payment, appLogger)
+	miniProgram, err := wechat.NewClient(cfg.WeChat, appLogger)
+	if err != nil {
+		log.Fatalf("failed to init wechat mini program client: %v", err)
+	}
 
-	services := service.NewServices(service.Dependencies{
-		Config:  cfg,
-		Logger:  appLogger,
-		DB:      db,
-		Payment: paymentClient,
-	})
+	smsSender, err := sms.New(cfg.SMS, appLogger)
+	if err != nil {
+		log.Fatalf("failed to init sms sender: %v", err)
+	}
+
+	regions, err := region.New(cfg.Region)
+	if err != nil {
+		log.Fatalf("failed to load region data: %v", err)
+	}
+
+	tokens, err := auth.NewTokenManager(cfg.Auth)
+	if err != nil {
+		log.Fatalf("failed to init token manager: %v", err)
+	}
+
+	nonceStore := payment.WithNonceStore(database.NewNonceStore(db))
+	var (
+		wechatClient payment.WeChatClient
+		mockGateway  *payment.MockGateway
+	)
+	switch cfg.Payment.Mode {
+	case "", payment.ModeLive:
+		wechatClient, err = payment.NewWeChatClient(cfg.Payment, appLogger, nonceStore)
+	case payment.ModeMock:
+		appLogger.Printf("WARNING: payment.mode is mock, wechat pay is simulated and orders can be paid without real money")
+		mockGateway, wechatClient, err = payment.NewMockWeChat(cfg.Payment, fmt.Sprintf("http://127.0.0.1:%d", cfg.Server.Port), appLogger, nonceStore)
+	default:
+		err = fmt.Errorf("unknown payment mode %q", cfg.Payment.Mode)
+	}
+	if err != nil {
+		log.Fatalf("failed to init wechat pay client: %v", err)
+	}
+	providers := []payment.Provider{wechatClient}
+	if cfg.Payment.Alipay.Enabled {
+		alipayClient, err := payment.NewAlipayClient(cfg.Payment.Alipay, appLogger)
+		if err != nil {
+			log.Fatalf("failed to init alipay client: %v", err)
+		}
+		providers = append(providers, alipayClient)
+	}
+	if cfg.Payment.Offline.Enabled {
+		offlineProvider, err := payment.NewOfflineProvider(cfg.Payment.Offline)
+		if err != nil {
+			log.Fatalf("failed to init offline payment: %v", err)
+		}
+		providers = append(providers, offlineProvider)
+	}
+	paymentRegistry, err := payment.NewRegistry(providers...)
+	if err != nil {
+		log.Fatalf("failed to register payment providers: %v", err)
+	}
+
+	deps := service.Dependencies{
+		Config:   cfg,
+		Logger:   appLogger,
+		DB:       db,
+		Payments: paymentRegistry,
+		WeChat:   miniProgram,
+		SMS:      smsSender,
+		Tokens:   tokens,
+		Regions:  regions,
+	}
+	services := service.NewServices(deps)
+
+	if cfg.Staff.BootstrapUsername != "" {
+		if err := services.Staff.EnsureManager(context.Background(), cfg.Staff.BootstrapUsername, cfg.Staff.BootstrapPassword); err != nil {
+			log.Fatalf("failed to bootstrap manager account: %v", err)
+		}
+	}
+
+	orderTimeoutWorker, err := service.NewOrderTimeoutWorker(deps, services.Order)
+	if err != nil {
+		log.Fatalf("failed to init order timeout worker: %v", err)
+	}
+
+	paymentReconcileWorker, err := service.NewPaymentReconcileWorker(deps, services.Payment)
+	if err != nil {
+		log.Fatalf("failed to init payment reconcile worker: %v", err)
+	}
+
+	refundSettleWorker, err := service.NewRefundSettleWorker(deps, services.Refund)
+	if err != nil {
+		log.Fatalf("failed to init refund settle worker: %v", err)
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go orderTimeoutWorker.Run(workerCtx)
+	go paymentReconcileWorker.Run(workerCtx)
+	go refundSettleWorker.Run(workerCtx)
+
+	if cfg.Reconciliation.Enabled {
+		reconciliationWorker, err := service.NewReconciliationWorker(deps, services.Reconciliation)
+		if err != nil {
+			log.Fatalf("failed to init reconciliation worker: %v", err)
+		}
+		go reconciliationWorker.Run(workerCtx)
+	}
 
 	handlers := handler.NewHandlers(services)
 
 	engine := gin.Default()
+	// 未配置代理时传入 nil，客户端 IP 取连接对端地址，伪造的 X-Forwarded-For 不会绕过按 IP 限流。
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("invalid server trusted_proxies: %v", err)
+	}
 	routes.RegisterRoutes(engine, routes.HandlerSet{
-		User:         handlers.User,
-		Product:      handlers.Product,
-		AdminProduct: handlers.AdminProduct,
-		Upload:       handlers.Upload,
-		Cart:         handlers.Cart,
-		Order:        handlers.Order,
-		Payment:      handlers.Payment,
-		Delivery:     handlers.Delivery,
+		Auth:           handlers.Auth,
+		User:           handlers.User,
+		Account:        handlers.Account,
+		Phone:          handlers.Phone,
+		Loyalty:        handlers.Loyalty,
+		Region:         handlers.Region,
+		Staff:          handlers.Staff,
+		Product:        handlers.Product,
+		AdminProduct:   handlers.AdminProduct,
+		Upload:         handlers.Upload,
+		Cart:           handlers.Cart,
+		Order:          handlers.Order,
+		AdminOrder:     handlers.AdminOrder,
+		Payment:        handlers.Payment,
+		AdminPayment:   handlers.AdminPayment,
+		Refund:         handlers.Refund,
+		Delivery:       handlers.Delivery,
+		Reconciliation: handlers.Reconciliation,
 	})
+	if mockGateway != nil {
+		routes.RegisterMockPayment(engine, mockGateway.Handler())
+	}
 
 	if err := engine.Run(cfg.Server.Address()); err != nil {
 		appLogger.Fatalf("failed to start server: %v", err)
@@ -0,0 +1,4 @@
+-- Indexes for the payment timeout sweep and order listing
+ALTER TABLE orders
+    ADD INDEX idx_orders_status_created (status, created_at),
+    ADD INDEX idx_orders_user_created (user_id, created_at);
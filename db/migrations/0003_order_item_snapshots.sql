@@ -0,0 +1,17 @@
+-- Order items keep a snapshot of the product and no longer reference products,
+-- so products with order history can be deleted.
+ALTER TABLE order_items DROP FOREIGN KEY fk_order_items_products;
+ALTER TABLE order_items RENAME INDEX fk_order_items_products TO idx_order_items_product;
+
+ALTER TABLE order_items
+    ADD COLUMN product_name VARCHAR(128) NOT NULL DEFAULT '' AFTER price,
+    ADD COLUMN product_image VARCHAR(255) DEFAULT NULL AFTER product_name,
+    ADD COLUMN product_tags JSON NULL AFTER product_image;
+
+-- Backfill snapshots of existing items from the current product details.
+UPDATE order_items oi
+JOIN products p ON p.id = oi.product_id
+SET oi.product_name = p.name,
+    oi.product_image = JSON_UNQUOTE(JSON_EXTRACT(p.images, '$[0]')),
+    oi.product_tags = p.tags
+WHERE oi.product_name = '';
@@ -0,0 +1,17 @@
+-- Payment notifications, deduplicated by provider transaction id
+CREATE TABLE IF NOT EXISTS payment_transactions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    transaction_id VARCHAR(64) NOT NULL,
+    order_id VARCHAR(64) NOT NULL,
+    trade_state VARCHAR(32) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    payer_open_id VARCHAR(128) DEFAULT NULL,
+    status VARCHAR(32) NOT NULL,
+    note VARCHAR(255) DEFAULT NULL,
+    paid_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_payment_transactions_provider_txn (provider, transaction_id),
+    INDEX idx_payment_transactions_order (order_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
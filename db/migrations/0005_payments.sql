@@ -0,0 +1,16 @@
+-- Payment attempts
+CREATE TABLE IF NOT EXISTS payments (
+    id VARCHAR(64) PRIMARY KEY,
+    order_id VARCHAR(64) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    prepay_id VARCHAR(128) DEFAULT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    transaction_id VARCHAR(64) DEFAULT NULL,
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    INDEX idx_payments_order (order_id, created_at),
+    INDEX idx_payments_status_created (status, created_at),
+    CONSTRAINT fk_payments_orders FOREIGN KEY (order_id) REFERENCES orders(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
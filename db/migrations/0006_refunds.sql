@@ -0,0 +1,26 @@
+-- Refunds and refunded items
+CREATE TABLE IF NOT EXISTS refunds (
+    id VARCHAR(64) PRIMARY KEY,
+    order_id VARCHAR(64) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    provider_refund_id VARCHAR(64) DEFAULT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    reason VARCHAR(255) DEFAULT NULL,
+    previous_status VARCHAR(32) NOT NULL,
+    restock BOOLEAN NOT NULL DEFAULT FALSE,
+    completed_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    INDEX idx_refunds_order (order_id, created_at),
+    CONSTRAINT fk_refunds_orders FOREIGN KEY (order_id) REFERENCES orders(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS refund_items (
+    refund_id VARCHAR(64) NOT NULL,
+    product_id VARCHAR(64) NOT NULL,
+    quantity INT NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    PRIMARY KEY (refund_id, product_id),
+    CONSTRAINT fk_refund_items_refunds FOREIGN KEY (refund_id) REFERENCES refunds(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,34 @@
+-- Daily payment reconciliation reports
+CREATE TABLE IF NOT EXISTS reconciliation_reports (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    bill_date DATE NOT NULL,
+    source VARCHAR(16) NOT NULL,
+    remote_count INT NOT NULL,
+    remote_amount DECIMAL(14,2) NOT NULL,
+    local_count INT NOT NULL,
+    local_amount DECIMAL(14,2) NOT NULL,
+    matched_count INT NOT NULL,
+    refund_remote_count INT NOT NULL DEFAULT 0,
+    refund_remote_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    refund_local_count INT NOT NULL DEFAULT 0,
+    refund_local_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    refund_matched_count INT NOT NULL DEFAULT 0,
+    discrepancy_count INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_reconciliation_reports_provider_date (provider, bill_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    report_id BIGINT UNSIGNED NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    order_id VARCHAR(64) NOT NULL,
+    transaction_id VARCHAR(64) NOT NULL DEFAULT '',
+    refund_id VARCHAR(64) NOT NULL DEFAULT '',
+    local_amount DECIMAL(10,2) NOT NULL,
+    remote_amount DECIMAL(10,2) NOT NULL,
+    note VARCHAR(255) DEFAULT NULL,
+    INDEX idx_reconciliation_discrepancies_report (report_id),
+    CONSTRAINT fk_reconciliation_discrepancies_reports FOREIGN KEY (report_id) REFERENCES reconciliation_reports(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+-- WeChat union id and session key from code2session
+ALTER TABLE users
+    ADD COLUMN wechat_union_id VARCHAR(128) DEFAULT NULL AFTER wechat_open_id,
+    ADD COLUMN session_key VARCHAR(128) DEFAULT NULL AFTER wechat_union_id,
+    ADD INDEX idx_users_union (wechat_union_id);
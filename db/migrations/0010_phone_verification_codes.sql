@@ -0,0 +1,15 @@
+-- Phone binding verification codes
+CREATE TABLE IF NOT EXISTS phone_verification_codes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    phone VARCHAR(32) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL,
+    INDEX idx_phone_codes_phone (phone, created_at),
+    INDEX idx_phone_codes_ip (client_ip, created_at),
+    INDEX idx_phone_codes_user (user_id, phone)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+-- Account deletion marker
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL AFTER default_address_id;
@@ -0,0 +1,5 @@
+-- Region codes of addresses. Existing addresses keep empty codes until they are edited.
+ALTER TABLE addresses
+    ADD COLUMN province_code VARCHAR(12) NOT NULL DEFAULT '' AFTER province,
+    ADD COLUMN city_code VARCHAR(12) NOT NULL DEFAULT '' AFTER city,
+    ADD COLUMN district_code VARCHAR(12) NOT NULL DEFAULT '' AFTER district;
@@ -0,0 +1,31 @@
+-- Member accounts, points ledger and checkout redemption
+ALTER TABLE orders
+    ADD COLUMN points_used BIGINT NOT NULL DEFAULT 0 AFTER total,
+    ADD COLUMN points_discount DECIMAL(10,2) NOT NULL DEFAULT 0 AFTER points_used;
+
+CREATE TABLE IF NOT EXISTS member_accounts (
+    user_id VARCHAR(64) PRIMARY KEY,
+    balance BIGINT NOT NULL DEFAULT 0,
+    tier_points BIGINT NOT NULL DEFAULT 0,
+    tier VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CONSTRAINT fk_member_accounts_users FOREIGN KEY (user_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS points_ledger (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    order_id VARCHAR(64) NOT NULL,
+    refund_id VARCHAR(64) NOT NULL DEFAULT '',
+    type VARCHAR(32) NOT NULL,
+    points BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    base_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    reason VARCHAR(255) DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_points_ledger_entry (order_id, type, refund_id),
+    INDEX idx_points_ledger_user (user_id, id),
+    CONSTRAINT fk_points_ledger_users FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_points_ledger_orders FOREIGN KEY (order_id) REFERENCES orders(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
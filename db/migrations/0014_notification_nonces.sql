@@ -0,0 +1,9 @@
+-- Payment notification nonces, used to reject replayed callbacks
+CREATE TABLE IF NOT EXISTS notification_nonces (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    nonce VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_notification_nonces_nonce (nonce),
+    INDEX idx_notification_nonces_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+-- Lock rows that serialize verification code requests per phone number and client IP
+CREATE TABLE IF NOT EXISTS phone_verification_locks (
+    lock_key VARCHAR(96) PRIMARY KEY
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+-- Last time the payment timeout sweep skipped an order, used to rotate skipped orders to the back
+ALTER TABLE orders ADD COLUMN last_sweep_attempt_at TIMESTAMP NULL DEFAULT NULL AFTER address_id;
@@ -0,0 +1,10 @@
+-- Staff login attempts, used to throttle failed logins per username and client IP
+CREATE TABLE IF NOT EXISTS staff_login_attempts (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL,
+    INDEX idx_staff_login_attempts_username (username, created_at),
+    INDEX idx_staff_login_attempts_ip (client_ip, created_at),
+    INDEX idx_staff_login_attempts_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -1,12 +1,16 @@
 CREATE TABLE IF NOT EXISTS users (
     id VARCHAR(64) PRIMARY KEY,
     wechat_open_id VARCHAR(128) NOT NULL UNIQUE,
+    wechat_union_id VARCHAR(128) DEFAULT NULL,
+    session_key VARCHAR(128) DEFAULT NULL,
     nickname VARCHAR(128) NOT NULL,
     avatar_url VARCHAR(255) NOT NULL,
     phone VARCHAR(32) DEFAULT NULL,
     default_address_id VARCHAR(64) DEFAULT NULL,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_users_union (wechat_union_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 CREATE TABLE IF NOT EXISTS addresses (
@@ -15,8 +19,11 @@ CREATE TABLE IF NOT EXISTS addresses (
     recipient VARCHAR(128) NOT NULL,
     phone VARCHAR(32) NOT NULL,
     province VARCHAR(64) NOT NULL,
+    province_code VARCHAR(12) NOT NULL DEFAULT '',
     city VARCHAR(64) NOT NULL,
+    city_code VARCHAR(12) NOT NULL DEFAULT '',
     district VARCHAR(64) NOT NULL,
+    district_code VARCHAR(12) NOT NULL DEFAULT '',
     detail VARCHAR(255) NOT NULL,
     postal_code VARCHAR(16) DEFAULT NULL,
     is_default BOOLEAN NOT NULL DEFAULT FALSE,
@@ -56,9 +63,14 @@ CREATE TABLE IF NOT EXISTS orders (
     user_id VARCHAR(64) NOT NULL,
     status VARCHAR(32) NOT NULL,
     total DECIMAL(10,2) NOT NULL,
+    points_used BIGINT NOT NULL DEFAULT 0,
+    points_discount DECIMAL(10,2) NOT NULL DEFAULT 0,
     address_id VARCHAR(64) DEFAULT NULL,
+    last_sweep_attempt_at TIMESTAMP NULL DEFAULT NULL,
     created_at TIMESTAMP NOT NULL,
     updated_at TIMESTAMP NOT NULL,
+    INDEX idx_orders_status_created (status, created_at),
+    INDEX idx_orders_user_created (user_id, created_at),
     CONSTRAINT fk_orders_users FOREIGN KEY (user_id) REFERENCES users(id),
     CONSTRAINT fk_orders_addresses FOREIGN KEY (address_id) REFERENCES addresses(id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -69,6 +81,204 @@ CREATE TABLE IF NOT EXISTS order_items (
     product_id VARCHAR(64) NOT NULL,
     quantity INT NOT NULL,
     price DECIMAL(10,2) NOT NULL,
-    CONSTRAINT fk_order_items_orders FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
-    CONSTRAINT fk_order_items_products FOREIGN KEY (product_id) REFERENCES products(id)
+    product_name VARCHAR(128) NOT NULL DEFAULT '',
+    product_image VARCHAR(255) DEFAULT NULL,
+    product_tags JSON NULL,
+    INDEX idx_order_items_product (product_id),
+    CONSTRAINT fk_order_items_orders FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS order_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    order_id VARCHAR(64) NOT NULL,
+    from_status VARCHAR(32) DEFAULT NULL,
+    to_status VARCHAR(32) NOT NULL,
+    actor VARCHAR(64) DEFAULT NULL,
+    source VARCHAR(32) NOT NULL,
+    reason VARCHAR(255) DEFAULT NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_order_events_order (order_id, id),
+    CONSTRAINT fk_order_events_orders FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS payment_transactions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    transaction_id VARCHAR(64) NOT NULL,
+    order_id VARCHAR(64) NOT NULL,
+    trade_state VARCHAR(32) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    payer_open_id VARCHAR(128) DEFAULT NULL,
+    status VARCHAR(32) NOT NULL,
+    note VARCHAR(255) DEFAULT NULL,
+    paid_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_payment_transactions_provider_txn (provider, transaction_id),
+    INDEX idx_payment_transactions_order (order_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS notification_nonces (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    nonce VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_notification_nonces_nonce (nonce),
+    INDEX idx_notification_nonces_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS payments (
+    id VARCHAR(64) PRIMARY KEY,
+    order_id VARCHAR(64) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    prepay_id VARCHAR(128) DEFAULT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    transaction_id VARCHAR(64) DEFAULT NULL,
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    INDEX idx_payments_order (order_id, created_at),
+    INDEX idx_payments_status_created (status, created_at),
+    CONSTRAINT fk_payments_orders FOREIGN KEY (order_id) REFERENCES orders(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id VARCHAR(64) PRIMARY KEY,
+    order_id VARCHAR(64) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    provider_refund_id VARCHAR(64) DEFAULT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    reason VARCHAR(255) DEFAULT NULL,
+    previous_status VARCHAR(32) NOT NULL,
+    restock BOOLEAN NOT NULL DEFAULT FALSE,
+    completed_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    INDEX idx_refunds_order (order_id, created_at),
+    CONSTRAINT fk_refunds_orders FOREIGN KEY (order_id) REFERENCES orders(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS refund_items (
+    refund_id VARCHAR(64) NOT NULL,
+    product_id VARCHAR(64) NOT NULL,
+    quantity INT NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    PRIMARY KEY (refund_id, product_id),
+    CONSTRAINT fk_refund_items_refunds FOREIGN KEY (refund_id) REFERENCES refunds(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS reconciliation_reports (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    bill_date DATE NOT NULL,
+    source VARCHAR(16) NOT NULL,
+    remote_count INT NOT NULL,
+    remote_amount DECIMAL(14,2) NOT NULL,
+    local_count INT NOT NULL,
+    local_amount DECIMAL(14,2) NOT NULL,
+    matched_count INT NOT NULL,
+    refund_remote_count INT NOT NULL DEFAULT 0,
+    refund_remote_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    refund_local_count INT NOT NULL DEFAULT 0,
+    refund_local_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    refund_matched_count INT NOT NULL DEFAULT 0,
+    discrepancy_count INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_reconciliation_reports_provider_date (provider, bill_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    report_id BIGINT UNSIGNED NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    order_id VARCHAR(64) NOT NULL,
+    transaction_id VARCHAR(64) NOT NULL DEFAULT '',
+    refund_id VARCHAR(64) NOT NULL DEFAULT '',
+    local_amount DECIMAL(10,2) NOT NULL,
+    remote_amount DECIMAL(10,2) NOT NULL,
+    note VARCHAR(255) DEFAULT NULL,
+    INDEX idx_reconciliation_discrepancies_report (report_id),
+    CONSTRAINT fk_reconciliation_discrepancies_reports FOREIGN KEY (report_id) REFERENCES reconciliation_reports(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS staff (
+    id VARCHAR(64) PRIMARY KEY,
+    username VARCHAR(64) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'ACTIVE',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS staff_api_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    staff_id VARCHAR(64) NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_staff_api_keys_staff (staff_id),
+    CONSTRAINT fk_staff_api_keys_staff FOREIGN KEY (staff_id) REFERENCES staff(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS staff_login_attempts (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL,
+    INDEX idx_staff_login_attempts_username (username, created_at),
+    INDEX idx_staff_login_attempts_ip (client_ip, created_at),
+    INDEX idx_staff_login_attempts_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS phone_verification_codes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    phone VARCHAR(32) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    client_ip VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL,
+    INDEX idx_phone_codes_phone (phone, created_at),
+    INDEX idx_phone_codes_ip (client_ip, created_at),
+    INDEX idx_phone_codes_user (user_id, phone)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS phone_verification_locks (
+    lock_key VARCHAR(96) PRIMARY KEY
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS member_accounts (
+    user_id VARCHAR(64) PRIMARY KEY,
+    balance BIGINT NOT NULL DEFAULT 0,
+    tier_points BIGINT NOT NULL DEFAULT 0,
+    tier VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CONSTRAINT fk_member_accounts_users FOREIGN KEY (user_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS points_ledger (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    order_id VARCHAR(64) NOT NULL,
+    refund_id VARCHAR(64) NOT NULL DEFAULT '',
+    type VARCHAR(32) NOT NULL,
+    points BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    base_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    reason VARCHAR(255) DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_points_ledger_entry (order_id, type, refund_id),
+    INDEX idx_points_ledger_user (user_id, id),
+    CONSTRAINT fk_points_ledger_users FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_points_ledger_orders FOREIGN KEY (order_id) REFERENCES orders(id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
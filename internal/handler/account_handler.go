@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/internal/service"
+)
+
+// AccountHandler 提供个人信息导出与账号注销接口。
+type AccountHandler struct {
+	service service.AccountService
+}
+
+// NewAccountHandler 构建 AccountHandler 实例。
+func NewAccountHandler(service service.AccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+// ExportData 导出当前用户的个人信息，默认为 ZIP 压缩包，format=json 时直接返回 JSON。
+func (h *AccountHandler) ExportData(c *gin.Context) {
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid format value: %s", format)})
+		return
+	}
+
+	export, err := h.service.ExportData(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("account-export-%s-%s", export.Profile.ID, export.ExportedAt.Format("20060102150405"))
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	c.Status(http.StatusOK)
+	if err := writeExportArchive(c.Writer, export); err != nil {
+		// 响应头已发出，只能记录错误并中断连接。
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// DeleteAccount 注销当前用户账号，存在未完成订单时拒绝。
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	if err := h.service.DeleteAccount(c.Request.Context(), currentUserID(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// writeExportArchive 将导出内容按类别写为压缩包中的多个 JSON 文件。
+func writeExportArchive(w http.ResponseWriter, export *model.AccountExport) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", gin.H{"exported_at": export.ExportedAt, "profile": export.Profile}},
+		{"addresses.json", export.Addresses},
+		{"cart_items.json", export.CartItems},
+		{"orders.json", export.Orders},
+		{"payments.json", export.Payments},
+		{"refunds.json", export.Refunds},
+		{"points.json", gin.H{"member": export.Member, "ledger": export.PointsLedger}},
+	}
+	for _, file := range files {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
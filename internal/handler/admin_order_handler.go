@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/service"
+)
+
+// AdminOrderHandler 为门店员工提供订单管理接口。
+type AdminOrderHandler struct {
+	service service.OrderService
+	refunds service.RefundService
+}
+
+// NewAdminOrderHandler 构建 AdminOrderHandler 实例。
+func NewAdminOrderHandler(service service.OrderService, refunds service.RefundService) *AdminOrderHandler {
+	return &AdminOrderHandler{service: service, refunds: refunds}
+}
+
+// ListOrders 按条件分页查询全部顾客的订单，user_id 可选。
+func (h *AdminOrderHandler) ListOrders(c *gin.Context) {
+	query, err := parseOrderListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.ListOrders(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// RefundOrder 由门店员工为订单发起全额或按商品部分退款，无需审核，订单事件记为该员工的后台操作。
+// goods_returned 表示已发货的商品已退回入库，未发货订单始终归还库存。
+func (h *AdminOrderHandler) RefundOrder(c *gin.Context) {
+	var req struct {
+		refundRequestBody
+		GoodsReturned bool `json:"goods_returned"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.refunds.RefundOrder(c.Request.Context(), service.RefundRequest{
+		OrderID: c.Param("id"),
+		Items:   req.Items,
+		Reason:  req.Reason,
+	}, req.GoodsReturned)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
+
+// ListPendingRefunds 返回等待审核的退款申请。
+func (h *AdminOrderHandler) ListPendingRefunds(c *gin.Context) {
+	refunds, err := h.refunds.ListPendingRefunds(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"refunds": refunds})
+}
+
+// ApproveRefund 审核通过退款申请并提交渠道退款，goods_returned 表示员工已确认商品退回入库。
+func (h *AdminOrderHandler) ApproveRefund(c *gin.Context) {
+	var req struct {
+		GoodsReturned bool `json:"goods_returned"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.refunds.ApproveRefund(c.Request.Context(), c.Param("id"), req.GoodsReturned)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
+
+// RejectRefund 驳回退款申请，订单回到申请前的状态。
+func (h *AdminOrderHandler) RejectRefund(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.refunds.RejectRefund(c.Request.Context(), c.Param("id"), req.Reason)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
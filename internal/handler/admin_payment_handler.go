@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/internal/service"
+)
+
+// AdminPaymentHandler 为门店员工提供支付记录检索接口。
+type AdminPaymentHandler struct {
+	service service.PaymentService
+}
+
+// NewAdminPaymentHandler 构建 AdminPaymentHandler 实例。
+func NewAdminPaymentHandler(service service.PaymentService) *AdminPaymentHandler {
+	return &AdminPaymentHandler{service: service}
+}
+
+// SearchPayments 按订单、渠道、状态、交易号与时间范围分页检索支付记录。
+func (h *AdminPaymentHandler) SearchPayments(c *gin.Context) {
+	query, err := parsePaymentSearchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.SearchPayments(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ConfirmOfflinePayment 由店员确认订单的货到付款或到店付款已收讫。
+func (h *AdminPaymentHandler) ConfirmOfflinePayment(c *gin.Context) {
+	orderID := c.Param("id")
+	if err := h.service.ConfirmOfflinePayment(c.Request.Context(), orderID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order_id": orderID, "status": model.OrderStatusPaid})
+}
+
+// parsePaymentSearchQuery 从查询参数解析支付检索条件，status 支持逗号分隔或重复传参。
+func parsePaymentSearchQuery(c *gin.Context) (service.PaymentSearchQuery, error) {
+	query := service.PaymentSearchQuery{
+		OrderID:       c.Query("order_id"),
+		Provider:      c.Query("provider"),
+		TransactionID: c.Query("transaction_id"),
+		Cursor:        c.Query("cursor"),
+	}
+
+	for _, value := range c.QueryArray("status") {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				query.Statuses = append(query.Statuses, model.PaymentStatus(strings.ToUpper(status)))
+			}
+		}
+	}
+
+	if value := c.Query("created_from"); value != "" {
+		createdFrom, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid created_from: %w", err)
+		}
+		query.CreatedFrom = createdFrom
+	}
+	if value := c.Query("created_to"); value != "" {
+		createdTo, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid created_to: %w", err)
+		}
+		query.CreatedTo = createdTo
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return query, fmt.Errorf("invalid limit value: %s", value)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
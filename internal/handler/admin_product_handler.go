@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"convenienceStore/internal/model"
 	"convenienceStore/internal/service"
 )
 
@@ -19,13 +20,13 @@ func NewAdminProductHandler(service service.AdminProductService) *AdminProductHa
 }
 
 type adminProductRequest struct {
-	Name        string   `json:"name" binding:"required"`
-	Description string   `json:"description"`
-	Price       float64  `json:"price" binding:"required"`
-	Stock       int      `json:"stock" binding:"required"`
-	Tags        []string `json:"tags"`
-	Images      []string `json:"images"`
-	IsActive    *bool    `json:"is_active"`
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	Price       model.Money `json:"price" binding:"required"`
+	Stock       int         `json:"stock" binding:"required"`
+	Tags        []string    `json:"tags"`
+	Images      []string    `json:"images"`
+	IsActive    *bool       `json:"is_active"`
 }
 
 // ListProducts returns products for the management console.
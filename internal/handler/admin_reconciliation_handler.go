@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/internal/service"
+	"convenienceStore/pkg/payment"
+)
+
+// AdminReconciliationHandler 为财务人员提供账单对账与差异报告接口。
+type AdminReconciliationHandler struct {
+	service service.ReconciliationService
+}
+
+// NewAdminReconciliationHandler 构建 AdminReconciliationHandler 实例。
+func NewAdminReconciliationHandler(service service.ReconciliationService) *AdminReconciliationHandler {
+	return &AdminReconciliationHandler{service: service}
+}
+
+type reconcileRequest struct {
+	BillDate string `json:"bill_date" binding:"required"`
+}
+
+// ListReports 按账单日倒序列出对账报告。
+func (h *AdminReconciliationHandler) ListReports(c *gin.Context) {
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid limit value: %s", value)})
+			return
+		}
+		limit = parsed
+	}
+
+	reports, err := h.service.ListReports(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+// Reconcile 下载指定账单日的渠道账单并重新对账。
+func (h *AdminReconciliationHandler) Reconcile(c *gin.Context) {
+	var req reconcileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	billDate, err := parseBillDate(req.BillDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.ReconcileDay(c.Request.Context(), billDate)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ImportBill 使用上传的账单文件（multipart 字段 file）对账，账单日由表单字段 bill_date 指定。
+func (h *AdminReconciliationHandler) ImportBill(c *gin.Context) {
+	billDate, err := parseBillDate(c.PostForm("bill_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file field is required"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer src.Close()
+
+	report, err := h.service.ImportBill(c.Request.Context(), billDate, src)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetReport 返回对账报告及差异明细。
+func (h *AdminReconciliationHandler) GetReport(c *gin.Context) {
+	report, ok := h.loadReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportReport 以 CSV 导出对账报告的差异明细。
+func (h *AdminReconciliationHandler) ExportReport(c *gin.Context) {
+	report, ok := h.loadReport(c)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("reconciliation-%s-%s.csv", report.Provider, report.BillDate)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// 写入 BOM 以便表格软件按 UTF-8 打开。
+	c.Writer.WriteString("\ufeff")
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"type", "order_id", "transaction_id", "refund_id", "local_amount", "remote_amount", "note"})
+	for _, d := range report.Discrepancies {
+		writer.Write([]string{string(d.Type), d.OrderID, d.TransactionID, d.RefundID, d.LocalAmount.String(), d.RemoteAmount.String(), d.Note})
+	}
+	writer.Flush()
+}
+
+func (h *AdminReconciliationHandler) loadReport(c *gin.Context) (*model.ReconciliationReport, bool) {
+	reportID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || reportID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return nil, false
+	}
+
+	report, err := h.service.GetReport(c.Request.Context(), reportID)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	return report, true
+}
+
+// parseBillDate 按账单时区解析 YYYY-MM-DD 格式的账单日，不接受今天及以后的日期。
+func parseBillDate(value string) (time.Time, error) {
+	billDate, err := time.ParseInLocation(time.DateOnly, value, payment.BillLocation)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid bill_date %q, expected YYYY-MM-DD", value)
+	}
+	now := time.Now().In(payment.BillLocation)
+	if !billDate.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, payment.BillLocation)) {
+		return time.Time{}, fmt.Errorf("bill_date %s must be before today", value)
+	}
+	return billDate, nil
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/internal/service"
+)
+
+// userIDContextKey 是已认证顾客 ID 在 gin.Context 中的键。
+const userIDContextKey = "auth.user_id"
+
+// AuthHandler 提供令牌刷新接口与顾客身份认证中间件。
+type AuthHandler struct {
+	service service.AuthService
+}
+
+// NewAuthHandler 构建 AuthHandler 实例。
+func NewAuthHandler(service service.AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+// RequireUser 校验 Authorization: Bearer 访问令牌，将顾客身份写入 gin.Context，
+// 并作为操作主体写入请求上下文供服务层记录审计信息。
+func (h *AuthHandler) RequireUser(c *gin.Context) {
+	scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		respondError(c, model.NewError(model.ErrCodeUnauthorized, "missing bearer token"))
+		c.Abort()
+		return
+	}
+
+	userID, err := h.service.Authenticate(c.Request.Context(), strings.TrimSpace(token))
+	if err != nil {
+		respondError(c, err)
+		c.Abort()
+		return
+	}
+
+	c.Set(userIDContextKey, userID)
+	ctx := service.WithOperator(c.Request.Context(), service.Operator{Actor: userID, Source: model.OrderEventSourceCustomer})
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// RefreshToken 用刷新令牌换取新的访问令牌与刷新令牌。
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.RefreshTokens(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// currentUserID 返回 RequireUser 写入的顾客 ID。
+func currentUserID(c *gin.Context) string {
+	return c.GetString(userIDContextKey)
+}
@@ -19,10 +19,9 @@ func NewCartHandler(service service.CartService) *CartHandler {
 	return &CartHandler{service: service}
 }
 
-// ListItems 返回用户的购物车内容。
+// ListItems 返回当前用户的购物车内容。
 func (h *CartHandler) ListItems(c *gin.Context) {
-	userID := c.Query("user_id")
-	items, err := h.service.ListItems(c.Request.Context(), userID)
+	items, err := h.service.ListItems(c.Request.Context(), currentUserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -38,6 +37,7 @@ func (h *CartHandler) AddItem(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	item.UserID = currentUserID(c)
 
 	if err := h.service.AddItem(c.Request.Context(), &item); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -55,6 +55,7 @@ func (h *CartHandler) UpdateItem(c *gin.Context) {
 		return
 	}
 	item.ID = c.Param("id")
+	item.UserID = currentUserID(c)
 
 	if err := h.service.UpdateItem(c.Request.Context(), &item); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -66,7 +67,7 @@ func (h *CartHandler) UpdateItem(c *gin.Context) {
 
 // RemoveItem 从购物车移除商品。
 func (h *CartHandler) RemoveItem(c *gin.Context) {
-	if err := h.service.RemoveItem(c.Request.Context(), c.Param("id")); err != nil {
+	if err := h.service.RemoveItem(c.Request.Context(), currentUserID(c), c.Param("id")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -48,7 +48,7 @@ func (h *DeliveryHandler) ShipOrder(c *gin.Context) {
 	}
 
 	if err := h.service.ShipOrder(c.Request.Context(), req.OrderID, req.Carrier, req.TrackNo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
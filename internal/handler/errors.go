@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/model"
+)
+
+// errorStatus 将领域错误码映射为 HTTP 状态码。
+var errorStatus = map[model.ErrorCode]int{
+	model.ErrCodeInvalidParameter:  http.StatusBadRequest,
+	model.ErrCodeUserNotFound:      http.StatusNotFound,
+	model.ErrCodeAddressNotFound:   http.StatusNotFound,
+	model.ErrCodeProductNotFound:   http.StatusNotFound,
+	model.ErrCodeInventoryShort:    http.StatusConflict,
+	model.ErrCodeCartEmpty:         http.StatusBadRequest,
+	model.ErrCodeOrderNotFound:     http.StatusNotFound,
+	model.ErrCodePaymentFailed:     http.StatusBadGateway,
+	model.ErrCodeInvalidTransition: http.StatusConflict,
+	model.ErrCodeProductInactive:   http.StatusConflict,
+	model.ErrCodePriceChanged:      http.StatusConflict,
+	model.ErrCodeRefundExceeded:    http.StatusConflict,
+	model.ErrCodeRefundNotFound:    http.StatusNotFound,
+	model.ErrCodeReportNotFound:    http.StatusNotFound,
+	model.ErrCodeUnauthorized:      http.StatusUnauthorized,
+	model.ErrCodeForbidden:         http.StatusForbidden,
+	model.ErrCodeStaffNotFound:     http.StatusNotFound,
+	model.ErrCodeStaffExists:       http.StatusConflict,
+	model.ErrCodeAPIKeyNotFound:    http.StatusNotFound,
+	model.ErrCodeTooManyRequests:   http.StatusTooManyRequests,
+	model.ErrCodeInvalidCode:       http.StatusBadRequest,
+	model.ErrCodePhoneInUse:        http.StatusConflict,
+	model.ErrCodeOpenOrders:        http.StatusConflict,
+	model.ErrCodeRegionNotFound:    http.StatusNotFound,
+	model.ErrCodePointsShort:       http.StatusConflict,
+}
+
+// respondError 输出统一的错误响应，未识别的错误按 500 处理。
+func respondError(c *gin.Context, err error) {
+	var domainErr *model.Error
+	if !errors.As(err, &domainErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, ok := errorStatus[domainErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	body := gin.H{"error": domainErr.Message, "code": domainErr.Code}
+	if domainErr.Details != nil {
+		body["details"] = domainErr.Details
+	}
+	c.JSON(status, body)
+}
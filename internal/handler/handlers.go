@@ -4,26 +4,46 @@ import "convenienceStore/internal/service"
 
 // Handlers 汇集各领域的 HTTP 处理器。
 type Handlers struct {
-	User         *UserHandler
-	Product      *ProductHandler
-	AdminProduct *AdminProductHandler
-	Upload       *UploadHandler
-	Cart         *CartHandler
-	Order        *OrderHandler
-	Payment      *PaymentHandler
-	Delivery     *DeliveryHandler
+	Auth           *AuthHandler
+	User           *UserHandler
+	Account        *AccountHandler
+	Phone          *PhoneHandler
+	Loyalty        *LoyaltyHandler
+	Region         *RegionHandler
+	Staff          *StaffHandler
+	Product        *ProductHandler
+	AdminProduct   *AdminProductHandler
+	Upload         *UploadHandler
+	Cart           *CartHandler
+	Order          *OrderHandler
+	AdminOrder     *AdminOrderHandler
+	Payment        *PaymentHandler
+	AdminPayment   *AdminPaymentHandler
+	Refund         *RefundHandler
+	Delivery       *DeliveryHandler
+	Reconciliation *AdminReconciliationHandler
 }
 
 // NewHandlers 基于服务层依赖初始化所有处理器实例。
 func NewHandlers(services service.Services) Handlers {
 	return Handlers{
-		User:         NewUserHandler(services.User),
-		Product:      NewProductHandler(services.Product),
-		AdminProduct: NewAdminProductHandler(services.AdminProduct),
-		Upload:       NewUploadHandler(services.Upload),
-		Cart:         NewCartHandler(services.Cart),
-		Order:        NewOrderHandler(services.Order),
-		Payment:      NewPaymentHandler(services.Payment),
-		Delivery:     NewDeliveryHandler(services.Delivery),
+		Auth:           NewAuthHandler(services.Auth),
+		User:           NewUserHandler(services.User, services.Auth),
+		Account:        NewAccountHandler(services.Account),
+		Phone:          NewPhoneHandler(services.Phone),
+		Loyalty:        NewLoyaltyHandler(services.Loyalty),
+		Region:         NewRegionHandler(services.Region),
+		Staff:          NewStaffHandler(services.Staff),
+		Product:        NewProductHandler(services.Product),
+		AdminProduct:   NewAdminProductHandler(services.AdminProduct),
+		Upload:         NewUploadHandler(services.Upload),
+		Cart:           NewCartHandler(services.Cart),
+		Order:          NewOrderHandler(services.Order),
+		AdminOrder:     NewAdminOrderHandler(services.Order, services.Refund),
+		Payment:        NewPaymentHandler(services.Payment),
+		AdminPayment:   NewAdminPaymentHandler(services.Payment),
+		Refund:         NewRefundHandler(services.Refund),
+		Delivery:       NewDeliveryHandler(services.Delivery),
+		Reconciliation: NewAdminReconciliationHandler(services.Reconciliation),
 	}
 }
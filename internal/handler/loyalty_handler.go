@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/internal/service"
+)
+
+// LoyaltyHandler 提供顾客积分与会员等级查询接口。
+type LoyaltyHandler struct {
+	service service.LoyaltyService
+}
+
+// NewLoyaltyHandler 构建 LoyaltyHandler 实例。
+func NewLoyaltyHandler(service service.LoyaltyService) *LoyaltyHandler {
+	return &LoyaltyHandler{service: service}
+}
+
+// GetAccount 返回当前顾客的积分余额、会员等级、等级权益与升级进度。
+func (h *LoyaltyHandler) GetAccount(c *gin.Context) {
+	account, err := h.service.GetAccount(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// ListLedger 按时间倒序分页返回当前顾客的积分流水。
+func (h *LoyaltyHandler) ListLedger(c *gin.Context) {
+	query := service.PointsLedgerQuery{UserID: currentUserID(c), Cursor: c.Query("cursor")}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit value: " + value})
+			return
+		}
+		query.Limit = limit
+	}
+
+	page, err := h.service.ListLedger(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ListTiers 返回全部会员等级的门槛与权益。
+func (h *LoyaltyHandler) ListTiers(c *gin.Context) {
+	c.JSON(http.StatusOK, model.TierRules)
+}
@@ -1,7 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -19,17 +25,59 @@ func NewOrderHandler(service service.OrderService) *OrderHandler {
 	return &OrderHandler{service: service}
 }
 
-// CreateOrder 生成新的订单草稿。
+// RequireOwnership 校验路径中的订单属于当前顾客。他人订单按不存在处理，避免泄露订单号是否有效。
+func (h *OrderHandler) RequireOwnership(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.Next()
+		return
+	}
+
+	owner, err := h.service.OrderOwner(c.Request.Context(), orderID)
+	if err == nil && owner != currentUserID(c) {
+		err = model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+	}
+	if err != nil {
+		respondError(c, err)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// CreateOrder 为当前顾客生成新的订单草稿。
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	var req model.Order
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.UserID = currentUserID(c)
 
 	order, err := h.service.CreateOrder(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// Checkout 将购物车中已勾选的商品直接结算为订单，可选以积分抵扣部分金额。
+func (h *OrderHandler) Checkout(c *gin.Context) {
+	var req struct {
+		AddressID    string `json:"address_id" binding:"required"`
+		RedeemPoints int64  `json:"redeem_points" binding:"gte=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.service.Checkout(c.Request.Context(), currentUserID(c), req.AddressID, req.RedeemPoints)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -40,38 +88,124 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 func (h *OrderHandler) GetOrder(c *gin.Context) {
 	order, err := h.service.GetOrder(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, order)
 }
 
-// PayOrder 发起订单支付。
+// ListOrders 返回顾客本人的历史订单，支持状态、时间范围与地址筛选及游标分页。
+func (h *OrderHandler) ListOrders(c *gin.Context) {
+	query, err := parseOrderListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.UserID = currentUserID(c)
+
+	page, err := h.service.ListOrders(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseOrderListQuery 从查询参数解析订单筛选条件，status 支持逗号分隔或重复传参。
+func parseOrderListQuery(c *gin.Context) (service.OrderListQuery, error) {
+	query := service.OrderListQuery{
+		UserID:    c.Query("user_id"),
+		AddressID: c.Query("address_id"),
+		Cursor:    c.Query("cursor"),
+	}
+
+	for _, value := range c.QueryArray("status") {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				query.Statuses = append(query.Statuses, model.OrderStatus(strings.ToUpper(status)))
+			}
+		}
+	}
+
+	if value := c.Query("created_from"); value != "" {
+		createdFrom, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid created_from: %w", err)
+		}
+		query.CreatedFrom = createdFrom
+	}
+	if value := c.Query("created_to"); value != "" {
+		createdTo, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid created_to: %w", err)
+		}
+		query.CreatedTo = createdTo
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return query, fmt.Errorf("invalid limit value: %s", value)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// PayOrder 发起订单支付，请求体可选地通过 provider 指定支付方式（wechat、alipay、offline），默认微信支付。
 func (h *OrderHandler) PayOrder(c *gin.Context) {
-	paymentInfo, err := h.service.PayOrder(c.Request.Context(), c.Param("id"))
+	var req struct {
+		Provider string `json:"provider"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentInfo, err := h.service.PayOrder(c.Request.Context(), c.Param("id"), req.Provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, paymentInfo)
 }
 
-// CancelOrder 在发货前取消订单。
+// CancelOrder 取消待支付订单，请求体可选地携带取消原因；已支付订单需通过退款撤销。
 func (h *OrderHandler) CancelOrder(c *gin.Context) {
-	if err := h.service.CancelOrder(c.Request.Context(), c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.CancelOrder(c.Request.Context(), c.Param("id"), req.Reason); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// GetTimeline 返回订单从创建至今的状态变化记录。
+func (h *OrderHandler) GetTimeline(c *gin.Context) {
+	events, err := h.service.GetTimeline(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
 // ShipOrder 将订单状态更新为已发货。
 func (h *OrderHandler) ShipOrder(c *gin.Context) {
 	if err := h.service.ShipOrder(c.Request.Context(), c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -81,7 +215,7 @@ func (h *OrderHandler) ShipOrder(c *gin.Context) {
 // CompleteOrder 将订单标记为已完成。
 func (h *OrderHandler) CompleteOrder(c *gin.Context) {
 	if err := h.service.CompleteOrder(c.Request.Context(), c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
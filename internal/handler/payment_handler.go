@@ -1,14 +1,17 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
+	"convenienceStore/internal/model"
 	"convenienceStore/internal/service"
+	"convenienceStore/pkg/payment"
 )
 
-// PaymentHandler 处理支付相关的回调。
+// PaymentHandler 处理支付相关的回调与查询。
 type PaymentHandler struct {
 	service service.PaymentService
 }
@@ -18,18 +21,71 @@ func NewPaymentHandler(service service.PaymentService) *PaymentHandler {
 	return &PaymentHandler{service: service}
 }
 
-// HandleWeChatCallback 处理来自微信支付的异步通知。
+// HandleWeChatCallback 处理来自微信支付的异步通知，按 v3 协议以 JSON 应答，
+// 非 2xx 应答会使微信支付稍后重试。
 func (h *PaymentHandler) HandleWeChatCallback(c *gin.Context) {
 	payload, err := c.GetRawData()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, weChatAck("FAIL", err.Error()))
 		return
 	}
 
-	if err := h.service.HandleWeChatCallback(c.Request.Context(), payload); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.HandleCallback(c.Request.Context(), model.PaymentProviderWeChat, c.Request.Header, payload); err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) || errors.Is(err, payment.ErrReplayedNotification) {
+			c.JSON(http.StatusUnauthorized, weChatAck("FAIL", err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, weChatAck("FAIL", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, weChatAck("SUCCESS", "成功"))
+}
+
+// HandleAlipayCallback 处理支付宝异步通知，按支付宝约定以纯文本 success 应答，
+// 其他应答会使支付宝稍后重试。
+func (h *PaymentHandler) HandleAlipayCallback(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return
+	}
+
+	if err := h.service.HandleCallback(c.Request.Context(), model.PaymentProviderAlipay, c.Request.Header, payload); err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			c.String(http.StatusUnauthorized, "fail")
+			return
+		}
+		c.String(http.StatusInternalServerError, "fail")
 		return
 	}
 
 	c.String(http.StatusOK, "success")
 }
+
+// ListOrderPayments 返回订单的全部支付尝试，便于顾客查看支付进度。
+func (h *PaymentHandler) ListOrderPayments(c *gin.Context) {
+	payments, err := h.service.ListOrderPayments(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payments": payments})
+}
+
+// SyncOrderPayment 在小程序支付弹窗关闭后主动同步订单的支付结果，避免等待异步回调。
+func (h *PaymentHandler) SyncOrderPayment(c *gin.Context) {
+	orderID := c.Param("id")
+	status, err := h.service.SyncOrderPayment(c.Request.Context(), orderID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order_id": orderID, "status": status})
+}
+
+func weChatAck(code, message string) gin.H {
+	return gin.H{"code": code, "message": message}
+}
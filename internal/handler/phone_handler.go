@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/service"
+)
+
+// PhoneHandler 提供顾客手机号验证与绑定接口。
+type PhoneHandler struct {
+	service service.PhoneService
+}
+
+// NewPhoneHandler 构建 PhoneHandler 实例。
+func NewPhoneHandler(service service.PhoneService) *PhoneHandler {
+	return &PhoneHandler{service: service}
+}
+
+// SendCode 向待绑定的手机号下发短信验证码。
+func (h *PhoneHandler) SendCode(c *gin.Context) {
+	var req struct {
+		Phone string `json:"phone" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl, err := h.service.SendBindingCode(c.Request.Context(), currentUserID(c), req.Phone, c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"expires_in": int64(ttl.Seconds())})
+}
+
+// BindPhone 校验短信验证码并绑定手机号。
+func (h *PhoneHandler) BindPhone(c *gin.Context) {
+	var req struct {
+		Phone string `json:"phone" binding:"required"`
+		Code  string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.BindPhone(c.Request.Context(), currentUserID(c), req.Phone, req.Code); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"phone": req.Phone})
+}
+
+// BindWeChatPhone 绑定微信手机号：新版基础库传 getPhoneNumber 返回的 code，
+// 旧版基础库传加密数据 encrypted_data 与 iv。
+func (h *PhoneHandler) BindWeChatPhone(c *gin.Context) {
+	var req struct {
+		Code          string `json:"code" binding:"required_without=EncryptedData"`
+		EncryptedData string `json:"encrypted_data" binding:"required_without=Code"`
+		IV            string `json:"iv" binding:"required_with=EncryptedData"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var (
+		phone string
+		err   error
+	)
+	if req.Code != "" {
+		phone, err = h.service.BindWeChatPhoneCode(c.Request.Context(), currentUserID(c), req.Code)
+	} else {
+		phone, err = h.service.BindWeChatPhone(c.Request.Context(), currentUserID(c), req.EncryptedData, req.IV)
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"phone": phone})
+}
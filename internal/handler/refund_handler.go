@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/internal/service"
+	"convenienceStore/pkg/payment"
+)
+
+// RefundHandler 提供退款申请、查询与退款结果回调接口。
+type RefundHandler struct {
+	service service.RefundService
+}
+
+// NewRefundHandler 构建 RefundHandler 实例。
+func NewRefundHandler(service service.RefundService) *RefundHandler {
+	return &RefundHandler{service: service}
+}
+
+type refundRequestBody struct {
+	Items  []model.RefundItem `json:"items"`
+	Reason string             `json:"reason"`
+}
+
+// RequestRefund 为已支付订单申请退款，items 为空时退还剩余的全部商品与金额；
+// 未发货订单立即退款，其余订单等待员工审核。
+func (h *RefundHandler) RequestRefund(c *gin.Context) {
+	var req refundRequestBody
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.service.RequestRefund(c.Request.Context(), service.RefundRequest{
+		OrderID: c.Param("id"),
+		Items:   req.Items,
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
+
+// ListRefunds 返回订单的全部退款记录。
+func (h *RefundHandler) ListRefunds(c *gin.Context) {
+	refunds, err := h.service.ListRefunds(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"refunds": refunds})
+}
+
+// HandleWeChatRefundCallback 处理微信支付退款结果通知，应答格式与支付回调一致。
+func (h *RefundHandler) HandleWeChatRefundCallback(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, weChatAck("FAIL", err.Error()))
+		return
+	}
+
+	if err := h.service.HandleWeChatRefundCallback(c.Request.Context(), c.Request.Header, payload); err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) || errors.Is(err, payment.ErrReplayedNotification) {
+			c.JSON(http.StatusUnauthorized, weChatAck("FAIL", err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, weChatAck("FAIL", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, weChatAck("SUCCESS", "成功"))
+}
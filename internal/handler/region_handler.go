@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/service"
+)
+
+// regionCacheControl 允许客户端缓存行政区划数据，数据仅随版本发布更新。
+const regionCacheControl = "public, max-age=86400"
+
+// RegionHandler 提供行政区划查询接口。
+type RegionHandler struct {
+	service service.RegionService
+}
+
+// NewRegionHandler 构建 RegionHandler 实例。
+func NewRegionHandler(service service.RegionService) *RegionHandler {
+	return &RegionHandler{service: service}
+}
+
+// ListRegions 默认返回省级行政区；parent_code 指定上级区划时返回其下级，tree=true 时返回完整区划树。
+func (h *RegionHandler) ListRegions(c *gin.Context) {
+	var (
+		regions any
+		err     error
+	)
+	if c.Query("tree") == "true" {
+		regions, err = h.service.Tree(c.Request.Context())
+	} else {
+		regions, err = h.service.ListRegions(c.Request.Context(), c.Query("parent_code"))
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", regionCacheControl)
+	c.JSON(http.StatusOK, regions)
+}
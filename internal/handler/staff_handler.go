@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/internal/service"
+	"convenienceStore/pkg/auth"
+)
+
+// staffContextKey 是已认证员工在 gin.Context 中的键。
+const staffContextKey = "auth.staff"
+
+// StaffHandler 提供员工登录、账号与 API Key 管理接口，以及后台接口的认证与权限中间件。
+type StaffHandler struct {
+	service service.StaffService
+}
+
+// NewStaffHandler 构建 StaffHandler 实例。
+func NewStaffHandler(service service.StaffService) *StaffHandler {
+	return &StaffHandler{service: service}
+}
+
+// RequireStaff 校验员工身份，支持 X-API-Key 请求头或 Authorization: Bearer 访问令牌，
+// 并将员工作为操作主体写入请求上下文。
+func (h *StaffHandler) RequireStaff(c *gin.Context) {
+	var (
+		staff *model.Staff
+		err   error
+	)
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		staff, err = h.service.AuthenticateAPIKey(c.Request.Context(), key)
+	} else {
+		scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			respondError(c, model.NewError(model.ErrCodeUnauthorized, "missing bearer token or api key"))
+			c.Abort()
+			return
+		}
+		staff, err = h.service.AuthenticateToken(c.Request.Context(), strings.TrimSpace(token))
+	}
+	if err != nil {
+		respondError(c, err)
+		c.Abort()
+		return
+	}
+
+	c.Set(staffContextKey, staff)
+	ctx := service.WithOperator(c.Request.Context(), service.Operator{Actor: staff.ID, Source: model.OrderEventSourceAdmin})
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// RequirePermission 返回校验当前员工是否拥有指定权限的中间件，需挂在 RequireStaff 之后。
+func (h *StaffHandler) RequirePermission(permission model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		staff := currentStaff(c)
+		if staff == nil || !staff.Role.Can(permission) {
+			respondError(c, model.NewError(model.ErrCodeForbidden, "permission %s is required", permission))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Login 使用用户名与口令登录，返回员工资料与令牌。
+func (h *StaffHandler) Login(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	staff, tokens, err := h.service.Login(c.Request.Context(), req.Username, req.Password, c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		*model.Staff
+		auth.TokenPair
+	}{staff, tokens})
+}
+
+// RefreshToken 用员工刷新令牌换取新的令牌。
+func (h *StaffHandler) RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.RefreshTokens(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Me 返回当前员工资料。
+func (h *StaffHandler) Me(c *gin.Context) {
+	c.JSON(http.StatusOK, currentStaff(c))
+}
+
+// ListStaff 列出全部员工账号。
+func (h *StaffHandler) ListStaff(c *gin.Context) {
+	staffList, err := h.service.ListStaff(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"staff": staffList})
+}
+
+// CreateStaff 新建员工账号。
+func (h *StaffHandler) CreateStaff(c *gin.Context) {
+	var req struct {
+		Username string          `json:"username" binding:"required"`
+		Password string          `json:"password" binding:"required"`
+		Name     string          `json:"name"`
+		Role     model.StaffRole `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	staff := model.Staff{Username: req.Username, Name: req.Name, Role: req.Role}
+	if err := h.service.CreateStaff(c.Request.Context(), &staff, req.Password); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, staff)
+}
+
+// UpdateStaff 修改员工姓名、角色或状态。
+func (h *StaffHandler) UpdateStaff(c *gin.Context) {
+	var req struct {
+		Name   string            `json:"name"`
+		Role   model.StaffRole   `json:"role" binding:"required"`
+		Status model.StaffStatus `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	staff := model.Staff{ID: c.Param("id"), Name: req.Name, Role: req.Role, Status: req.Status}
+	if err := h.service.UpdateStaff(c.Request.Context(), &staff); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResetPassword 重置员工登录口令。
+func (h *StaffHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), c.Param("id"), req.Password); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListAPIKeys 列出员工的 API Key，不含明文。
+func (h *StaffHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.ListAPIKeys(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// CreateAPIKey 为员工生成 API Key，明文仅在本次响应中返回。
+func (h *StaffHandler) CreateAPIKey(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(c.Request.Context(), c.Param("id"), req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAPIKey 吊销员工的 API Key。
+func (h *StaffHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.service.RevokeAPIKey(c.Request.Context(), c.Param("id"), c.Param("key_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// currentStaff 返回 RequireStaff 写入的员工。
+func currentStaff(c *gin.Context) *model.Staff {
+	value, ok := c.Get(staffContextKey)
+	if !ok {
+		return nil
+	}
+	staff, _ := value.(*model.Staff)
+	return staff
+}
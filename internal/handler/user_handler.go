@@ -7,19 +7,27 @@ import (
 
 	"convenienceStore/internal/model"
 	"convenienceStore/internal/service"
+	"convenienceStore/pkg/auth"
 )
 
 // UserHandler 对外提供用户相关的 HTTP 接口。
 type UserHandler struct {
 	service service.UserService
+	auth    service.AuthService
 }
 
 // NewUserHandler 构建新的 UserHandler 实例。
-func NewUserHandler(service service.UserService) *UserHandler {
-	return &UserHandler{service: service}
+func NewUserHandler(service service.UserService, auth service.AuthService) *UserHandler {
+	return &UserHandler{service: service, auth: auth}
 }
 
-// WeChatLogin 使用微信授权码换取用户会话。
+// loginResponse 在用户资料之外附带会话令牌。
+type loginResponse struct {
+	*model.User
+	auth.TokenPair
+}
+
+// WeChatLogin 使用微信授权码换取用户会话，返回用户资料与访问令牌、刷新令牌。
 func (h *UserHandler) WeChatLogin(c *gin.Context) {
 	var req struct {
 		Code string `json:"code" binding:"required"`
@@ -31,20 +39,27 @@ func (h *UserHandler) WeChatLogin(c *gin.Context) {
 
 	user, err := h.service.WeChatLogin(c.Request.Context(), req.Code)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	tokens, err := h.auth.IssueTokens(c.Request.Context(), user.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loginResponse{User: user, TokenPair: tokens})
 }
 
-// BindUser 将用户账号与扩展档案数据绑定。
+// BindUser 将当前用户账号与扩展档案数据绑定。
 func (h *UserHandler) BindUser(c *gin.Context) {
 	var req model.User
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.ID = currentUserID(c)
 
 	if err := h.service.BindUser(c.Request.Context(), &req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -54,10 +69,9 @@ func (h *UserHandler) BindUser(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-// ListAddresses 返回用户保存的全部收货地址。
+// ListAddresses 返回当前用户保存的全部收货地址。
 func (h *UserHandler) ListAddresses(c *gin.Context) {
-	userID := c.Query("user_id")
-	addresses, err := h.service.ListAddresses(c.Request.Context(), userID)
+	addresses, err := h.service.ListAddresses(c.Request.Context(), currentUserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -66,13 +80,14 @@ func (h *UserHandler) ListAddresses(c *gin.Context) {
 	c.JSON(http.StatusOK, addresses)
 }
 
-// CreateAddress 为用户新增收货地址。
+// CreateAddress 为当前用户新增收货地址。
 func (h *UserHandler) CreateAddress(c *gin.Context) {
 	var req model.Address
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.UserID = currentUserID(c)
 
 	if err := h.service.CreateAddress(c.Request.Context(), &req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -82,7 +97,7 @@ func (h *UserHandler) CreateAddress(c *gin.Context) {
 	c.Status(http.StatusCreated)
 }
 
-// UpdateAddress 更新已有的收货地址。
+// UpdateAddress 更新当前用户已有的收货地址。
 func (h *UserHandler) UpdateAddress(c *gin.Context) {
 	var req model.Address
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -90,19 +105,20 @@ func (h *UserHandler) UpdateAddress(c *gin.Context) {
 		return
 	}
 	req.ID = c.Param("id")
+	req.UserID = currentUserID(c)
 
 	if err := h.service.UpdateAddress(c.Request.Context(), &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
-// DeleteAddress 从用户资料中移除该地址。
+// DeleteAddress 从当前用户资料中移除该地址。
 func (h *UserHandler) DeleteAddress(c *gin.Context) {
-	if err := h.service.DeleteAddress(c.Request.Context(), c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteAddress(c.Request.Context(), currentUserID(c), c.Param("id")); err != nil {
+		respondError(c, err)
 		return
 	}
 
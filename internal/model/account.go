@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// AccountExport 汇总顾客可下载的个人信息副本。
+type AccountExport struct {
+	ExportedAt time.Time  `json:"exported_at"`
+	Profile    User       `json:"profile"`
+	Addresses  []Address  `json:"addresses"`
+	CartItems  []CartItem `json:"cart_items"`
+	Orders     []Order    `json:"orders"`
+	Payments   []Payment  `json:"payments"`
+	Refunds    []Refund   `json:"refunds"`
+	// Member 与 PointsLedger 为会员积分账户及全部积分流水。
+	Member       *MemberAccount `json:"member"`
+	PointsLedger []PointsEntry  `json:"points_ledger"`
+}
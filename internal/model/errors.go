@@ -1,17 +1,39 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+)
+
 // ErrorCode 枚举领域内使用的错误标识。
 type ErrorCode string
 
 const (
-	ErrCodeInvalidParameter ErrorCode = "ERR_INVALID_PARAMETER"
-	ErrCodeUserNotFound     ErrorCode = "ERR_USER_NOT_FOUND"
-	ErrCodeAddressNotFound  ErrorCode = "ERR_ADDRESS_NOT_FOUND"
-	ErrCodeProductNotFound  ErrorCode = "ERR_PRODUCT_NOT_FOUND"
-	ErrCodeInventoryShort   ErrorCode = "ERR_INVENTORY_SHORTAGE"
-	ErrCodeCartEmpty        ErrorCode = "ERR_CART_EMPTY"
-	ErrCodeOrderNotFound    ErrorCode = "ERR_ORDER_NOT_FOUND"
-	ErrCodePaymentFailed    ErrorCode = "ERR_PAYMENT_FAILED"
+	ErrCodeInvalidParameter  ErrorCode = "ERR_INVALID_PARAMETER"
+	ErrCodeUserNotFound      ErrorCode = "ERR_USER_NOT_FOUND"
+	ErrCodeAddressNotFound   ErrorCode = "ERR_ADDRESS_NOT_FOUND"
+	ErrCodeProductNotFound   ErrorCode = "ERR_PRODUCT_NOT_FOUND"
+	ErrCodeInventoryShort    ErrorCode = "ERR_INVENTORY_SHORTAGE"
+	ErrCodeCartEmpty         ErrorCode = "ERR_CART_EMPTY"
+	ErrCodeOrderNotFound     ErrorCode = "ERR_ORDER_NOT_FOUND"
+	ErrCodePaymentFailed     ErrorCode = "ERR_PAYMENT_FAILED"
+	ErrCodeInvalidTransition ErrorCode = "ERR_INVALID_TRANSITION"
+	ErrCodeProductInactive   ErrorCode = "ERR_PRODUCT_INACTIVE"
+	ErrCodePriceChanged      ErrorCode = "ERR_PRICE_CHANGED"
+	ErrCodeRefundExceeded    ErrorCode = "ERR_REFUND_EXCEEDED"
+	ErrCodeRefundNotFound    ErrorCode = "ERR_REFUND_NOT_FOUND"
+	ErrCodeReportNotFound    ErrorCode = "ERR_REPORT_NOT_FOUND"
+	ErrCodeUnauthorized      ErrorCode = "ERR_UNAUTHORIZED"
+	ErrCodeForbidden         ErrorCode = "ERR_FORBIDDEN"
+	ErrCodeStaffNotFound     ErrorCode = "ERR_STAFF_NOT_FOUND"
+	ErrCodeStaffExists       ErrorCode = "ERR_STAFF_EXISTS"
+	ErrCodeAPIKeyNotFound    ErrorCode = "ERR_API_KEY_NOT_FOUND"
+	ErrCodeTooManyRequests   ErrorCode = "ERR_TOO_MANY_REQUESTS"
+	ErrCodeInvalidCode       ErrorCode = "ERR_INVALID_VERIFICATION_CODE"
+	ErrCodePhoneInUse        ErrorCode = "ERR_PHONE_IN_USE"
+	ErrCodeOpenOrders        ErrorCode = "ERR_OPEN_ORDERS"
+	ErrCodeRegionNotFound    ErrorCode = "ERR_REGION_NOT_FOUND"
+	ErrCodePointsShort       ErrorCode = "ERR_INSUFFICIENT_POINTS"
 )
 
 // KnownErrorCodes 方便在文档接口中暴露支持的错误码。
@@ -24,4 +46,52 @@ var KnownErrorCodes = []ErrorCode{
 	ErrCodeCartEmpty,
 	ErrCodeOrderNotFound,
 	ErrCodePaymentFailed,
+	ErrCodeInvalidTransition,
+	ErrCodeProductInactive,
+	ErrCodePriceChanged,
+	ErrCodeRefundExceeded,
+	ErrCodeRefundNotFound,
+	ErrCodeReportNotFound,
+	ErrCodeUnauthorized,
+	ErrCodeForbidden,
+	ErrCodeStaffNotFound,
+	ErrCodeStaffExists,
+	ErrCodeAPIKeyNotFound,
+	ErrCodeTooManyRequests,
+	ErrCodeInvalidCode,
+	ErrCodePhoneInUse,
+	ErrCodeOpenOrders,
+	ErrCodeRegionNotFound,
+	ErrCodePointsShort,
+}
+
+// Error 是携带错误码的领域错误，接口层据此映射 HTTP 状态码。
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Details any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError 构建带错误码的领域错误。
+func NewError(code ErrorCode, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithDetails 附加便于客户端处理的结构化信息。
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+// ErrorCodeOf 从错误链中提取错误码，非领域错误返回 false。
+func ErrorCodeOf(err error) (ErrorCode, bool) {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code, true
+	}
+	return "", false
 }
@@ -0,0 +1,107 @@
+package model
+
+import "time"
+
+// MemberTier 表示会员等级，由累计成长积分决定。
+type MemberTier string
+
+const (
+	MemberTierStandard MemberTier = "STANDARD"
+	MemberTierSilver   MemberTier = "SILVER"
+	MemberTierGold     MemberTier = "GOLD"
+	MemberTierPlatinum MemberTier = "PLATINUM"
+)
+
+// TierBenefits 描述会员等级享有的权益。
+type TierBenefits struct {
+	// EarnMultiplierPercent 为订单完成时积分发放倍率的百分比，100 表示 1 倍。
+	EarnMultiplierPercent int64 `json:"earn_multiplier_percent"`
+	// MaxRedeemPercent 为结算时积分最多可抵扣的商品金额比例。
+	MaxRedeemPercent int64 `json:"max_redeem_percent"`
+}
+
+// TierRule 描述会员等级的门槛与权益，Threshold 为升级所需的累计成长积分。
+type TierRule struct {
+	Tier      MemberTier   `json:"tier"`
+	Threshold int64        `json:"threshold"`
+	Benefits  TierBenefits `json:"benefits"`
+}
+
+// TierRules 按门槛从低到高列出全部会员等级。
+var TierRules = []TierRule{
+	{Tier: MemberTierStandard, Threshold: 0, Benefits: TierBenefits{EarnMultiplierPercent: 100, MaxRedeemPercent: 30}},
+	{Tier: MemberTierSilver, Threshold: 2000, Benefits: TierBenefits{EarnMultiplierPercent: 120, MaxRedeemPercent: 40}},
+	{Tier: MemberTierGold, Threshold: 10000, Benefits: TierBenefits{EarnMultiplierPercent: 150, MaxRedeemPercent: 50}},
+	{Tier: MemberTierPlatinum, Threshold: 30000, Benefits: TierBenefits{EarnMultiplierPercent: 200, MaxRedeemPercent: 50}},
+}
+
+// TierFor 返回累计成长积分对应的会员等级规则。
+func TierFor(tierPoints int64) TierRule {
+	rule := TierRules[0]
+	for _, candidate := range TierRules {
+		if tierPoints >= candidate.Threshold {
+			rule = candidate
+		}
+	}
+	return rule
+}
+
+// NextTier 返回高于当前成长积分的下一等级，已是最高等级时返回 false。
+func NextTier(tierPoints int64) (TierRule, bool) {
+	for _, candidate := range TierRules {
+		if tierPoints < candidate.Threshold {
+			return candidate, true
+		}
+	}
+	return TierRule{}, false
+}
+
+// PointsEntryType 标识积分流水的业务类型。
+type PointsEntryType string
+
+const (
+	// PointsEntryEarn 为订单完成时发放的积分。
+	PointsEntryEarn PointsEntryType = "EARN"
+	// PointsEntryEarnReversal 为订单退款时按退款金额扣回的已发放积分。
+	PointsEntryEarnReversal PointsEntryType = "EARN_REVERSAL"
+	// PointsEntryRedeem 为结算时抵扣订单金额消耗的积分。
+	PointsEntryRedeem PointsEntryType = "REDEEM"
+	// PointsEntryRedeemReturn 为订单取消或退款时退回的抵扣积分。
+	PointsEntryRedeemReturn PointsEntryType = "REDEEM_RETURN"
+)
+
+// PointsEntry 是积分流水中的一条记录，流水只追加不修改。
+// Points 为带符号的变动值，Balance 为变动后的可用积分；退款扣回可能使余额为负，之后获得的积分先抵补欠额。
+type PointsEntry struct {
+	ID       int64           `json:"id"`
+	UserID   string          `json:"user_id"`
+	OrderID  string          `json:"order_id"`
+	RefundID string          `json:"refund_id,omitempty"`
+	Type     PointsEntryType `json:"type"`
+	Points   int64           `json:"points"`
+	Balance  int64           `json:"balance"`
+	// BaseAmount 为计算积分所依据的订单金额。
+	BaseAmount Money     `json:"base_amount"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PointsLedgerPage 是积分流水的一页结果，NextCursor 为空表示没有更多数据。
+type PointsLedgerPage struct {
+	Entries    []PointsEntry `json:"entries"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// MemberAccount 汇总顾客的积分余额与会员等级，TierPoints 为累计发放且未被扣回的成长积分。
+type MemberAccount struct {
+	UserID     string       `json:"user_id"`
+	Balance    int64        `json:"balance"`
+	TierPoints int64        `json:"tier_points"`
+	Tier       MemberTier   `json:"tier"`
+	Benefits   TierBenefits `json:"benefits"`
+	// NextTier 与 PointsToNextTier 提示升级进度，已是最高等级时省略。
+	NextTier         MemberTier `json:"next_tier,omitempty"`
+	PointsToNextTier int64      `json:"points_to_next_tier,omitempty"`
+	// RedeemPointsPerYuan 为抵扣 1 元所需的积分。
+	RedeemPointsPerYuan int64 `json:"redeem_points_per_yuan"`
+}
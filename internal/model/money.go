@@ -0,0 +1,150 @@
+package model
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 以分为单位存储金额，避免浮点运算带来的误差。
+// 在 JSON 与 SQL 中均以两位小数的“元”表示，与 DECIMAL(10,2) 列保持兼容。
+type Money int64
+
+// Fen 返回以分为单位的整数金额，可直接用于支付网关。
+func (m Money) Fen() int64 {
+	return int64(m)
+}
+
+// Mul 返回金额乘以数量后的结果。
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// String 以“元”格式输出金额，例如 4.50。
+func (m Money) String() string {
+	sign := ""
+	fen := int64(m)
+	if fen < 0 {
+		sign = "-"
+		fen = -fen
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, fen/100, fen%100)
+}
+
+// ParseMoney 将“元”格式的十进制字符串精确转换为 Money，小数位最多两位。
+func ParseMoney(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("invalid money value %q", value)
+	}
+
+	negative := false
+	switch value[0] {
+	case '-':
+		negative = true
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(value, ".")
+	if whole == "" && (!hasFrac || frac == "") {
+		return 0, fmt.Errorf("invalid money value %q", value)
+	}
+	if hasFrac {
+		frac = strings.TrimRight(frac, "0")
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("money value %q has more than two decimal places", value)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	var yuan int64
+	if whole != "" {
+		parsed, err := strconv.ParseUint(whole, 10, 63)
+		if err != nil {
+			return 0, fmt.Errorf("invalid money value %q", value)
+		}
+		if parsed > math.MaxInt64/100 {
+			return 0, fmt.Errorf("money value %q is out of range", value)
+		}
+		yuan = int64(parsed)
+	}
+	cents, err := strconv.ParseUint(frac, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid money value %q", value)
+	}
+	if yuan == math.MaxInt64/100 && cents > math.MaxInt64%100 {
+		return 0, fmt.Errorf("money value %q is out of range", value)
+	}
+
+	fen := yuan*100 + int64(cents)
+	if negative {
+		fen = -fen
+	}
+	return Money(fen), nil
+}
+
+// MarshalJSON 以数字形式输出两位小数的金额。
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 接受数字或字符串形式的金额，按十进制文本解析以免精度丢失。
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	text := string(data)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value 实现 driver.Valuer，写入 DECIMAL 列时使用字符串避免浮点转换。
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan 实现 sql.Scanner，兼容驱动返回的文本、整数与浮点表示。
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case float64:
+		*m = Money(math.Round(v * 100))
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+}
@@ -0,0 +1,209 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{input: "0", want: 0},
+		{input: "4.5", want: 450},
+		{input: "4.50", want: 450},
+		{input: "12.34", want: 1234},
+		{input: " 12.34 ", want: 1234},
+		{input: "+1.01", want: 101},
+		{input: ".5", want: 50},
+		{input: "5.", want: 500},
+		{input: "0.01", want: 1},
+		{input: "-0.01", want: -1},
+		{input: "-12.34", want: -1234},
+		{input: "1.500", want: 150},
+		{input: "1.2300000", want: 123},
+		{input: "0.001", wantErr: true},
+		{input: "1.234", wantErr: true},
+		{input: "-1.005", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: ".", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "1.2a", wantErr: true},
+		{input: "--1", wantErr: true},
+		{input: "1e2", wantErr: true},
+		{input: "92233720368547758.07", want: Money(1<<63 - 1)},
+		{input: "92233720368547758.08", wantErr: true},
+		{input: "92233720368547759", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMoney(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q) = %d, want error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q): %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{name: "number", input: `4.5`, want: 450},
+		{name: "integer number", input: `12`, want: 1200},
+		{name: "string", input: `"4.50"`, want: 450},
+		{name: "negative number", input: `-0.3`, want: -30},
+		{name: "negative string", input: `"-12.34"`, want: -1234},
+		{name: "trailing zeros", input: `19.900`, want: 1990},
+		{name: "number with three decimals", input: `0.125`, wantErr: true},
+		{name: "string with three decimals", input: `"0.125"`, wantErr: true},
+		{name: "exponent", input: `1e2`, wantErr: true},
+		{name: "empty string", input: `""`, wantErr: true},
+		{name: "boolean", input: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %d, want error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s): %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	// null 不修改原值，与标准库对其他类型的处理一致。
+	got := Money(100)
+	if err := json.Unmarshal([]byte(`null`), &got); err != nil || got != 100 {
+		t.Errorf("Unmarshal(null) = %d, %v; want 100 unchanged", got, err)
+	}
+}
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	tests := []struct {
+		value Money
+		want  string
+	}{
+		{value: 0, want: `0.00`},
+		{value: 1, want: `0.01`},
+		{value: 450, want: `4.50`},
+		{value: 1234, want: `12.34`},
+		{value: -1, want: `-0.01`},
+		{value: -1234, want: `-12.34`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			data, err := json.Marshal(struct {
+				Total Money `json:"total"`
+			}{tt.value})
+			if err != nil {
+				t.Fatalf("Marshal(%d): %v", tt.value, err)
+			}
+			if want := `{"total":` + tt.want + `}`; string(data) != want {
+				t.Errorf("Marshal(%d) = %s, want %s", tt.value, data, want)
+			}
+
+			var back Money
+			if err := json.Unmarshal([]byte(tt.want), &back); err != nil || back != tt.value {
+				t.Errorf("round trip of %d = %d, %v", tt.value, back, err)
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     any
+		want    Money
+		wantErr bool
+	}{
+		{name: "NULL", src: nil, want: 0},
+		{name: "bytes", src: []byte("12.34"), want: 1234},
+		{name: "negative bytes", src: []byte("-0.50"), want: -50},
+		{name: "string", src: "4.50", want: 450},
+		{name: "int64", src: int64(12), want: 1200},
+		{name: "negative int64", src: int64(-3), want: -300},
+		{name: "float64", src: 12.34, want: 1234},
+		{name: "float64 rounds half away from zero", src: 0.125, want: 13},
+		{name: "float64 rounds binary error", src: 1.005, want: 100},
+		{name: "negative float64", src: -0.125, want: -13},
+		{name: "bytes with three decimals", src: []byte("1.234"), wantErr: true},
+		{name: "malformed bytes", src: []byte("abc"), wantErr: true},
+		{name: "unsupported type", src: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Money(999)
+			err := got.Scan(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v) = %d, want error", tt.src, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%v): %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %d, want %d", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyValue(t *testing.T) {
+	tests := []struct {
+		value Money
+		want  string
+	}{
+		{value: 0, want: "0.00"},
+		{value: 5, want: "0.05"},
+		{value: 1990, want: "19.90"},
+		{value: -1234, want: "-12.34"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := tt.value.Value()
+			if err != nil {
+				t.Fatalf("Value(%d): %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("Value(%d) = %v, want %q", tt.value, got, tt.want)
+			}
+
+			var back Money
+			if err := back.Scan([]byte(got.(string))); err != nil || back != tt.value {
+				t.Errorf("Scan(Value(%d)) = %d, %v", tt.value, back, err)
+			}
+		})
+	}
+}
@@ -11,19 +11,71 @@ const (
 	OrderStatusShipped        OrderStatus = "SHIPPED"
 	OrderStatusCompleted      OrderStatus = "COMPLETED"
 	OrderStatusCancelled      OrderStatus = "CANCELLED"
+	OrderStatusRefunding      OrderStatus = "REFUNDING"
+	OrderStatusRefunded       OrderStatus = "REFUNDED"
 )
 
-var (
-	_ = OrderStatusShipped
-	_ = OrderStatusCompleted
-	_ = OrderStatusCancelled
-)
+// OrderStatuses 按生命周期顺序列出全部订单状态。
+var OrderStatuses = []OrderStatus{
+	OrderStatusPendingPayment,
+	OrderStatusPaid,
+	OrderStatusShipped,
+	OrderStatusCompleted,
+	OrderStatusCancelled,
+	OrderStatusRefunding,
+	OrderStatusRefunded,
+}
+
+// Valid 判断状态是否属于已定义的订单状态。
+func (s OrderStatus) Valid() bool {
+	for _, status := range OrderStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// orderTransitions 描述每个状态允许流转到的下一状态。
+// 已支付的订单只能通过退款撤销；退款处理中的订单在部分退款完成或退款失败后回到发起前的状态。
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPendingPayment: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:           {OrderStatusShipped, OrderStatusRefunding},
+	OrderStatusShipped:        {OrderStatusCompleted, OrderStatusRefunding},
+	OrderStatusCompleted:      {OrderStatusRefunding},
+	OrderStatusRefunding:      {OrderStatusRefunded, OrderStatusPaid, OrderStatusShipped, OrderStatusCompleted},
+}
+
+// CanTransitionTo 判断订单能否从当前状态流转到目标状态。
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, candidate := range orderTransitions[s] {
+		if candidate == next {
+			return true
+		}
+	}
+	return false
+}
+
+// SourceStatuses 返回可以流转到目标状态的全部前置状态。
+func SourceStatuses(target OrderStatus) []OrderStatus {
+	var sources []OrderStatus
+	for _, from := range OrderStatuses {
+		if from.CanTransitionTo(target) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
 
-// OrderItem 表示订单中购买的单件商品。
+// OrderItem 表示订单中购买的单件商品，商品名称、主图与标签为下单时的快照，
+// Price 为成交单价，均不受之后商品资料修改或下架删除的影响。
 type OrderItem struct {
-	ProductID string  `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID    string   `json:"product_id"`
+	Quantity     int      `json:"quantity"`
+	Price        Money    `json:"price"`
+	ProductName  string   `json:"product_name,omitempty"`
+	ProductImage string   `json:"product_image,omitempty"`
+	ProductTags  []string `json:"product_tags,omitempty"`
 }
 
 // Order 包含订单的核心信息及状态流转。
@@ -32,8 +84,40 @@ type Order struct {
 	UserID    string      `json:"user_id"`
 	Items     []OrderItem `json:"items"`
 	Status    OrderStatus `json:"status"`
-	Total     float64     `json:"total"`
+	Total     Money       `json:"total"`
 	AddressID string      `json:"address_id"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
+	// PointsUsed 为结算时抵扣的积分，PointsDiscount 为对应的抵扣金额，Total 为抵扣后的应付金额。
+	PointsUsed     int64     `json:"points_used"`
+	PointsDiscount Money     `json:"points_discount"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// OrderPage 是订单列表的一页结果，NextCursor 为空表示没有更多数据。
+type OrderPage struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// OrderEventSource 标识触发订单状态变化的渠道。
+type OrderEventSource string
+
+const (
+	OrderEventSourceCustomer OrderEventSource = "customer"
+	OrderEventSourceAdmin    OrderEventSource = "admin"
+	OrderEventSourceCallback OrderEventSource = "callback"
+	OrderEventSourceTimeout  OrderEventSource = "timeout"
+	OrderEventSourceSystem   OrderEventSource = "system"
+)
+
+// OrderEvent 记录订单的一次状态变化，FromStatus 为空表示订单创建。
+type OrderEvent struct {
+	ID         int64            `json:"id"`
+	OrderID    string           `json:"order_id"`
+	FromStatus OrderStatus      `json:"from_status,omitempty"`
+	ToStatus   OrderStatus      `json:"to_status"`
+	Actor      string           `json:"actor,omitempty"`
+	Source     OrderEventSource `json:"source"`
+	Reason     string           `json:"reason,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
 }
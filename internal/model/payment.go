@@ -1,8 +1,75 @@
 package model
 
+import "time"
+
+// 支付方式标识，与 payment 包中注册表的名称一致。
+const (
+	PaymentProviderWeChat  = "wechat"
+	PaymentProviderAlipay  = "alipay"
+	PaymentProviderOffline = "offline"
+)
+
 // PaymentIntent 包含客户端初始化支付所需的配置。
 type PaymentIntent struct {
 	OrderID     string            `json:"order_id"`
+	PaymentID   string            `json:"payment_id"`
 	Provider    string            `json:"provider"`
 	Credentials map[string]string `json:"credentials"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+}
+
+// PaymentStatus 描述一次支付尝试的状态。
+type PaymentStatus string
+
+const (
+	PaymentStatusCreated PaymentStatus = "CREATED"
+	PaymentStatusPaid    PaymentStatus = "PAID"
+	PaymentStatusClosed  PaymentStatus = "CLOSED"
+)
+
+// Payment 记录向支付渠道发起的一次支付尝试。
+type Payment struct {
+	ID            string        `json:"id"`
+	OrderID       string        `json:"order_id"`
+	Provider      string        `json:"provider"`
+	PrepayID      string        `json:"prepay_id,omitempty"`
+	Amount        Money         `json:"amount"`
+	Status        PaymentStatus `json:"status"`
+	TransactionID string        `json:"transaction_id,omitempty"`
+	ExpiresAt     *time.Time    `json:"expires_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// PaymentPage 是支付记录查询的一页结果，NextCursor 为空表示没有更多数据。
+type PaymentPage struct {
+	Payments   []Payment `json:"payments"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// PaymentTransactionStatus 描述一笔渠道交易在本地的处理结果。
+type PaymentTransactionStatus string
+
+const (
+	// PaymentTransactionReceived 表示交易已登记但尚未完成入账，重复通知时会再次尝试。
+	PaymentTransactionReceived PaymentTransactionStatus = "RECEIVED"
+	// PaymentTransactionApplied 表示交易已驱动订单进入已支付状态。
+	PaymentTransactionApplied PaymentTransactionStatus = "APPLIED"
+	// PaymentTransactionReview 表示交易存在金额不符等异常，需要人工复核。
+	PaymentTransactionReview PaymentTransactionStatus = "REVIEW"
+)
+
+// PaymentTransaction 记录支付渠道通知的一笔交易，以渠道交易号去重。
+type PaymentTransaction struct {
+	ID            int64                    `json:"id"`
+	Provider      string                   `json:"provider"`
+	TransactionID string                   `json:"transaction_id"`
+	OrderID       string                   `json:"order_id"`
+	TradeState    string                   `json:"trade_state"`
+	Amount        Money                    `json:"amount"`
+	PayerOpenID   string                   `json:"payer_open_id,omitempty"`
+	Status        PaymentTransactionStatus `json:"status"`
+	Note          string                   `json:"note,omitempty"`
+	PaidAt        *time.Time               `json:"paid_at,omitempty"`
+	CreatedAt     time.Time                `json:"created_at"`
 }
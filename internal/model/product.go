@@ -5,9 +5,23 @@ type Product struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
-	Price       float64  `json:"price"`
+	Price       Money    `json:"price"`
 	Stock       int      `json:"stock"`
 	Tags        []string `json:"tags"`
 	Images      []string `json:"images"`
 	IsActive    bool     `json:"is_active"`
 }
+
+// InventoryShortage describes a product that cannot cover the requested quantity.
+type InventoryShortage struct {
+	ProductID string `json:"product_id"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+}
+
+// PriceChange reports a product whose current price differs from what the client expected.
+type PriceChange struct {
+	ProductID string `json:"product_id"`
+	Expected  Money  `json:"expected"`
+	Actual    Money  `json:"actual"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// ReconciliationSource 描述对账所用账单的来源。
+type ReconciliationSource string
+
+const (
+	// ReconciliationSourceDownload 表示账单由系统从支付渠道下载。
+	ReconciliationSourceDownload ReconciliationSource = "DOWNLOAD"
+	// ReconciliationSourceImport 表示账单由财务人员上传。
+	ReconciliationSourceImport ReconciliationSource = "IMPORT"
+)
+
+// DiscrepancyType 描述账单与本地记录之间的差异类别。
+type DiscrepancyType string
+
+const (
+	// DiscrepancyMissingLocal 表示渠道账单中有成功交易，本地没有对应的已支付记录。
+	DiscrepancyMissingLocal DiscrepancyType = "MISSING_LOCAL"
+	// DiscrepancyMissingRemote 表示本地已支付，渠道账单中没有对应的交易。
+	DiscrepancyMissingRemote DiscrepancyType = "MISSING_REMOTE"
+	// DiscrepancyAmountMismatch 表示双方都有记录但金额不一致。
+	DiscrepancyAmountMismatch DiscrepancyType = "AMOUNT_MISMATCH"
+	// DiscrepancyRefundMissingLocal 表示渠道账单中有成功退款，本地没有对应的已完成退款。
+	DiscrepancyRefundMissingLocal DiscrepancyType = "REFUND_MISSING_LOCAL"
+	// DiscrepancyRefundMissingRemote 表示本地退款已完成，渠道账单中没有对应的退款。
+	DiscrepancyRefundMissingRemote DiscrepancyType = "REFUND_MISSING_REMOTE"
+	// DiscrepancyRefundAmountMismatch 表示双方都有退款记录但金额或订单不一致。
+	DiscrepancyRefundAmountMismatch DiscrepancyType = "REFUND_AMOUNT_MISMATCH"
+)
+
+// ReconciliationReport 是某一支付方式某一账单日的对账结果，同一账单日重新对账会替换旧报告。
+// Remote/Local/Matched 统计支付交易，Refund 前缀的字段单独统计退款，差异数包含两者。
+type ReconciliationReport struct {
+	ID                 int64                       `json:"id"`
+	Provider           string                      `json:"provider"`
+	BillDate           string                      `json:"bill_date"`
+	Source             ReconciliationSource        `json:"source"`
+	RemoteCount        int                         `json:"remote_count"`
+	RemoteAmount       Money                       `json:"remote_amount"`
+	LocalCount         int                         `json:"local_count"`
+	LocalAmount        Money                       `json:"local_amount"`
+	MatchedCount       int                         `json:"matched_count"`
+	RefundRemoteCount  int                         `json:"refund_remote_count"`
+	RefundRemoteAmount Money                       `json:"refund_remote_amount"`
+	RefundLocalCount   int                         `json:"refund_local_count"`
+	RefundLocalAmount  Money                       `json:"refund_local_amount"`
+	RefundMatchedCount int                         `json:"refund_matched_count"`
+	DiscrepancyCount   int                         `json:"discrepancy_count"`
+	CreatedAt          time.Time                   `json:"created_at"`
+	Discrepancies      []ReconciliationDiscrepancy `json:"discrepancies,omitempty"`
+}
+
+// ReconciliationDiscrepancy 是对账报告中的一条差异，缺失一方的金额为 0。
+type ReconciliationDiscrepancy struct {
+	ID            int64           `json:"id"`
+	Type          DiscrepancyType `json:"type"`
+	OrderID       string          `json:"order_id"`
+	TransactionID string          `json:"transaction_id"`
+	// RefundID 为退款类差异的商户退款单号。
+	RefundID     string `json:"refund_id,omitempty"`
+	LocalAmount  Money  `json:"local_amount"`
+	RemoteAmount Money  `json:"remote_amount"`
+	Note         string `json:"note,omitempty"`
+}
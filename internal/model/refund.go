@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// RefundStatus 描述一笔退款的处理状态。
+type RefundStatus string
+
+const (
+	// RefundStatusPending 表示顾客申请的退款等待员工审核，尚未提交支付渠道。
+	RefundStatusPending    RefundStatus = "PENDING"
+	RefundStatusProcessing RefundStatus = "PROCESSING"
+	RefundStatusSucceeded  RefundStatus = "SUCCEEDED"
+	RefundStatusFailed     RefundStatus = "FAILED"
+	// RefundStatusRejected 表示员工驳回了退款申请，订单回到申请前的状态。
+	RefundStatusRejected RefundStatus = "REJECTED"
+)
+
+// RefundItem 表示退款中退回的商品数量，Amount 按下单时的成交单价计算。
+type RefundItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	Amount    Money  `json:"amount"`
+}
+
+// Refund 记录订单的一次退款，PreviousStatus 为发起退款前的订单状态，
+// 部分退款完成或退款失败时订单回到该状态。
+type Refund struct {
+	ID               string       `json:"id"`
+	OrderID          string       `json:"order_id"`
+	Provider         string       `json:"provider"`
+	ProviderRefundID string       `json:"provider_refund_id,omitempty"`
+	Amount           Money        `json:"amount"`
+	Status           RefundStatus `json:"status"`
+	Reason           string       `json:"reason,omitempty"`
+	PreviousStatus   OrderStatus  `json:"previous_status"`
+	// Restock 表示退款成功后归还库存：未发货订单自动归还，已发货订单需员工确认商品已退回。
+	Restock     bool         `json:"restock"`
+	Items       []RefundItem `json:"items"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
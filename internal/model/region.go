@@ -0,0 +1,10 @@
+package model
+
+// Region 表示一个行政区划节点，Code 为国家统计局六位区划代码。
+type Region struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// HasChildren 表示是否收录了下级区划，为 false 时客户端应让用户手动填写下一级。
+	HasChildren bool     `json:"has_children"`
+	Children    []Region `json:"children,omitempty"`
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// StaffRole 是门店员工的角色，决定其可使用的后台与履约接口。
+type StaffRole string
+
+const (
+	// StaffRoleManager 为店长，拥有全部权限，包括退款、对账与员工管理。
+	StaffRoleManager StaffRole = "MANAGER"
+	// StaffRoleClerk 为店员，负责商品维护、订单发货与线下收款。
+	StaffRoleClerk StaffRole = "CLERK"
+	// StaffRoleRider 为配送员，负责查看待配送订单与配送发货。
+	StaffRoleRider StaffRole = "RIDER"
+)
+
+// StaffStatus 是员工账号状态，停用的账号无法登录，已签发的令牌与 API Key 也随即失效。
+type StaffStatus string
+
+const (
+	StaffStatusActive   StaffStatus = "ACTIVE"
+	StaffStatusDisabled StaffStatus = "DISABLED"
+)
+
+// Permission 是后台接口所需的权限，每个路由声明自己需要的权限。
+type Permission string
+
+const (
+	PermissionProductsRead    Permission = "products:read"
+	PermissionProductsWrite   Permission = "products:write"
+	PermissionUploadsWrite    Permission = "uploads:write"
+	PermissionOrdersRead      Permission = "orders:read"
+	PermissionOrdersShip      Permission = "orders:ship"
+	PermissionOrdersRefund    Permission = "orders:refund"
+	PermissionPaymentsRead    Permission = "payments:read"
+	PermissionPaymentsConfirm Permission = "payments:confirm"
+	PermissionReconciliation  Permission = "reconciliation:manage"
+	PermissionDelivery        Permission = "delivery:write"
+	PermissionStaffManage     Permission = "staff:manage"
+)
+
+// rolePermissions 定义各角色拥有的权限，店长拥有全部权限，不在此列出。
+var rolePermissions = map[StaffRole][]Permission{
+	StaffRoleClerk: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionUploadsWrite,
+		PermissionOrdersRead,
+		PermissionOrdersShip,
+		PermissionPaymentsRead,
+		PermissionPaymentsConfirm,
+	},
+	StaffRoleRider: {
+		PermissionOrdersRead,
+		PermissionDelivery,
+	},
+}
+
+// Valid 判断角色是否为已定义的取值。
+func (r StaffRole) Valid() bool {
+	switch r {
+	case StaffRoleManager, StaffRoleClerk, StaffRoleRider:
+		return true
+	}
+	return false
+}
+
+// Can 判断角色是否拥有指定权限。
+func (r StaffRole) Can(permission Permission) bool {
+	if r == StaffRoleManager {
+		return true
+	}
+	return slices.Contains(rolePermissions[r], permission)
+}
+
+// Staff 表示门店员工账号，与微信顾客账号相互独立。
+type Staff struct {
+	ID        string      `json:"id"`
+	Username  string      `json:"username"`
+	Name      string      `json:"name"`
+	Role      StaffRole   `json:"role"`
+	Status    StaffStatus `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// StaffAPIKey 是员工用于脚本或设备接入的 API Key。明文 Key 仅在创建时返回一次，库中只保存摘要。
+type StaffAPIKey struct {
+	ID         string     `json:"id"`
+	StaffID    string     `json:"staff_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -4,6 +4,7 @@ package model
 type User struct {
 	ID            string `json:"id"`
 	WeChatOpenID  string `json:"wechat_open_id"`
+	WeChatUnionID string `json:"wechat_union_id,omitempty"`
 	Nickname      string `json:"nickname"`
 	AvatarURL     string `json:"avatar_url"`
 	Phone         string `json:"phone"`
@@ -22,4 +23,9 @@ type Address struct {
 	Detail     string `json:"detail"`
 	PostalCode string `json:"postal_code"`
 	IsDefault  bool   `json:"is_default"`
+	// ProvinceCode、CityCode、DistrictCode 为国家统计局区划代码，由服务端按省市区名称校验后填充；
+	// 城市不设区县（如东莞、中山）时 DistrictCode 为空。
+	ProvinceCode string `json:"province_code"`
+	CityCode     string `json:"city_code"`
+	DistrictCode string `json:"district_code"`
 }
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"convenienceStore/internal/model"
+)
+
+// AccountService 处理顾客个人信息的导出与账号注销。
+type AccountService interface {
+	// ExportData 汇总用户资料、地址、购物车、订单、支付与退款记录及积分流水。
+	ExportData(ctx context.Context, userID string) (*model.AccountExport, error)
+	// DeleteAccount 注销账号：匿名化用户资料与地址，清除购物车等个人数据，订单与支付记录保留用于财务核算。
+	DeleteAccount(ctx context.Context, userID string) error
+}
+
+// openOrderStatuses 是尚未结束的订单状态，存在此类订单时不允许注销。
+var openOrderStatuses = []model.OrderStatus{
+	model.OrderStatusPendingPayment,
+	model.OrderStatusPaid,
+	model.OrderStatusShipped,
+	model.OrderStatusRefunding,
+}
+
+// deletedNickname 是注销后用户与地址保留的占位名称。
+const deletedNickname = "已注销用户"
+
+type accountService struct {
+	deps     Dependencies
+	users    UserService
+	carts    CartService
+	orders   OrderService
+	payments PaymentService
+	refunds  RefundService
+	loyalty  LoyaltyService
+}
+
+// NewAccountService 创建 AccountService 实例，导出数据复用各领域服务的查询。
+func NewAccountService(deps Dependencies, users UserService, carts CartService, orders OrderService, payments PaymentService, refunds RefundService, loyalty LoyaltyService) AccountService {
+	return &accountService{deps: deps, users: users, carts: carts, orders: orders, payments: payments, refunds: refunds, loyalty: loyalty}
+}
+
+func (s *accountService) ExportData(ctx context.Context, userID string) (*model.AccountExport, error) {
+	if s.deps.DB == nil {
+		return nil, errUserDBUnavailable
+	}
+
+	export := &model.AccountExport{ExportedAt: time.Now()}
+	const query = `SELECT id, wechat_open_id, COALESCE(wechat_union_id, ''), nickname, avatar_url, COALESCE(phone, ''), COALESCE(default_address_id, '')
+		FROM users WHERE id = ? AND deleted_at IS NULL`
+	err := s.deps.DB.QueryRowContext(ctx, query, userID).Scan(
+		&export.Profile.ID,
+		&export.Profile.WeChatOpenID,
+		&export.Profile.WeChatUnionID,
+		&export.Profile.Nickname,
+		&export.Profile.AvatarURL,
+		&export.Profile.Phone,
+		&export.Profile.DefaultAddrID,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeUserNotFound, "user %s not found", userID)
+		}
+		return nil, err
+	}
+
+	if export.Addresses, err = s.users.ListAddresses(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.CartItems, err = s.carts.ListItems(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	export.Orders = []model.Order{}
+	export.Payments = []model.Payment{}
+	export.Refunds = []model.Refund{}
+	listQuery := OrderListQuery{UserID: userID, Limit: maxOrderPageSize}
+	for {
+		page, err := s.orders.ListOrders(ctx, listQuery)
+		if err != nil {
+			return nil, err
+		}
+		export.Orders = append(export.Orders, page.Orders...)
+		if page.NextCursor == "" {
+			break
+		}
+		listQuery.Cursor = page.NextCursor
+	}
+	for _, order := range export.Orders {
+		payments, err := s.payments.ListOrderPayments(ctx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.Payments = append(export.Payments, payments...)
+
+		refunds, err := s.refunds.ListRefunds(ctx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.Refunds = append(export.Refunds, refunds...)
+	}
+
+	if export.Member, err = s.loyalty.GetAccount(ctx, userID); err != nil {
+		return nil, err
+	}
+	export.PointsLedger = []model.PointsEntry{}
+	ledgerQuery := PointsLedgerQuery{UserID: userID, Limit: maxLedgerPageSize}
+	for {
+		page, err := s.loyalty.ListLedger(ctx, ledgerQuery)
+		if err != nil {
+			return nil, err
+		}
+		export.PointsLedger = append(export.PointsLedger, page.Entries...)
+		if page.NextCursor == "" {
+			break
+		}
+		ledgerQuery.Cursor = page.NextCursor
+	}
+
+	return export, nil
+}
+
+func (s *accountService) DeleteAccount(ctx context.Context, userID string) error {
+	if s.deps.DB == nil {
+		return errUserDBUnavailable
+	}
+
+	err := runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, userID).Scan(&exists)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.NewError(model.ErrCodeUserNotFound, "user %s not found", userID)
+			}
+			return err
+		}
+
+		args := []any{userID}
+		for _, status := range openOrderStatuses {
+			args = append(args, status)
+		}
+		var openOrders int
+		query := `SELECT COUNT(*) FROM orders WHERE user_id = ? AND status IN (` + placeholders(len(openOrderStatuses)) + `)`
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&openOrders); err != nil {
+			return err
+		}
+		if openOrders > 0 {
+			return model.NewError(model.ErrCodeOpenOrders, "account has %d unfinished orders, complete or cancel them before deleting the account", openOrders)
+		}
+
+		// openid 列唯一且非空，改写为与用户 ID 绑定的占位值，同一微信号再次登录时将创建新账号。
+		const anonymizeUser = `UPDATE users SET wechat_open_id = CONCAT('deleted:', id), wechat_union_id = NULL, session_key = NULL,
+			nickname = ?, avatar_url = '', phone = NULL, default_address_id = NULL, deleted_at = NOW(), updated_at = NOW() WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, anonymizeUser, deletedNickname, userID); err != nil {
+			return err
+		}
+
+		// 地址可能被历史订单引用，不能删除；仅保留省市区用于财务与税务统计。
+		const anonymizeAddresses = `UPDATE addresses SET recipient = ?, phone = '', detail = '', postal_code = '', is_default = FALSE, updated_at = NOW() WHERE user_id = ?`
+		if _, err := tx.ExecContext(ctx, anonymizeAddresses, deletedNickname, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE user_id = ?`, userID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM phone_verification_codes WHERE user_id = ?`, userID); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deps.Logger.Printf("user %s deleted account, personal data anonymized", userID)
+	return nil
+}
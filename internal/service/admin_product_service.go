@@ -14,7 +14,7 @@ import (
 type AdminProductPayload struct {
 	Name        string
 	Description string
-	Price       float64
+	Price       model.Money
 	Stock       int
 	Tags        []string
 	Images      []string
@@ -169,26 +169,33 @@ func (s *adminProductService) UpdateProduct(ctx context.Context, productID strin
 	return s.GetProduct(ctx, productID)
 }
 
+// DeleteProduct removes a product together with any cart lines still pointing at it.
+// Order items keep their own snapshot, so purchase history is unaffected.
 func (s *adminProductService) DeleteProduct(ctx context.Context, productID string) error {
 	if s.deps.DB == nil {
 		return errAdminProductDBUnavailable
 	}
 
-	const query = `DELETE FROM products WHERE id = ?`
-	result, err := s.deps.DB.ExecContext(ctx, query, productID)
-	if err != nil {
-		return err
-	}
+	return runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE product_id = ?`, productID); err != nil {
+			return err
+		}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if affected == 0 {
-		return fmt.Errorf("product %s not found", productID)
-	}
+		result, err := tx.ExecContext(ctx, `DELETE FROM products WHERE id = ?`, productID)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("product %s not found", productID)
+		}
+
+		return nil
+	})
 }
 
 func (s *adminProductService) SetProductStatus(ctx context.Context, productID string, isActive bool) error {
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/pkg/auth"
+)
+
+// AuthService 负责顾客会话令牌的签发、刷新与校验。
+type AuthService interface {
+	IssueTokens(ctx context.Context, userID string) (auth.TokenPair, error)
+	// RefreshTokens 用刷新令牌换取新的一对令牌，用户已不存在或已注销时拒绝。
+	RefreshTokens(ctx context.Context, refreshToken string) (auth.TokenPair, error)
+	// Authenticate 校验访问令牌并返回其所属用户，用户已注销时拒绝。
+	Authenticate(ctx context.Context, accessToken string) (string, error)
+}
+
+var errAuthTokensUnavailable = errors.New("auth token manager is not configured")
+
+type authService struct {
+	deps Dependencies
+}
+
+// NewAuthService 创建 AuthService 实例。
+func NewAuthService(deps Dependencies) AuthService {
+	return &authService{deps: deps}
+}
+
+func (s *authService) IssueTokens(ctx context.Context, userID string) (auth.TokenPair, error) {
+	if s.deps.Tokens == nil {
+		return auth.TokenPair{}, errAuthTokensUnavailable
+	}
+	return s.deps.Tokens.Issue(auth.AudienceCustomer, userID)
+}
+
+func (s *authService) RefreshTokens(ctx context.Context, refreshToken string) (auth.TokenPair, error) {
+	if s.deps.Tokens == nil {
+		return auth.TokenPair{}, errAuthTokensUnavailable
+	}
+	if s.deps.DB == nil {
+		return auth.TokenPair{}, errUserDBUnavailable
+	}
+
+	claims, err := s.deps.Tokens.Parse(refreshToken, auth.AudienceCustomer, auth.TokenTypeRefresh)
+	if err != nil {
+		return auth.TokenPair{}, unauthorized(err)
+	}
+
+	if err := s.ensureActiveUser(ctx, claims.Subject); err != nil {
+		return auth.TokenPair{}, err
+	}
+
+	return s.deps.Tokens.Issue(auth.AudienceCustomer, claims.Subject)
+}
+
+func (s *authService) Authenticate(ctx context.Context, accessToken string) (string, error) {
+	if s.deps.Tokens == nil {
+		return "", errAuthTokensUnavailable
+	}
+
+	if s.deps.DB == nil {
+		return "", errUserDBUnavailable
+	}
+
+	claims, err := s.deps.Tokens.Parse(accessToken, auth.AudienceCustomer, auth.TokenTypeAccess)
+	if err != nil {
+		return "", unauthorized(err)
+	}
+	// 账号注销后已签发的令牌随即失效。
+	if err := s.ensureActiveUser(ctx, claims.Subject); err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+// ensureActiveUser 确认令牌所属用户仍然存在且未注销。
+func (s *authService) ensureActiveUser(ctx context.Context, userID string) error {
+	var exists int
+	err := s.deps.DB.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL`, userID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.NewError(model.ErrCodeUnauthorized, "user no longer exists")
+		}
+		return err
+	}
+	return nil
+}
+
+// unauthorized 将令牌校验错误转换为 401 领域错误。
+func unauthorized(err error) error {
+	if errors.Is(err, auth.ErrExpiredToken) {
+		return model.NewError(model.ErrCodeUnauthorized, "token is expired")
+	}
+	return model.NewError(model.ErrCodeUnauthorized, "token is invalid")
+}
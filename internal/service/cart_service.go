@@ -15,7 +15,7 @@ type CartService interface {
 	ListItems(ctx context.Context, userID string) ([]model.CartItem, error)
 	AddItem(ctx context.Context, item *model.CartItem) error
 	UpdateItem(ctx context.Context, item *model.CartItem) error
-	RemoveItem(ctx context.Context, itemID string) error
+	RemoveItem(ctx context.Context, userID, itemID string) error
 }
 
 var errCartDBUnavailable = errors.New("cart service database is not configured")
@@ -96,12 +96,12 @@ func (s *cartService) UpdateItem(ctx context.Context, item *model.CartItem) erro
 	if item == nil {
 		return errors.New("cart item is nil")
 	}
-	if item.ID == "" {
-		return errors.New("cart item id is required")
+	if item.ID == "" || item.UserID == "" {
+		return errors.New("cart item id and user id are required")
 	}
 
-	const stmt = `UPDATE cart_items SET quantity = ?, selected = ?, price = ?, updated_at = NOW() WHERE id = ?`
-	res, err := s.deps.DB.ExecContext(ctx, stmt, item.Quantity, item.Selected, item.Price, item.ID)
+	const stmt = `UPDATE cart_items SET quantity = ?, selected = ?, price = ?, updated_at = NOW() WHERE id = ? AND user_id = ?`
+	res, err := s.deps.DB.ExecContext(ctx, stmt, item.Quantity, item.Selected, item.Price, item.ID, item.UserID)
 	if err != nil {
 		return err
 	}
@@ -117,16 +117,16 @@ func (s *cartService) UpdateItem(ctx context.Context, item *model.CartItem) erro
 	return nil
 }
 
-func (s *cartService) RemoveItem(ctx context.Context, itemID string) error {
+func (s *cartService) RemoveItem(ctx context.Context, userID, itemID string) error {
 	if s.deps.DB == nil {
 		return errCartDBUnavailable
 	}
-	if itemID == "" {
-		return errors.New("cart item id is required")
+	if userID == "" || itemID == "" {
+		return errors.New("user id and cart item id are required")
 	}
 
-	const stmt = `DELETE FROM cart_items WHERE id = ?`
-	res, err := s.deps.DB.ExecContext(ctx, stmt, itemID)
+	const stmt = `DELETE FROM cart_items WHERE id = ? AND user_id = ?`
+	res, err := s.deps.DB.ExecContext(ctx, stmt, itemID, userID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+
+	"convenienceStore/internal/model"
+)
+
+// reserveStockTx 在事务内按条件扣减库存，任一商品不足时整体失败并列出缺货商品。
+func reserveStockTx(ctx context.Context, tx *sql.Tx, items []model.OrderItem) error {
+	productIDs, quantities := aggregateItemQuantities(items)
+
+	var shortages []model.InventoryShortage
+	for _, productID := range productIDs {
+		quantity := quantities[productID]
+		res, err := tx.ExecContext(ctx, `UPDATE products SET stock = stock - ? WHERE id = ? AND stock >= ?`, quantity, productID, quantity)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected > 0 {
+			continue
+		}
+
+		var available int
+		if err := tx.QueryRowContext(ctx, `SELECT stock FROM products WHERE id = ?`, productID).Scan(&available); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.NewError(model.ErrCodeProductNotFound, "product %s not found", productID)
+			}
+			return err
+		}
+		shortages = append(shortages, model.InventoryShortage{
+			ProductID: productID,
+			Requested: quantity,
+			Available: available,
+		})
+	}
+
+	if len(shortages) > 0 {
+		ids := make([]string, 0, len(shortages))
+		for _, shortage := range shortages {
+			ids = append(ids, shortage.ProductID)
+		}
+		return model.NewError(model.ErrCodeInventoryShort, "insufficient stock for %s", strings.Join(ids, ", ")).
+			WithDetails(shortages)
+	}
+
+	return nil
+}
+
+// releaseStockTx 将订单占用的库存归还到商品上。
+func releaseStockTx(ctx context.Context, tx *sql.Tx, orderID string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT product_id, quantity FROM order_items WHERE order_id = ?`, orderID)
+	if err != nil {
+		return err
+	}
+
+	var items []model.OrderItem
+	for rows.Next() {
+		var item model.OrderItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return restockItemsTx(ctx, tx, items)
+}
+
+// restockItemsTx 按商品累加归还库存。
+func restockItemsTx(ctx context.Context, tx *sql.Tx, items []model.OrderItem) error {
+	productIDs, quantities := aggregateItemQuantities(items)
+	for _, productID := range productIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE products SET stock = stock + ? WHERE id = ?`, quantities[productID], productID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggregateItemQuantities 合并同一商品的数量，商品 ID 排序后返回以固定加锁次序，避免并发下单互相死锁。
+func aggregateItemQuantities(items []model.OrderItem) ([]string, map[string]int) {
+	var productIDs []string
+	quantities := make(map[string]int, len(items))
+	for _, item := range items {
+		if _, seen := quantities[item.ProductID]; !seen {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+	sort.Strings(productIDs)
+	return productIDs, quantities
+}
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"convenienceStore/internal/model"
+)
+
+// LoyaltyService 提供顾客积分余额、会员等级与积分流水的查询。
+// 积分的发放、扣回与抵扣在订单及退款的状态流转事务内完成，见 earnPointsHook 等辅助函数。
+type LoyaltyService interface {
+	GetAccount(ctx context.Context, userID string) (*model.MemberAccount, error)
+	ListLedger(ctx context.Context, query PointsLedgerQuery) (*model.PointsLedgerPage, error)
+}
+
+// PointsLedgerQuery 描述积分流水的分页条件。
+type PointsLedgerQuery struct {
+	UserID string
+	Cursor string
+	Limit  int
+}
+
+const (
+	defaultEarnPointsPerYuan   = 1
+	defaultRedeemPointsPerYuan = 100
+
+	defaultLedgerPageSize = 20
+	maxLedgerPageSize     = 100
+)
+
+var errLoyaltyDBUnavailable = errors.New("loyalty service database is not configured")
+
+// loyaltyRates 是积分发放与抵扣的换算比例。
+type loyaltyRates struct {
+	earnPerYuan   int64
+	redeemPerYuan int64
+}
+
+// loyaltyRatesOf 读取 loyalty 配置，未配置的项使用默认值。
+func loyaltyRatesOf(deps Dependencies) loyaltyRates {
+	rates := loyaltyRates{earnPerYuan: defaultEarnPointsPerYuan, redeemPerYuan: defaultRedeemPointsPerYuan}
+	if deps.Config == nil {
+		return rates
+	}
+	if deps.Config.Loyalty.EarnPointsPerYuan > 0 {
+		rates.earnPerYuan = deps.Config.Loyalty.EarnPointsPerYuan
+	}
+	if deps.Config.Loyalty.RedeemPointsPerYuan > 0 {
+		rates.redeemPerYuan = deps.Config.Loyalty.RedeemPointsPerYuan
+	}
+	return rates
+}
+
+type loyaltyService struct {
+	deps Dependencies
+}
+
+// NewLoyaltyService 创建 LoyaltyService 实例。
+func NewLoyaltyService(deps Dependencies) LoyaltyService {
+	return &loyaltyService{deps: deps}
+}
+
+// GetAccount 返回顾客的积分余额与会员等级，尚未产生积分的顾客视为普通会员。
+func (s *loyaltyService) GetAccount(ctx context.Context, userID string) (*model.MemberAccount, error) {
+	if s.deps.DB == nil {
+		return nil, errLoyaltyDBUnavailable
+	}
+
+	account := &model.MemberAccount{UserID: userID}
+	err := s.deps.DB.QueryRowContext(ctx, `SELECT balance, tier_points FROM member_accounts WHERE user_id = ?`, userID).
+		Scan(&account.Balance, &account.TierPoints)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	rule := model.TierFor(account.TierPoints)
+	account.Tier = rule.Tier
+	account.Benefits = rule.Benefits
+	if next, ok := model.NextTier(account.TierPoints); ok {
+		account.NextTier = next.Tier
+		account.PointsToNextTier = next.Threshold - account.TierPoints
+	}
+	account.RedeemPointsPerYuan = loyaltyRatesOf(s.deps).redeemPerYuan
+	return account, nil
+}
+
+// ListLedger 按时间倒序分页返回顾客的积分流水。
+func (s *loyaltyService) ListLedger(ctx context.Context, query PointsLedgerQuery) (*model.PointsLedgerPage, error) {
+	if s.deps.DB == nil {
+		return nil, errLoyaltyDBUnavailable
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultLedgerPageSize
+	}
+	if limit > maxLedgerPageSize {
+		limit = maxLedgerPageSize
+	}
+
+	stmt := `SELECT ` + pointsEntryColumns + ` FROM points_ledger WHERE user_id = ?`
+	args := []any{query.UserID}
+	if query.Cursor != "" {
+		_, idText, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		id, err := strconv.ParseInt(idText, 10, 64)
+		if err != nil {
+			return nil, model.NewError(model.ErrCodeInvalidParameter, "invalid cursor")
+		}
+		stmt += ` AND id < ?`
+		args = append(args, id)
+	}
+	stmt += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := s.deps.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &model.PointsLedgerPage{Entries: []model.PointsEntry{}}
+	for rows.Next() {
+		entry, err := scanPointsEntryRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Entries = append(page.Entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		last := page.Entries[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, strconv.FormatInt(last.ID, 10))
+	}
+	return page, nil
+}
+
+const pointsEntryColumns = `id, user_id, order_id, refund_id, type, points, balance_after, base_amount, reason, created_at`
+
+func scanPointsEntryRow(scanner interface {
+	Scan(dest ...any) error
+}) (*model.PointsEntry, error) {
+	var (
+		entry  model.PointsEntry
+		reason sql.NullString
+	)
+	if err := scanner.Scan(&entry.ID, &entry.UserID, &entry.OrderID, &entry.RefundID, &entry.Type, &entry.Points, &entry.Balance, &entry.BaseAmount, &reason, &entry.CreatedAt); err != nil {
+		return nil, err
+	}
+	entry.Reason = reason.String
+	return &entry, nil
+}
+
+// lockMemberAccountTx 锁定顾客的会员账户并返回可用积分与成长积分，首次使用积分时创建账户。
+// 同一顾客的积分变动因此串行执行，流水中的变动后余额与账户保持一致。
+func lockMemberAccountTx(ctx context.Context, tx *sql.Tx, userID string) (int64, int64, error) {
+	const ensure = `INSERT INTO member_accounts (user_id, tier) VALUES (?, ?) ON DUPLICATE KEY UPDATE user_id = user_id`
+	if _, err := tx.ExecContext(ctx, ensure, userID, model.MemberTierStandard); err != nil {
+		return 0, 0, err
+	}
+
+	var balance, tierPoints int64
+	const query = `SELECT balance, tier_points FROM member_accounts WHERE user_id = ? FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&balance, &tierPoints); err != nil {
+		return 0, 0, err
+	}
+	return balance, tierPoints, nil
+}
+
+// appendPointsTx 追加一条积分流水并同步账户余额、成长积分与等级，tierDelta 为成长积分的变动。
+// 同一订单、类型与退款的流水只记一次，重复调用不会重复记账。
+func appendPointsTx(ctx context.Context, tx *sql.Tx, entry *model.PointsEntry, tierDelta int64) error {
+	balance, tierPoints, err := lockMemberAccountTx(ctx, tx, entry.UserID)
+	if err != nil {
+		return err
+	}
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM points_ledger WHERE order_id = ? AND type = ? AND refund_id = ?`, entry.OrderID, entry.Type, entry.RefundID).Scan(&exists)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	entry.Balance = balance + entry.Points
+	tierPoints += tierDelta
+	if tierPoints < 0 {
+		tierPoints = 0
+	}
+
+	const insert = `INSERT INTO points_ledger (user_id, order_id, refund_id, type, points, balance_after, base_amount, reason) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, insert, entry.UserID, entry.OrderID, entry.RefundID, entry.Type, entry.Points, entry.Balance, entry.BaseAmount, nullableString(entry.Reason)); err != nil {
+		return err
+	}
+
+	const update = `UPDATE member_accounts SET balance = ?, tier_points = ?, tier = ?, updated_at = NOW() WHERE user_id = ?`
+	_, err = tx.ExecContext(ctx, update, entry.Balance, tierPoints, model.TierFor(tierPoints).Tier, entry.UserID)
+	return err
+}
+
+// redeemPointsTx 校验订单要抵扣的积分并计算抵扣金额，order.Total 需为抵扣前的商品金额。
+// 抵扣金额不超过会员等级允许的比例，实际消耗的积分按抵扣金额向上取整。
+func redeemPointsTx(ctx context.Context, tx *sql.Tx, rates loyaltyRates, order *model.Order) error {
+	if order.PointsUsed < 0 {
+		return model.NewError(model.ErrCodeInvalidParameter, "redeem points must not be negative")
+	}
+
+	balance, tierPoints, err := lockMemberAccountTx(ctx, tx, order.UserID)
+	if err != nil {
+		return err
+	}
+
+	discount := model.Money(order.PointsUsed * 100 / rates.redeemPerYuan)
+	if discount <= 0 {
+		return model.NewError(model.ErrCodeInvalidParameter, "at least %d points are required to redeem", (rates.redeemPerYuan+99)/100)
+	}
+	rule := model.TierFor(tierPoints)
+	maxDiscount := model.Money(order.Total.Fen() * rule.Benefits.MaxRedeemPercent / 100)
+	if discount > maxDiscount {
+		return model.NewError(model.ErrCodeInvalidParameter, "%s members can redeem at most %d%% of the order amount", rule.Tier, rule.Benefits.MaxRedeemPercent).
+			WithDetails(map[string]any{"max_points": maxDiscount.Fen() * rates.redeemPerYuan / 100})
+	}
+
+	used := (discount.Fen()*rates.redeemPerYuan + 99) / 100
+	if used > balance {
+		return model.NewError(model.ErrCodePointsShort, "redeeming %d points exceeds the available balance of %d", used, balance).
+			WithDetails(map[string]any{"balance": balance})
+	}
+
+	order.PointsUsed = used
+	order.PointsDiscount = discount
+	order.Total -= discount
+	return nil
+}
+
+// earnPointsHook 在订单完成的事务内发放积分：以实付金额扣除已退款部分为基数，乘以会员等级倍率。
+func earnPointsHook(ctx context.Context, rates loyaltyRates, orderID string) statusHook {
+	return func(tx *sql.Tx, from model.OrderStatus) error {
+		var (
+			userID   string
+			total    model.Money
+			refunded model.Money
+		)
+		const query = `SELECT o.user_id, o.total, COALESCE((SELECT SUM(r.amount) FROM refunds r WHERE r.order_id = o.id AND r.status = ?), 0) FROM orders o WHERE o.id = ?`
+		if err := tx.QueryRowContext(ctx, query, model.RefundStatusSucceeded, orderID).Scan(&userID, &total, &refunded); err != nil {
+			return err
+		}
+		base := total - refunded
+		if base <= 0 {
+			return nil
+		}
+
+		_, tierPoints, err := lockMemberAccountTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		rule := model.TierFor(tierPoints)
+		points := base.Fen() * rates.earnPerYuan * rule.Benefits.EarnMultiplierPercent / (100 * 100)
+		if points <= 0 {
+			return nil
+		}
+
+		return appendPointsTx(ctx, tx, &model.PointsEntry{
+			UserID:     userID,
+			OrderID:    orderID,
+			Type:       model.PointsEntryEarn,
+			Points:     points,
+			BaseAmount: base,
+			Reason:     fmt.Sprintf("order completed, %s tier earns %d%%", rule.Tier, rule.Benefits.EarnMultiplierPercent),
+		}, points)
+	}
+}
+
+// reverseRefundPointsTx 在退款成功的事务内按退款金额占比扣回已发放的积分并退回抵扣积分，
+// full 为 true 表示订单已全额退款，此时扣回或退回全部剩余积分以消除取整误差。
+func reverseRefundPointsTx(ctx context.Context, tx *sql.Tx, refund *model.Refund, full bool) error {
+	var (
+		userID     string
+		total      model.Money
+		pointsUsed int64
+	)
+	if err := tx.QueryRowContext(ctx, `SELECT user_id, total, points_used FROM orders WHERE id = ?`, refund.OrderID).Scan(&userID, &total, &pointsUsed); err != nil {
+		return err
+	}
+
+	var (
+		earned   int64
+		earnBase model.Money
+	)
+	err := tx.QueryRowContext(ctx, `SELECT points, base_amount FROM points_ledger WHERE order_id = ? AND type = ?`, refund.OrderID, model.PointsEntryEarn).Scan(&earned, &earnBase)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// 订单完成前的退款无需扣回，完成时发放的积分已扣除退款金额。
+	if earned > 0 && earnBase > 0 {
+		reversed, err := sumPointsTx(ctx, tx, refund.OrderID, model.PointsEntryEarnReversal)
+		if err != nil {
+			return err
+		}
+		points := proportionalPoints(earned, -reversed, refund.Amount, earnBase, full)
+		if points > 0 {
+			err := appendPointsTx(ctx, tx, &model.PointsEntry{
+				UserID:     userID,
+				OrderID:    refund.OrderID,
+				RefundID:   refund.ID,
+				Type:       model.PointsEntryEarnReversal,
+				Points:     -points,
+				BaseAmount: refund.Amount,
+				Reason:     fmt.Sprintf("refund %s of %s", refund.ID, refund.Amount),
+			}, -points)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if pointsUsed > 0 && total > 0 {
+		returned, err := sumPointsTx(ctx, tx, refund.OrderID, model.PointsEntryRedeemReturn)
+		if err != nil {
+			return err
+		}
+		points := proportionalPoints(pointsUsed, returned, refund.Amount, total, full)
+		if points > 0 {
+			return appendPointsTx(ctx, tx, &model.PointsEntry{
+				UserID:     userID,
+				OrderID:    refund.OrderID,
+				RefundID:   refund.ID,
+				Type:       model.PointsEntryRedeemReturn,
+				Points:     points,
+				BaseAmount: refund.Amount,
+				Reason:     fmt.Sprintf("refund %s of %s", refund.ID, refund.Amount),
+			}, 0)
+		}
+	}
+	return nil
+}
+
+// returnRedeemedPointsTx 在订单取消的事务内退回结算时抵扣的全部积分。
+func returnRedeemedPointsTx(ctx context.Context, tx *sql.Tx, orderID string) error {
+	var (
+		userID     string
+		pointsUsed int64
+		discount   model.Money
+	)
+	if err := tx.QueryRowContext(ctx, `SELECT user_id, points_used, points_discount FROM orders WHERE id = ?`, orderID).Scan(&userID, &pointsUsed, &discount); err != nil {
+		return err
+	}
+	if pointsUsed <= 0 {
+		return nil
+	}
+	return appendPointsTx(ctx, tx, &model.PointsEntry{
+		UserID:     userID,
+		OrderID:    orderID,
+		Type:       model.PointsEntryRedeemReturn,
+		Points:     pointsUsed,
+		BaseAmount: discount,
+		Reason:     "order cancelled",
+	}, 0)
+}
+
+// sumPointsTx 返回订单某类积分流水的变动合计。
+func sumPointsTx(ctx context.Context, tx *sql.Tx, orderID string, entryType model.PointsEntryType) (int64, error) {
+	var sum int64
+	const query = `SELECT COALESCE(SUM(points), 0) FROM points_ledger WHERE order_id = ? AND type = ?`
+	err := tx.QueryRowContext(ctx, query, orderID, entryType).Scan(&sum)
+	return sum, err
+}
+
+// proportionalPoints 按 amount 占 base 的比例折算 total 积分，结果不超过尚未处理的 total-done。
+func proportionalPoints(total, done int64, amount, base model.Money, full bool) int64 {
+	remaining := total - done
+	if remaining <= 0 {
+		return 0
+	}
+	if full {
+		return remaining
+	}
+	points := total * amount.Fen() / base.Fen()
+	if points > remaining {
+		points = remaining
+	}
+	return points
+}
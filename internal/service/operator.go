@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	"convenienceStore/internal/model"
+)
+
+// Operator 描述发起当前操作的主体，用于订单事件等审计记录。
+type Operator struct {
+	Actor  string
+	Source model.OrderEventSource
+}
+
+type operatorContextKey struct{}
+
+// WithOperator 将操作主体写入上下文，供服务层记录审计信息。
+func WithOperator(ctx context.Context, operator Operator) context.Context {
+	return context.WithValue(ctx, operatorContextKey{}, operator)
+}
+
+// OperatorFromContext 读取上下文中的操作主体。
+func OperatorFromContext(ctx context.Context) (Operator, bool) {
+	operator, ok := ctx.Value(operatorContextKey{}).(Operator)
+	return operator, ok
+}
+
+// resolveOperator 优先使用上下文中的操作主体，缺省时回落到调用方给出的来源。
+func resolveOperator(ctx context.Context, fallback model.OrderEventSource) Operator {
+	operator, ok := OperatorFromContext(ctx)
+	if !ok {
+		return Operator{Source: fallback}
+	}
+	if operator.Source == "" {
+		operator.Source = fallback
+	}
+	return operator
+}
@@ -4,8 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
-	"math"
+	"strings"
 	"time"
 
 	"convenienceStore/internal/model"
@@ -16,14 +15,42 @@ import (
 // OrderService 调度订单全生命周期的业务操作。
 type OrderService interface {
 	CreateOrder(ctx context.Context, order *model.Order) (*model.Order, error)
+	Checkout(ctx context.Context, userID, addressID string, redeemPoints int64) (*model.Order, error)
 	GetOrder(ctx context.Context, orderID string) (*model.Order, error)
-	PayOrder(ctx context.Context, orderID string) (*model.PaymentIntent, error)
-	CancelOrder(ctx context.Context, orderID string) error
+	OrderOwner(ctx context.Context, orderID string) (string, error)
+	ListOrders(ctx context.Context, query OrderListQuery) (*model.OrderPage, error)
+	PayOrder(ctx context.Context, orderID, provider string) (*model.PaymentIntent, error)
+	CancelOrder(ctx context.Context, orderID, reason string) error
 	ShipOrder(ctx context.Context, orderID string) error
 	CompleteOrder(ctx context.Context, orderID string) error
 	MarkPaid(ctx context.Context, orderID string) error
+	CancelExpiredOrders(ctx context.Context, createdBefore time.Time, limit int) (int, error)
+	GetTimeline(ctx context.Context, orderID string) ([]model.OrderEvent, error)
 }
 
+// OrderListQuery 描述订单列表的筛选与分页条件，零值字段表示不限制。
+type OrderListQuery struct {
+	UserID      string
+	Statuses    []model.OrderStatus
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	AddressID   string
+	Cursor      string
+	Limit       int
+}
+
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+
+	// prepayMaxTTL 是渠道预支付交易的最长有效期，以微信支付 prepay_id 的两小时为准。
+	prepayMaxTTL = 2 * time.Hour
+	// prepayReuseMargin 保证复用的预支付交易在客户端完成支付前不会过期。
+	prepayReuseMargin = time.Minute
+	// expiredOrderRetryBackoff 是超时扫描跳过某个订单后，再次尝试取消该订单前的最短间隔。
+	expiredOrderRetryBackoff = 5 * time.Minute
+)
+
 var errOrderDBUnavailable = errors.New("order service database is not configured")
 
 type orderService struct {
@@ -50,56 +77,155 @@ func (s *orderService) CreateOrder(ctx context.Context, order *model.Order) (*mo
 		order.ID = uid.New("ord_")
 	}
 
-	var total float64
-	for i := range order.Items {
-		item := &order.Items[i]
+	if len(order.Items) == 0 {
+		return nil, model.NewError(model.ErrCodeInvalidParameter, "order must contain at least one item")
+	}
+	for _, item := range order.Items {
 		if item.ProductID == "" {
 			return nil, errors.New("order item product id is required")
 		}
 		if item.Quantity <= 0 {
 			return nil, errors.New("order item quantity must be positive")
 		}
-		if item.Price == 0 {
-			const priceQuery = `SELECT price FROM products WHERE id = ?`
-			if err := s.deps.DB.QueryRowContext(ctx, priceQuery, item.ProductID).Scan(&item.Price); err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
-					return nil, fmt.Errorf("product %s not found", item.ProductID)
-				}
-				return nil, err
-			}
-		}
-		total += item.Price * float64(item.Quantity)
 	}
-	order.Total = total
 
 	now := time.Now()
 	order.CreatedAt = now
 	order.UpdatedAt = now
 	order.Status = model.OrderStatusPendingPayment
 
-	tx, err := s.deps.DB.BeginTx(ctx, nil)
+	err := runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		if order.AddressID != "" {
+			var exists int
+			if err := tx.QueryRowContext(ctx, `SELECT 1 FROM addresses WHERE id = ? AND user_id = ?`, order.AddressID, order.UserID).Scan(&exists); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return model.NewError(model.ErrCodeAddressNotFound, "address %s not found", order.AddressID)
+				}
+				return err
+			}
+		}
+		return s.insertOrderTx(ctx, tx, order)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
+
+	return order, nil
+}
+
+// insertOrderTx 按商品表定价、抵扣积分、扣减库存并写入订单及明细，任一校验失败时不会落下任何记录。
+// order.PointsUsed 大于零时按其抵扣订单金额。
+func (s *orderService) insertOrderTx(ctx context.Context, tx *sql.Tx, order *model.Order) error {
+	if err := priceItemsTx(ctx, tx, order.Items); err != nil {
+		return err
+	}
+
+	var total model.Money
+	for _, item := range order.Items {
+		total += item.Price.Mul(item.Quantity)
+	}
+	order.Total = total
+	order.PointsDiscount = 0
+	if order.PointsUsed != 0 {
+		if err := redeemPointsTx(ctx, tx, loyaltyRatesOf(s.deps), order); err != nil {
+			return err
 		}
-	}()
+	}
 
-	const orderInsert = `INSERT INTO orders (id, user_id, status, total, address_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	if _, err = tx.ExecContext(ctx, orderInsert, order.ID, order.UserID, order.Status, order.Total, order.AddressID, order.CreatedAt, order.UpdatedAt); err != nil {
-		return nil, err
+	if err := reserveStockTx(ctx, tx, order.Items); err != nil {
+		return err
 	}
 
-	const itemInsert = `INSERT INTO order_items (order_id, product_id, quantity, price) VALUES (?, ?, ?, ?)`
+	const orderInsert = `INSERT INTO orders (id, user_id, status, total, points_used, points_discount, address_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, orderInsert, order.ID, order.UserID, order.Status, order.Total, order.PointsUsed, order.PointsDiscount, order.AddressID, order.CreatedAt, order.UpdatedAt); err != nil {
+		return err
+	}
+
+	const itemInsert = `INSERT INTO order_items (order_id, product_id, quantity, price, product_name, product_image, product_tags) VALUES (?, ?, ?, ?, ?, ?, ?)`
 	for _, item := range order.Items {
-		if _, err = tx.ExecContext(ctx, itemInsert, order.ID, item.ProductID, item.Quantity, item.Price); err != nil {
-			return nil, err
+		tagsJSON, err := stringSliceToJSONArg(item.ProductTags)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, itemInsert, order.ID, item.ProductID, item.Quantity, item.Price, item.ProductName, nullableString(item.ProductImage), tagsJSON); err != nil {
+			return err
 		}
 	}
 
-	err = tx.Commit()
+	if order.PointsUsed > 0 {
+		err := appendPointsTx(ctx, tx, &model.PointsEntry{
+			UserID:     order.UserID,
+			OrderID:    order.ID,
+			Type:       model.PointsEntryRedeem,
+			Points:     -order.PointsUsed,
+			BaseAmount: order.PointsDiscount,
+			Reason:     "redeemed at checkout",
+		}, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return insertOrderEventTx(ctx, tx, model.OrderEvent{
+		OrderID:  order.ID,
+		ToStatus: order.Status,
+	}, resolveOperator(ctx, model.OrderEventSourceCustomer))
+}
+
+// Checkout 将用户购物车中已勾选的商品按当前售价生成订单，并在同一事务内清除对应购物车条目。
+// redeemPoints 大于零时以积分抵扣订单金额。
+func (s *orderService) Checkout(ctx context.Context, userID, addressID string, redeemPoints int64) (*model.Order, error) {
+	if s.deps.DB == nil {
+		return nil, errOrderDBUnavailable
+	}
+	if userID == "" {
+		return nil, errors.New("user id is required")
+	}
+	if addressID == "" {
+		return nil, errors.New("address id is required")
+	}
+
+	now := time.Now()
+	order := &model.Order{
+		ID:         uid.New("ord_"),
+		UserID:     userID,
+		AddressID:  addressID,
+		Status:     model.OrderStatusPendingPayment,
+		PointsUsed: redeemPoints,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	err := runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, `SELECT 1 FROM addresses WHERE id = ? AND user_id = ?`, addressID, userID).Scan(&exists); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.NewError(model.ErrCodeAddressNotFound, "address %s not found", addressID)
+			}
+			return err
+		}
+
+		cartItemIDs, items, err := selectedCartItemsTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return model.NewError(model.ErrCodeCartEmpty, "no cart items selected for user %s", userID)
+		}
+
+		order.Items = items
+
+		if err := s.insertOrderTx(ctx, tx, order); err != nil {
+			return err
+		}
+
+		args := make([]any, 0, len(cartItemIDs))
+		for _, id := range cartItemIDs {
+			args = append(args, id)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM cart_items WHERE id IN (`+placeholders(len(args))+`)`, args...)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +233,101 @@ func (s *orderService) CreateOrder(ctx context.Context, order *model.Order) (*mo
 	return order, nil
 }
 
+// selectedCartItemsTx 锁定用户已勾选的购物车条目并转换为订单明细，价格留待下单时按商品表确定。
+func selectedCartItemsTx(ctx context.Context, tx *sql.Tx, userID string) ([]string, []model.OrderItem, error) {
+	const query = `SELECT id, product_id, quantity FROM cart_items WHERE user_id = ? AND selected = TRUE ORDER BY created_at FOR UPDATE`
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var (
+		cartItemIDs []string
+		items       []model.OrderItem
+	)
+	for rows.Next() {
+		var (
+			cartItemID string
+			item       model.OrderItem
+		)
+		if err := rows.Scan(&cartItemID, &item.ProductID, &item.Quantity); err != nil {
+			return nil, nil, err
+		}
+		if item.Quantity <= 0 {
+			return nil, nil, model.NewError(model.ErrCodeInvalidParameter, "cart item %s has invalid quantity %d", cartItemID, item.Quantity)
+		}
+		cartItemIDs = append(cartItemIDs, cartItemID)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return cartItemIDs, items, nil
+}
+
+// priceItemsTx 以商品表的售价覆盖订单明细价格，并记录商品名称、主图与标签快照。
+// 客户端传入的非零价格仅视为预期价格，与实际售价不一致时返回 ErrCodePriceChanged，便于前端刷新后重新确认。
+func priceItemsTx(ctx context.Context, tx *sql.Tx, items []model.OrderItem) error {
+	var (
+		inactive []string
+		changes  []model.PriceChange
+	)
+	for i := range items {
+		item := &items[i]
+
+		var (
+			name     string
+			price    model.Money
+			tags     sql.NullString
+			images   sql.NullString
+			isActive bool
+		)
+		const query = `SELECT name, price, tags, images, is_active FROM products WHERE id = ?`
+		if err := tx.QueryRowContext(ctx, query, item.ProductID).Scan(&name, &price, &tags, &images, &isActive); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.NewError(model.ErrCodeProductNotFound, "product %s not found", item.ProductID)
+			}
+			return err
+		}
+		if !isActive {
+			inactive = append(inactive, item.ProductID)
+			continue
+		}
+
+		if item.Price != 0 && item.Price != price {
+			changes = append(changes, model.PriceChange{
+				ProductID: item.ProductID,
+				Expected:  item.Price,
+				Actual:    price,
+			})
+		}
+		item.Price = price
+		item.ProductName = name
+		item.ProductTags = parseStringArray(tags)
+		item.ProductImage = ""
+		if parsedImages := parseStringArray(images); len(parsedImages) > 0 {
+			item.ProductImage = parsedImages[0]
+		}
+	}
+
+	if len(inactive) > 0 {
+		return model.NewError(model.ErrCodeProductInactive, "products %s are no longer on sale", strings.Join(inactive, ", ")).
+			WithDetails(map[string]any{"product_ids": inactive})
+	}
+	if len(changes) > 0 {
+		ids := make([]string, 0, len(changes))
+		for _, change := range changes {
+			ids = append(ids, change.ProductID)
+		}
+		return model.NewError(model.ErrCodePriceChanged, "prices changed for %s", strings.Join(ids, ", ")).
+			WithDetails(changes)
+	}
+
+	return nil
+}
+
 func (s *orderService) GetOrder(ctx context.Context, orderID string) (*model.Order, error) {
 	if s.deps.DB == nil {
 		return nil, errOrderDBUnavailable
@@ -115,105 +336,624 @@ func (s *orderService) GetOrder(ctx context.Context, orderID string) (*model.Ord
 		return nil, errors.New("order id is required")
 	}
 
-	const orderQuery = `SELECT id, user_id, status, total, address_id, created_at, updated_at FROM orders WHERE id = ?`
-	var order model.Order
-	if err := s.deps.DB.QueryRowContext(ctx, orderQuery, orderID).Scan(&order.ID, &order.UserID, &order.Status, &order.Total, &order.AddressID, &order.CreatedAt, &order.UpdatedAt); err != nil {
+	const orderQuery = `SELECT ` + orderColumns + ` FROM orders WHERE id = ?`
+	order, err := scanOrderRow(s.deps.DB.QueryRowContext(ctx, orderQuery, orderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+		}
+		return nil, err
+	}
+
+	itemsByOrder, err := s.loadOrderItems(ctx, []string{orderID})
+	if err != nil {
+		return nil, err
+	}
+	order.Items = itemsByOrder[orderID]
+
+	return order, nil
+}
+
+// OrderOwner 返回订单所属用户，用于接口层校验访问权限。
+func (s *orderService) OrderOwner(ctx context.Context, orderID string) (string, error) {
+	if s.deps.DB == nil {
+		return "", errOrderDBUnavailable
+	}
+
+	var userID string
+	err := s.deps.DB.QueryRowContext(ctx, `SELECT user_id FROM orders WHERE id = ?`, orderID).Scan(&userID)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("order %s not found", orderID)
+			return "", model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+// ListOrders 按筛选条件分页返回订单，按创建时间倒序排列，明细通过一次批量查询补齐。
+func (s *orderService) ListOrders(ctx context.Context, query OrderListQuery) (*model.OrderPage, error) {
+	if s.deps.DB == nil {
+		return nil, errOrderDBUnavailable
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultOrderPageSize
+	}
+	if limit > maxOrderPageSize {
+		limit = maxOrderPageSize
+	}
+
+	var (
+		conditions []string
+		args       []any
+	)
+	if query.UserID != "" {
+		conditions = append(conditions, `user_id = ?`)
+		args = append(args, query.UserID)
+	}
+	if len(query.Statuses) > 0 {
+		for _, status := range query.Statuses {
+			if !status.Valid() {
+				return nil, model.NewError(model.ErrCodeInvalidParameter, "invalid order status %s", status)
+			}
+			args = append(args, status)
+		}
+		conditions = append(conditions, `status IN (`+placeholders(len(query.Statuses))+`)`)
+	}
+	if !query.CreatedFrom.IsZero() {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, query.CreatedFrom)
+	}
+	if !query.CreatedTo.IsZero() {
+		conditions = append(conditions, `created_at < ?`)
+		args = append(args, query.CreatedTo)
+	}
+	if query.AddressID != "" {
+		conditions = append(conditions, `address_id = ?`)
+		args = append(args, query.AddressID)
+	}
+	if query.Cursor != "" {
+		createdAt, id, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, `(created_at < ? OR (created_at = ? AND id < ?))`)
+		args = append(args, createdAt, createdAt, id)
+	}
+
+	stmt := `SELECT ` + orderColumns + ` FROM orders`
+	if len(conditions) > 0 {
+		stmt += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	stmt += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := s.deps.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &model.OrderPage{Orders: []model.Order{}}
+	for rows.Next() {
+		order, err := scanOrderRow(rows)
+		if err != nil {
+			return nil, err
 		}
+		page.Orders = append(page.Orders, *order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Orders) > limit {
+		page.Orders = page.Orders[:limit]
+		last := page.Orders[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	orderIDs := make([]string, 0, len(page.Orders))
+	for _, order := range page.Orders {
+		orderIDs = append(orderIDs, order.ID)
+	}
+	itemsByOrder, err := s.loadOrderItems(ctx, orderIDs)
+	if err != nil {
 		return nil, err
 	}
+	for i := range page.Orders {
+		page.Orders[i].Items = itemsByOrder[page.Orders[i].ID]
+	}
+
+	return page, nil
+}
+
+// loadOrderItems 批量读取多个订单的明细，按订单 ID 分组返回。
+func (s *orderService) loadOrderItems(ctx context.Context, orderIDs []string) (map[string][]model.OrderItem, error) {
+	itemsByOrder := make(map[string][]model.OrderItem, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return itemsByOrder, nil
+	}
+
+	args := make([]any, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		args = append(args, id)
+	}
 
-	const itemsQuery = `SELECT product_id, quantity, price FROM order_items WHERE order_id = ?`
-	rows, err := s.deps.DB.QueryContext(ctx, itemsQuery, orderID)
+	itemsQuery := `SELECT order_id, product_id, quantity, price, product_name, product_image, product_tags FROM order_items WHERE order_id IN (` + placeholders(len(args)) + `) ORDER BY id`
+	rows, err := s.deps.DB.QueryContext(ctx, itemsQuery, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var item model.OrderItem
-		if err := rows.Scan(&item.ProductID, &item.Quantity, &item.Price); err != nil {
+		var (
+			orderID string
+			item    model.OrderItem
+			image   sql.NullString
+			tags    sql.NullString
+		)
+		if err := rows.Scan(&orderID, &item.ProductID, &item.Quantity, &item.Price, &item.ProductName, &image, &tags); err != nil {
 			return nil, err
 		}
-		order.Items = append(order.Items, item)
+		item.ProductImage = image.String
+		item.ProductTags = parseStringArray(tags)
+		itemsByOrder[orderID] = append(itemsByOrder[orderID], item)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
+	return itemsByOrder, nil
+}
+
+const orderColumns = `id, user_id, status, total, points_used, points_discount, address_id, created_at, updated_at`
+
+func scanOrderRow(scanner interface {
+	Scan(dest ...any) error
+}) (*model.Order, error) {
+	var (
+		order     model.Order
+		addressID sql.NullString
+	)
+	if err := scanner.Scan(&order.ID, &order.UserID, &order.Status, &order.Total, &order.PointsUsed, &order.PointsDiscount, &addressID, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		return nil, err
+	}
+	order.AddressID = addressID.String
 	return &order, nil
 }
 
-func (s *orderService) PayOrder(ctx context.Context, orderID string) (*model.PaymentIntent, error) {
+// PayOrder 以顾客选择的支付方式为待支付订单发起支付，provider 为空时使用微信支付。
+// 同一支付方式下仍在有效期内的交易会被复用，只重新生成客户端参数，避免重复下单。
+func (s *orderService) PayOrder(ctx context.Context, orderID, provider string) (*model.PaymentIntent, error) {
 	if s.deps.DB == nil {
 		return nil, errOrderDBUnavailable
 	}
+	if provider == "" {
+		provider = model.PaymentProviderWeChat
+	}
+	gateway, ok := s.deps.Payments.Get(provider)
+	if !ok {
+		return nil, model.NewError(model.ErrCodeInvalidParameter, "unsupported payment provider %s", provider)
+	}
 
-	const amountQuery = `SELECT total FROM orders WHERE id = ?`
-	var total float64
-	if err := s.deps.DB.QueryRowContext(ctx, amountQuery, orderID).Scan(&total); err != nil {
+	const orderQuery = `SELECT o.status, o.total, o.created_at, u.wechat_open_id FROM orders o JOIN users u ON u.id = o.user_id WHERE o.id = ?`
+	var (
+		status    model.OrderStatus
+		total     model.Money
+		createdAt time.Time
+		openID    string
+	)
+	if err := s.deps.DB.QueryRowContext(ctx, orderQuery, orderID).Scan(&status, &total, &createdAt, &openID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("order %s not found", orderID)
+			return nil, model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
 		}
 		return nil, err
 	}
+	if status != model.OrderStatusPendingPayment {
+		return nil, invalidTransitionError(orderID, status, model.OrderStatusPaid)
+	}
 
-	amount := int64(math.Round(total * 100))
-	resp, err := s.deps.Payment.CreateOrder(ctx, payment.OrderRequest{
-		OrderID: orderID,
-		Amount:  amount,
-		Subject: "Convenience Store Order",
+	now := time.Now()
+	existing, err := findReusablePayment(ctx, s.deps.DB, orderID, provider, total, now.Add(prepayReuseMargin))
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		checkout, err := gateway.Resume(ctx, existing.PrepayID)
+		if err != nil {
+			return nil, model.NewError(model.ErrCodePaymentFailed, "resume %s payment for order %s: %v", provider, orderID, err)
+		}
+		return &model.PaymentIntent{
+			OrderID:     orderID,
+			PaymentID:   existing.ID,
+			Provider:    existing.Provider,
+			Credentials: checkout.Credentials,
+			ExpiresAt:   existing.ExpiresAt,
+		}, nil
+	}
+
+	timeout, err := orderPaymentTimeout(s.deps)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := createdAt.Add(timeout)
+	if latest := now.Add(prepayMaxTTL); expiresAt.After(latest) {
+		expiresAt = latest
+	}
+	if !expiresAt.After(now.Add(prepayReuseMargin)) {
+		return nil, model.NewError(model.ErrCodeInvalidTransition, "order %s payment window has expired", orderID)
+	}
+
+	checkout, err := gateway.Prepare(ctx, payment.OrderRequest{
+		OrderID:     orderID,
+		Amount:      total.Fen(),
+		Subject:     "Convenience Store Order",
+		PayerOpenID: openID,
+		ExpireAt:    expiresAt,
 	})
 	if err != nil {
+		return nil, model.NewError(model.ErrCodePaymentFailed, "create %s payment for order %s: %v", provider, orderID, err)
+	}
+
+	record := &model.Payment{
+		ID:        uid.New("pay_"),
+		OrderID:   orderID,
+		Provider:  provider,
+		PrepayID:  checkout.Reference,
+		Amount:    total,
+		Status:    model.PaymentStatusCreated,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if !checkout.ExpiresAt.IsZero() {
+		record.ExpiresAt = &checkout.ExpiresAt
+	}
+	if err := insertPayment(ctx, s.deps.DB, record); err != nil {
 		return nil, err
 	}
 
 	return &model.PaymentIntent{
 		OrderID:     orderID,
-		Provider:    "wechat",
-		Credentials: resp.ClientConfig(),
+		PaymentID:   record.ID,
+		Provider:    record.Provider,
+		Credentials: checkout.Credentials,
+		ExpiresAt:   record.ExpiresAt,
 	}, nil
 }
 
-func (s *orderService) CancelOrder(ctx context.Context, orderID string) error {
-	return s.updateStatus(ctx, orderID, model.OrderStatusCancelled)
+// CancelOrder 取消待支付订单。取消前在支付渠道侧关闭预支付交易；若渠道报告顾客已经付款，
+// 先将该交易入账再返回冲突错误，库存与抵扣积分不会被归还。
+func (s *orderService) CancelOrder(ctx context.Context, orderID, reason string) error {
+	if s.deps.DB == nil {
+		return errOrderDBUnavailable
+	}
+	paid, err := s.closeProviderTrades(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if paid != nil {
+		if err := s.paymentLedger().applyTransaction(ctx, *paid, model.OrderEventSourceSystem); err != nil {
+			return err
+		}
+		return model.NewError(model.ErrCodeInvalidTransition, "order %s has already been paid via %s", orderID, paid.Provider).
+			WithDetails(map[string]any{"provider": paid.Provider})
+	}
+
+	change := statusChange{Source: model.OrderEventSourceCustomer, Reason: reason}
+	return s.updateStatus(ctx, orderID, model.OrderStatusCancelled, change, cancellationHook(ctx, orderID))
+}
+
+// cancellationHook 在取消订单的事务内归还库存、退回抵扣积分并关闭未完成的支付记录。
+func cancellationHook(ctx context.Context, orderID string) statusHook {
+	return func(tx *sql.Tx, from model.OrderStatus) error {
+		if err := releaseStockTx(ctx, tx, orderID); err != nil {
+			return err
+		}
+		if err := returnRedeemedPointsTx(ctx, tx, orderID); err != nil {
+			return err
+		}
+		return closePendingPaymentsTx(ctx, tx, orderID)
+	}
+}
+
+// CancelExpiredOrders 取消创建时间早于 createdBefore 且仍未支付的订单，返回实际取消的数量。
+// 取消前先向支付渠道查单并关闭预支付交易，渠道报告已支付的订单改为入账；查单或关单失败的订单记录本次尝试时间，
+// 排到队尾并在 expiredOrderRetryBackoff 之后重试，不会挡住其后的订单。存在待复核交易的订单由人工处理，不参与扫描。
+// 多个实例同时扫描时，同一订单只会被其中一个实例成功取消，其余实例会因状态校验失败而跳过。
+func (s *orderService) CancelExpiredOrders(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	if s.deps.DB == nil {
+		return 0, errOrderDBUnavailable
+	}
+	if limit <= 0 {
+		return 0, errors.New("limit must be positive")
+	}
+
+	// 选择了线下支付的订单在确认收款时限（线下支付记录的过期时间）内等待店员确认，过期后同样取消。
+	now := time.Now()
+	const query = `SELECT o.id FROM orders o
+		WHERE o.status = ? AND o.created_at < ?
+			AND (o.last_sweep_attempt_at IS NULL OR o.last_sweep_attempt_at < ?)
+			AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.order_id = o.id AND p.provider = ? AND p.status = ? AND p.expires_at > ?)
+			AND NOT EXISTS (SELECT 1 FROM payment_transactions t WHERE t.order_id = o.id AND t.status = ?)
+		ORDER BY o.last_sweep_attempt_at, o.created_at LIMIT ?`
+	rows, err := s.deps.DB.QueryContext(ctx, query, model.OrderStatusPendingPayment, createdBefore, now.Add(-expiredOrderRetryBackoff),
+		model.PaymentProviderOffline, model.PaymentStatusCreated, now, model.PaymentTransactionReview, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var orderIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orderIDs = append(orderIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for _, orderID := range orderIDs {
+		paid, err := s.closeProviderTrades(ctx, orderID)
+		if err != nil {
+			s.deps.Logger.Printf("skip cancelling expired order %s: %v", orderID, err)
+			if err := s.markSweepAttempt(ctx, orderID, now); err != nil {
+				return cancelled, err
+			}
+			continue
+		}
+		if paid != nil {
+			// 顾客已在渠道侧完成支付而回调丢失或尚未到达，按回调同样的幂等流程入账，不再取消。
+			// 入账转入人工复核的订单仍是待支付状态，同样记录尝试时间。
+			if err := s.paymentLedger().applyTransaction(ctx, *paid, model.OrderEventSourceSystem); err != nil {
+				return cancelled, err
+			}
+			if err := s.markSweepAttempt(ctx, orderID, now); err != nil {
+				return cancelled, err
+			}
+			continue
+		}
+
+		change := statusChange{Source: model.OrderEventSourceTimeout, Reason: "payment timeout"}
+		err = s.updateStatus(ctx, orderID, model.OrderStatusCancelled, change,
+			requireFromStatus(orderID, model.OrderStatusCancelled, model.OrderStatusPendingPayment),
+			cancellationHook(ctx, orderID),
+		)
+		if err != nil {
+			if code, ok := model.ErrorCodeOf(err); ok && code == model.ErrCodeInvalidTransition {
+				continue
+			}
+			return cancelled, err
+		}
+		cancelled++
+	}
+
+	return cancelled, nil
 }
 
+// markSweepAttempt 记录超时扫描最近一次跳过订单的时间，仍待支付的订单按该时间排到扫描队尾。
+func (s *orderService) markSweepAttempt(ctx context.Context, orderID string, attemptedAt time.Time) error {
+	const stmt = `UPDATE orders SET last_sweep_attempt_at = ? WHERE id = ? AND status = ?`
+	_, err := s.deps.DB.ExecContext(ctx, stmt, attemptedAt, orderID, model.OrderStatusPendingPayment)
+	return err
+}
+
+// closeProviderTrades 在取消订单前逐一向支付渠道查询待支付交易：仍未支付的交易在渠道侧关闭，
+// 使顾客无法在订单取消后继续付款；渠道报告已支付时返回该笔交易，由调用方入账而不是取消订单。
+func (s *orderService) closeProviderTrades(ctx context.Context, orderID string) (*model.PaymentTransaction, error) {
+	providers, err := pendingPaymentProviders(ctx, s.deps.DB, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, provider := range providers {
+		gateway, ok := s.deps.Payments.Get(provider)
+		if !ok {
+			s.deps.Logger.Printf("skip closing payment for order %s: provider %s is not configured", orderID, provider)
+			continue
+		}
+
+		result, err := gateway.QueryOrder(ctx, orderID)
+		if err != nil {
+			return nil, model.NewError(model.ErrCodePaymentFailed, "query %s payment for order %s: %v", provider, orderID, err)
+		}
+		switch result.TradeState {
+		case payment.TradeStateSuccess:
+			txn := providerTransaction(provider, result)
+			return &txn, nil
+		case payment.TradeStateClosed, payment.TradeStateRevoked, payment.TradeStatePayError:
+			continue
+		}
+		if err := gateway.CloseOrder(ctx, orderID); err != nil {
+			return nil, model.NewError(model.ErrCodePaymentFailed, "close %s payment for order %s: %v", provider, orderID, err)
+		}
+	}
+	return nil, nil
+}
+
+// paymentLedger 返回与当前订单服务共享依赖的支付入账流程，用于取消前发现渠道侧已支付的订单。
+func (s *orderService) paymentLedger() *paymentService {
+	return &paymentService{deps: s.deps, orderService: s}
+}
+
+// 退款结束后订单可能回到已支付、已发货或已完成，因此以下流转需显式限定前置状态。
+
 func (s *orderService) ShipOrder(ctx context.Context, orderID string) error {
-	return s.updateStatus(ctx, orderID, model.OrderStatusShipped)
+	return s.updateStatus(ctx, orderID, model.OrderStatusShipped, statusChange{Source: model.OrderEventSourceAdmin},
+		requireFromStatus(orderID, model.OrderStatusShipped, model.OrderStatusPaid))
 }
 
+// CompleteOrder 确认收货，并在同一事务内按实付金额发放积分。
 func (s *orderService) CompleteOrder(ctx context.Context, orderID string) error {
-	return s.updateStatus(ctx, orderID, model.OrderStatusCompleted)
+	return s.updateStatus(ctx, orderID, model.OrderStatusCompleted, statusChange{Source: model.OrderEventSourceCustomer},
+		requireFromStatus(orderID, model.OrderStatusCompleted, model.OrderStatusShipped),
+		earnPointsHook(ctx, loyaltyRatesOf(s.deps), orderID))
 }
 
 func (s *orderService) MarkPaid(ctx context.Context, orderID string) error {
-	return s.updateStatus(ctx, orderID, model.OrderStatusPaid)
+	return s.updateStatus(ctx, orderID, model.OrderStatusPaid, statusChange{Source: model.OrderEventSourceCallback},
+		requireFromStatus(orderID, model.OrderStatusPaid, model.OrderStatusPendingPayment))
+}
+
+// GetTimeline 按发生顺序返回订单的全部状态事件。
+func (s *orderService) GetTimeline(ctx context.Context, orderID string) ([]model.OrderEvent, error) {
+	if s.deps.DB == nil {
+		return nil, errOrderDBUnavailable
+	}
+	if orderID == "" {
+		return nil, errors.New("order id is required")
+	}
+
+	var exists int
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT 1 FROM orders WHERE id = ?`, orderID).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+		}
+		return nil, err
+	}
+
+	const query = `SELECT id, order_id, from_status, to_status, actor, source, reason, created_at FROM order_events WHERE order_id = ? ORDER BY id`
+	rows, err := s.deps.DB.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []model.OrderEvent{}
+	for rows.Next() {
+		var (
+			event      model.OrderEvent
+			fromStatus sql.NullString
+			actor      sql.NullString
+			reason     sql.NullString
+		)
+		if err := rows.Scan(&event.ID, &event.OrderID, &fromStatus, &event.ToStatus, &actor, &event.Source, &reason, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.FromStatus = model.OrderStatus(fromStatus.String)
+		event.Actor = actor.String
+		event.Reason = reason.String
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// statusChange 描述一次状态流转的来源与原因，Source 仅在上下文未携带操作主体时生效。
+type statusChange struct {
+	Source model.OrderEventSource
+	Reason string
 }
 
-func (s *orderService) updateStatus(ctx context.Context, orderID string, status model.OrderStatus) error {
+// statusHook 在状态流转所在事务内执行附带操作，例如归还库存。
+type statusHook func(tx *sql.Tx, from model.OrderStatus) error
+
+func (s *orderService) updateStatus(ctx context.Context, orderID string, status model.OrderStatus, change statusChange, hooks ...statusHook) error {
 	if s.deps.DB == nil {
 		return errOrderDBUnavailable
 	}
+	return updateOrderStatus(ctx, s.deps.DB, orderID, status, change, hooks...)
+}
+
+// updateOrderStatus 在事务内完成状态校验、流转、钩子与事件记录，供订单与退款流程共用。
+func updateOrderStatus(ctx context.Context, db *sql.DB, orderID string, status model.OrderStatus, change statusChange, hooks ...statusHook) error {
 	if orderID == "" {
 		return errors.New("order id is required")
 	}
 
-	const stmt = `UPDATE orders SET status = ?, updated_at = NOW() WHERE id = ?`
-	res, err := s.deps.DB.ExecContext(ctx, stmt, status, orderID)
+	return runInTx(ctx, db, func(tx *sql.Tx) error {
+		from, err := transitionOrderTx(ctx, tx, orderID, status)
+		if err != nil {
+			return err
+		}
+		for _, hook := range hooks {
+			if err := hook(tx, from); err != nil {
+				return err
+			}
+		}
+		return insertOrderEventTx(ctx, tx, model.OrderEvent{
+			OrderID:    orderID,
+			FromStatus: from,
+			ToStatus:   status,
+			Reason:     change.Reason,
+		}, resolveOperator(ctx, change.Source))
+	})
+}
+
+// insertOrderEventTx 追加一条订单事件，与状态变更处于同一事务以保证记录完整。
+func insertOrderEventTx(ctx context.Context, tx *sql.Tx, event model.OrderEvent, operator Operator) error {
+	const stmt = `INSERT INTO order_events (order_id, from_status, to_status, actor, source, reason) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := tx.ExecContext(ctx, stmt,
+		event.OrderID,
+		nullableString(string(event.FromStatus)),
+		event.ToStatus,
+		nullableString(operator.Actor),
+		operator.Source,
+		nullableString(event.Reason),
+	)
+	return err
+}
+
+// transitionOrderTx 在事务内按状态机校验并执行流转，返回流转前的状态。
+// UPDATE 语句带有前置状态条件，即使并发请求绕过了前面的读取也无法越权流转。
+func transitionOrderTx(ctx context.Context, tx *sql.Tx, orderID string, to model.OrderStatus) (model.OrderStatus, error) {
+	var current model.OrderStatus
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ? FOR UPDATE`, orderID).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+		}
+		return "", err
+	}
+	if !current.CanTransitionTo(to) {
+		return current, invalidTransitionError(orderID, current, to)
+	}
+
+	sources := model.SourceStatuses(to)
+	args := []any{to, orderID}
+	for _, from := range sources {
+		args = append(args, from)
+	}
+
+	stmt := `UPDATE orders SET status = ?, updated_at = NOW() WHERE id = ? AND status IN (` + placeholders(len(sources)) + `)`
+	res, err := tx.ExecContext(ctx, stmt, args...)
 	if err != nil {
-		return err
+		return current, err
 	}
 
 	affected, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return current, err
 	}
 	if affected == 0 {
-		return fmt.Errorf("order %s not found", orderID)
+		return current, invalidTransitionError(orderID, current, to)
 	}
 
-	return nil
+	return current, nil
+}
+
+// requireFromStatus 限定流转只能从指定状态发起，用于比状态机更严格的场景，例如超时取消只针对待支付订单。
+func requireFromStatus(orderID string, to model.OrderStatus, allowed model.OrderStatus) statusHook {
+	return func(tx *sql.Tx, from model.OrderStatus) error {
+		if from != allowed {
+			return invalidTransitionError(orderID, from, to)
+		}
+		return nil
+	}
+}
+
+func invalidTransitionError(orderID string, from, to model.OrderStatus) error {
+	return model.NewError(model.ErrCodeInvalidTransition, "order %s cannot change from %s to %s", orderID, from, to).
+		WithDetails(map[string]any{"from": from, "to": to})
 }
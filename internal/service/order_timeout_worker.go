@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	defaultPaymentTimeout = 15 * time.Minute
+	defaultSweepInterval  = time.Minute
+	defaultSweepBatchSize = 100
+)
+
+// OrderTimeoutWorker 周期性扫描并取消超过支付时限的待支付订单。
+type OrderTimeoutWorker struct {
+	logger         *log.Logger
+	orderService   OrderService
+	paymentTimeout time.Duration
+	sweepInterval  time.Duration
+	batchSize      int
+}
+
+// NewOrderTimeoutWorker 根据 order 配置构建超时取消任务，未配置的项使用默认值。
+func NewOrderTimeoutWorker(deps Dependencies, orderService OrderService) (*OrderTimeoutWorker, error) {
+	worker := &OrderTimeoutWorker{
+		logger:         deps.Logger,
+		orderService:   orderService,
+		paymentTimeout: defaultPaymentTimeout,
+		sweepInterval:  defaultSweepInterval,
+		batchSize:      defaultSweepBatchSize,
+	}
+	if deps.Config == nil {
+		return worker, nil
+	}
+
+	paymentTimeout, err := orderPaymentTimeout(deps)
+	if err != nil {
+		return nil, err
+	}
+	worker.paymentTimeout = paymentTimeout
+
+	cfg := deps.Config.Order
+	if cfg.SweepInterval != "" {
+		dur, err := time.ParseDuration(cfg.SweepInterval)
+		if err != nil {
+			return nil, fmt.Errorf("parse order sweep_interval: %w", err)
+		}
+		worker.sweepInterval = dur
+	}
+	if cfg.SweepBatchSize > 0 {
+		worker.batchSize = cfg.SweepBatchSize
+	}
+	if worker.paymentTimeout <= 0 || worker.sweepInterval <= 0 {
+		return nil, errors.New("order payment_timeout and sweep_interval must be positive")
+	}
+
+	return worker, nil
+}
+
+// orderPaymentTimeout 返回待支付订单的支付时限，未配置时使用默认值。
+func orderPaymentTimeout(deps Dependencies) (time.Duration, error) {
+	if deps.Config == nil || deps.Config.Order.PaymentTimeout == "" {
+		return defaultPaymentTimeout, nil
+	}
+	dur, err := time.ParseDuration(deps.Config.Order.PaymentTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("parse order payment_timeout: %w", err)
+	}
+	return dur, nil
+}
+
+// Run 阻塞执行扫描循环，直到 ctx 被取消。
+func (w *OrderTimeoutWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		w.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *OrderTimeoutWorker) sweep(ctx context.Context) {
+	cutoff := time.Now().Add(-w.paymentTimeout)
+	for {
+		cancelled, err := w.orderService.CancelExpiredOrders(ctx, cutoff, w.batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Printf("cancel expired orders failed: %v", err)
+			}
+			return
+		}
+		if cancelled > 0 {
+			w.logger.Printf("cancelled %d expired orders created before %s", cancelled, cutoff.Format(time.RFC3339))
+		}
+		if cancelled < w.batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	defaultReconcileInterval  = time.Minute
+	defaultReconcileDelay     = time.Minute
+	defaultReconcileBatchSize = 50
+)
+
+// PaymentReconcileWorker 周期性向支付渠道查询仍在待支付状态的订单，补偿丢失的支付回调。
+type PaymentReconcileWorker struct {
+	logger         *log.Logger
+	paymentService PaymentService
+	interval       time.Duration
+	delay          time.Duration
+	batchSize      int
+}
+
+// NewPaymentReconcileWorker 根据 order 配置构建支付对账任务，未配置的项使用默认值。
+func NewPaymentReconcileWorker(deps Dependencies, paymentService PaymentService) (*PaymentReconcileWorker, error) {
+	worker := &PaymentReconcileWorker{
+		logger:         deps.Logger,
+		paymentService: paymentService,
+		interval:       defaultReconcileInterval,
+		delay:          defaultReconcileDelay,
+		batchSize:      defaultReconcileBatchSize,
+	}
+	if deps.Config == nil {
+		return worker, nil
+	}
+
+	cfg := deps.Config.Order
+	if cfg.ReconcileInterval != "" {
+		dur, err := time.ParseDuration(cfg.ReconcileInterval)
+		if err != nil {
+			return nil, fmt.Errorf("parse order reconcile_interval: %w", err)
+		}
+		worker.interval = dur
+	}
+	if cfg.ReconcileDelay != "" {
+		dur, err := time.ParseDuration(cfg.ReconcileDelay)
+		if err != nil {
+			return nil, fmt.Errorf("parse order reconcile_delay: %w", err)
+		}
+		worker.delay = dur
+	}
+	if cfg.ReconcileBatchSize > 0 {
+		worker.batchSize = cfg.ReconcileBatchSize
+	}
+	if worker.interval <= 0 || worker.delay < 0 {
+		return nil, errors.New("order reconcile_interval must be positive and reconcile_delay must not be negative")
+	}
+
+	return worker, nil
+}
+
+// Run 阻塞执行对账循环，直到 ctx 被取消。每轮只处理一批订单，
+// 仍未支付的订单会在之后的轮次中按上次查询时间依次重试。
+func (w *PaymentReconcileWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.reconcile(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *PaymentReconcileWorker) reconcile(ctx context.Context) {
+	cutoff := time.Now().Add(-w.delay)
+	settled, err := w.paymentService.ReconcilePendingPayments(ctx, cutoff, w.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Printf("reconcile pending payments failed: %v", err)
+		}
+		return
+	}
+	if settled > 0 {
+		w.logger.Printf("reconciled %d orders with pending payments", settled)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"convenienceStore/internal/model"
+)
+
+const paymentColumns = `id, order_id, provider, prepay_id, amount, status, transaction_id, expires_at, created_at, updated_at`
+
+func scanPaymentRow(scanner interface {
+	Scan(dest ...any) error
+}) (*model.Payment, error) {
+	var (
+		p             model.Payment
+		prepayID      sql.NullString
+		transactionID sql.NullString
+		expiresAt     sql.NullTime
+	)
+	if err := scanner.Scan(&p.ID, &p.OrderID, &p.Provider, &prepayID, &p.Amount, &p.Status, &transactionID, &expiresAt, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.PrepayID = prepayID.String
+	p.TransactionID = transactionID.String
+	if expiresAt.Valid {
+		p.ExpiresAt = &expiresAt.Time
+	}
+	return &p, nil
+}
+
+// findReusablePayment 查找金额一致且在 validUntil 之后仍有效的待支付记录，没有时返回 nil。
+// 没有过期时间的记录始终可复用。
+func findReusablePayment(ctx context.Context, db *sql.DB, orderID, provider string, amount model.Money, validUntil time.Time) (*model.Payment, error) {
+	const query = `SELECT ` + paymentColumns + ` FROM payments WHERE order_id = ? AND provider = ? AND status = ? AND amount = ? AND (expires_at IS NULL OR expires_at > ?) ORDER BY created_at DESC LIMIT 1`
+	p, err := scanPaymentRow(db.QueryRowContext(ctx, query, orderID, provider, model.PaymentStatusCreated, amount, validUntil))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func insertPayment(ctx context.Context, db *sql.DB, p *model.Payment) error {
+	const stmt = `INSERT INTO payments (id, order_id, provider, prepay_id, amount, status, expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	var expiresAt any
+	if p.ExpiresAt != nil {
+		expiresAt = *p.ExpiresAt
+	}
+	_, err := db.ExecContext(ctx, stmt, p.ID, p.OrderID, p.Provider, nullableString(p.PrepayID), p.Amount, p.Status, expiresAt, p.CreatedAt, p.UpdatedAt)
+	return err
+}
+
+// markPaymentPaid 将订单最近一次待支付记录标记为已支付，其余待支付记录随之关闭。
+func markPaymentPaid(ctx context.Context, db *sql.DB, orderID, provider, transactionID string) error {
+	return runInTx(ctx, db, func(tx *sql.Tx) error {
+		const latest = `SELECT id FROM payments WHERE order_id = ? AND provider = ? AND status = ? ORDER BY created_at DESC LIMIT 1 FOR UPDATE`
+		var paymentID string
+		err := tx.QueryRowContext(ctx, latest, orderID, provider, model.PaymentStatusCreated).Scan(&paymentID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil {
+			const paid = `UPDATE payments SET status = ?, transaction_id = ?, updated_at = NOW() WHERE id = ?`
+			if _, err := tx.ExecContext(ctx, paid, model.PaymentStatusPaid, transactionID, paymentID); err != nil {
+				return err
+			}
+		}
+		return closePendingPaymentsTx(ctx, tx, orderID)
+	})
+}
+
+// closePendingPaymentsTx 关闭订单下全部待支付记录，例如订单取消或已经完成支付时。
+func closePendingPaymentsTx(ctx context.Context, tx *sql.Tx, orderID string) error {
+	const stmt = `UPDATE payments SET status = ?, updated_at = NOW() WHERE order_id = ? AND status = ?`
+	_, err := tx.ExecContext(ctx, stmt, model.PaymentStatusClosed, orderID, model.PaymentStatusCreated)
+	return err
+}
+
+// closeProviderPayments 关闭订单在指定支付方式下的待支付记录，例如渠道侧交易已关闭时。
+func closeProviderPayments(ctx context.Context, db *sql.DB, orderID, provider string) error {
+	const stmt = `UPDATE payments SET status = ?, updated_at = NOW() WHERE order_id = ? AND provider = ? AND status = ?`
+	_, err := db.ExecContext(ctx, stmt, model.PaymentStatusClosed, orderID, provider, model.PaymentStatusCreated)
+	return err
+}
+
+// pendingPaymentProviders 返回订单存在渠道侧交易的待支付记录所属的支付方式。
+func pendingPaymentProviders(ctx context.Context, db *sql.DB, orderID string) ([]string, error) {
+	const query = `SELECT DISTINCT provider FROM payments WHERE order_id = ? AND status = ? AND prepay_id IS NOT NULL ORDER BY provider`
+	rows, err := db.QueryContext(ctx, query, orderID, model.PaymentStatusCreated)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var providers []string
+	for rows.Next() {
+		var provider string
+		if err := rows.Scan(&provider); err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, rows.Err()
+}
+
+// touchPendingPayments 刷新待支付记录的更新时间，使对账任务按最近查询时间轮询各订单。
+func touchPendingPayments(ctx context.Context, db *sql.DB, orderID string) error {
+	const stmt = `UPDATE payments SET updated_at = NOW() WHERE order_id = ? AND status = ?`
+	_, err := db.ExecContext(ctx, stmt, orderID, model.PaymentStatusCreated)
+	return err
+}
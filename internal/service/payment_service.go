@@ -1,12 +1,47 @@
 package service
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/pkg/payment"
+)
 
 // PaymentService 负责支付流程的编排。
 type PaymentService interface {
-	HandleWeChatCallback(ctx context.Context, payload []byte) error
+	HandleCallback(ctx context.Context, provider string, headers http.Header, payload []byte) error
+	ListOrderPayments(ctx context.Context, orderID string) ([]model.Payment, error)
+	SearchPayments(ctx context.Context, query PaymentSearchQuery) (*model.PaymentPage, error)
+	SyncOrderPayment(ctx context.Context, orderID string) (model.OrderStatus, error)
+	ReconcilePendingPayments(ctx context.Context, createdBefore time.Time, limit int) (int, error)
+	ConfirmOfflinePayment(ctx context.Context, orderID string) error
+}
+
+// PaymentSearchQuery 描述后台支付记录检索条件，零值字段表示不限制。
+type PaymentSearchQuery struct {
+	OrderID       string
+	Provider      string
+	Statuses      []model.PaymentStatus
+	TransactionID string
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	Cursor        string
+	Limit         int
 }
 
+const (
+	defaultPaymentPageSize = 20
+	maxPaymentPageSize     = 100
+)
+
+var errPaymentDBUnavailable = errors.New("payment service database is not configured")
+
 type paymentService struct {
 	deps         Dependencies
 	orderService OrderService
@@ -17,19 +52,447 @@ func NewPaymentService(deps Dependencies, orderService OrderService) PaymentServ
 	return &paymentService{deps: deps, orderService: orderService}
 }
 
-func (s *paymentService) HandleWeChatCallback(ctx context.Context, payload []byte) error {
-	s.deps.Logger.Printf("processing wechat callback size=%d", len(payload))
+// HandleCallback 处理指定支付方式的支付结果通知，重复通知只会入账一次。
+func (s *paymentService) HandleCallback(ctx context.Context, provider string, headers http.Header, payload []byte) error {
+	if s.deps.DB == nil {
+		return errPaymentDBUnavailable
+	}
+	gateway, ok := s.deps.Payments.Get(provider)
+	if !ok {
+		return model.NewError(model.ErrCodeInvalidParameter, "unsupported payment provider %s", provider)
+	}
+	s.deps.Logger.Printf("processing %s callback size=%d", provider, len(payload))
 
-	result, err := s.deps.Payment.HandleCallback(ctx, payload)
+	result, err := gateway.HandleCallback(ctx, headers, payload)
 	if err != nil {
 		return err
 	}
 
-	if result.Success {
-		if err := s.orderService.MarkPaid(ctx, result.OrderID); err != nil {
-			return err
+	if !result.Success {
+		s.deps.Logger.Printf("%s callback ignored order_id=%s state=%s", provider, result.OrderID, result.TradeState)
+		return nil
+	}
+
+	return s.applyTransaction(ctx, providerTransaction(provider, result), model.OrderEventSourceCallback)
+}
+
+// providerTransaction 将渠道的回调或查单结果转换为待入账的交易。
+func providerTransaction(provider string, result payment.CallbackResult) model.PaymentTransaction {
+	txn := model.PaymentTransaction{
+		Provider:      provider,
+		TransactionID: result.TransactionID,
+		OrderID:       result.OrderID,
+		TradeState:    result.TradeState,
+		Amount:        model.Money(result.Amount),
+		PayerOpenID:   result.PayerOpenID,
+	}
+	if !result.SuccessTime.IsZero() {
+		paidAt := result.SuccessTime
+		txn.PaidAt = &paidAt
+	}
+	return txn
+}
+
+// SyncOrderPayment 主动向支付渠道查询待支付订单的交易状态，用于弥补丢失的支付回调：
+// 已支付的交易按回调同样的幂等流程入账，已关闭的交易关闭本地支付记录，
+// 超过支付时限仍未支付的交易在渠道侧关闭。订单在多个支付方式下都有待支付记录时逐一查询。
+// 返回同步后的订单状态。
+func (s *paymentService) SyncOrderPayment(ctx context.Context, orderID string) (model.OrderStatus, error) {
+	if s.deps.DB == nil {
+		return "", errPaymentDBUnavailable
+	}
+	if orderID == "" {
+		return "", model.NewError(model.ErrCodeInvalidParameter, "order id is required")
+	}
+
+	var (
+		status    model.OrderStatus
+		createdAt time.Time
+	)
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT status, created_at FROM orders WHERE id = ?`, orderID).Scan(&status, &createdAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+		}
+		return "", err
+	}
+	if status != model.OrderStatusPendingPayment {
+		return status, nil
+	}
+
+	providers, err := pendingPaymentProviders(ctx, s.deps.DB, orderID)
+	if err != nil {
+		return "", err
+	}
+	timeout, err := orderPaymentTimeout(s.deps)
+	if err != nil {
+		return "", err
+	}
+	expired := time.Now().After(createdAt.Add(timeout))
+
+	for _, provider := range providers {
+		gateway, ok := s.deps.Payments.Get(provider)
+		if !ok {
+			s.deps.Logger.Printf("skip payment sync for order %s: provider %s is not configured", orderID, provider)
+			continue
+		}
+
+		result, err := gateway.QueryOrder(ctx, orderID)
+		if err != nil {
+			return "", model.NewError(model.ErrCodePaymentFailed, "query %s payment for order %s: %v", provider, orderID, err)
 		}
+
+		switch result.TradeState {
+		case payment.TradeStateSuccess:
+			if err := s.applyTransaction(ctx, providerTransaction(provider, result), model.OrderEventSourceSystem); err != nil {
+				return "", err
+			}
+		case payment.TradeStateClosed, payment.TradeStateRevoked, payment.TradeStatePayError:
+			if err := closeProviderPayments(ctx, s.deps.DB, orderID, provider); err != nil {
+				return "", err
+			}
+		case payment.TradeStateNotPay:
+			if expired {
+				if err := gateway.CloseOrder(ctx, orderID); err != nil {
+					return "", model.NewError(model.ErrCodePaymentFailed, "close %s payment for order %s: %v", provider, orderID, err)
+				}
+				if err := closeProviderPayments(ctx, s.deps.DB, orderID, provider); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	if err := touchPendingPayments(ctx, s.deps.DB, orderID); err != nil {
+		return "", err
+	}
+
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ?`, orderID).Scan(&status); err != nil {
+		return "", err
 	}
+	return status, nil
+}
 
+// ConfirmOfflinePayment 由店员确认线下收款（货到付款或到店付款），订单按线下支付记录的金额入账。
+func (s *paymentService) ConfirmOfflinePayment(ctx context.Context, orderID string) error {
+	if s.deps.DB == nil {
+		return errPaymentDBUnavailable
+	}
+	if orderID == "" {
+		return model.NewError(model.ErrCodeInvalidParameter, "order id is required")
+	}
+
+	var total model.Money
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT total FROM orders WHERE id = ?`, orderID).Scan(&total); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+		}
+		return err
+	}
+
+	pending, err := findReusablePayment(ctx, s.deps.DB, orderID, model.PaymentProviderOffline, total, time.Now())
+	if err != nil {
+		return err
+	}
+	if pending == nil {
+		return model.NewError(model.ErrCodeInvalidTransition, "order %s has no pending offline payment", orderID)
+	}
+
+	now := time.Now()
+	txn := model.PaymentTransaction{
+		Provider:      model.PaymentProviderOffline,
+		TransactionID: pending.ID,
+		OrderID:       orderID,
+		TradeState:    payment.TradeStateSuccess,
+		Amount:        pending.Amount,
+		PaidAt:        &now,
+	}
+	if err := s.applyTransaction(ctx, txn, model.OrderEventSourceAdmin); err != nil {
+		return err
+	}
+
+	var (
+		status model.PaymentTransactionStatus
+		note   sql.NullString
+	)
+	const query = `SELECT status, note FROM payment_transactions WHERE provider = ? AND transaction_id = ?`
+	if err := s.deps.DB.QueryRowContext(ctx, query, txn.Provider, txn.TransactionID).Scan(&status, &note); err != nil {
+		return err
+	}
+	if status == model.PaymentTransactionReview {
+		return model.NewError(model.ErrCodeInvalidTransition, "offline payment for order %s needs review: %s", orderID, note.String)
+	}
 	return nil
 }
+
+// ReconcilePendingPayments 同步一批存在待支付记录的待支付订单，记录创建时间早于 createdBefore，
+// 以给支付回调留出到达时间。按上次查询时间轮询，返回同步后不再处于待支付状态的订单数量。
+func (s *paymentService) ReconcilePendingPayments(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	if s.deps.DB == nil {
+		return 0, errPaymentDBUnavailable
+	}
+	if limit <= 0 {
+		return 0, errors.New("limit must be positive")
+	}
+
+	const query = `SELECT p.order_id FROM payments p JOIN orders o ON o.id = p.order_id WHERE p.status = ? AND p.prepay_id IS NOT NULL AND p.created_at < ? AND o.status = ? GROUP BY p.order_id ORDER BY MIN(p.updated_at) LIMIT ?`
+	rows, err := s.deps.DB.QueryContext(ctx, query, model.PaymentStatusCreated, createdBefore, model.OrderStatusPendingPayment, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var orderIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orderIDs = append(orderIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, orderID := range orderIDs {
+		if err := ctx.Err(); err != nil {
+			return settled, err
+		}
+		status, err := s.SyncOrderPayment(ctx, orderID)
+		if err != nil {
+			s.deps.Logger.Printf("sync payment for order %s failed: %v", orderID, err)
+			continue
+		}
+		if status != model.OrderStatusPendingPayment {
+			settled++
+		}
+	}
+
+	return settled, nil
+}
+
+// ListOrderPayments 按发起时间倒序返回订单的全部支付尝试。
+func (s *paymentService) ListOrderPayments(ctx context.Context, orderID string) ([]model.Payment, error) {
+	if s.deps.DB == nil {
+		return nil, errPaymentDBUnavailable
+	}
+	if orderID == "" {
+		return nil, model.NewError(model.ErrCodeInvalidParameter, "order id is required")
+	}
+
+	var exists int
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT 1 FROM orders WHERE id = ?`, orderID).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+		}
+		return nil, err
+	}
+
+	const query = `SELECT ` + paymentColumns + ` FROM payments WHERE order_id = ? ORDER BY created_at DESC, id DESC`
+	rows, err := s.deps.DB.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []model.Payment{}
+	for rows.Next() {
+		p, err := scanPaymentRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, *p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// SearchPayments 为后台按条件分页检索支付记录，按创建时间倒序排列。
+func (s *paymentService) SearchPayments(ctx context.Context, query PaymentSearchQuery) (*model.PaymentPage, error) {
+	if s.deps.DB == nil {
+		return nil, errPaymentDBUnavailable
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultPaymentPageSize
+	}
+	if limit > maxPaymentPageSize {
+		limit = maxPaymentPageSize
+	}
+
+	var (
+		conditions []string
+		args       []any
+	)
+	if query.OrderID != "" {
+		conditions = append(conditions, `order_id = ?`)
+		args = append(args, query.OrderID)
+	}
+	if query.Provider != "" {
+		conditions = append(conditions, `provider = ?`)
+		args = append(args, query.Provider)
+	}
+	if len(query.Statuses) > 0 {
+		for _, status := range query.Statuses {
+			args = append(args, status)
+		}
+		conditions = append(conditions, `status IN (`+placeholders(len(query.Statuses))+`)`)
+	}
+	if query.TransactionID != "" {
+		conditions = append(conditions, `transaction_id = ?`)
+		args = append(args, query.TransactionID)
+	}
+	if !query.CreatedFrom.IsZero() {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, query.CreatedFrom)
+	}
+	if !query.CreatedTo.IsZero() {
+		conditions = append(conditions, `created_at < ?`)
+		args = append(args, query.CreatedTo)
+	}
+	if query.Cursor != "" {
+		createdAt, id, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, `(created_at < ? OR (created_at = ? AND id < ?))`)
+		args = append(args, createdAt, createdAt, id)
+	}
+
+	stmt := `SELECT ` + paymentColumns + ` FROM payments`
+	if len(conditions) > 0 {
+		stmt += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	stmt += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := s.deps.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &model.PaymentPage{Payments: []model.Payment{}}
+	for rows.Next() {
+		p, err := scanPaymentRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Payments = append(page.Payments, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Payments) > limit {
+		page.Payments = page.Payments[:limit]
+		last := page.Payments[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return page, nil
+}
+
+// applyTransaction 幂等地处理一笔成功的渠道交易：同一交易号只会入账一次，
+// 金额与订单不符或订单已无法支付时转入人工复核，而不是将订单标记为已支付。
+func (s *paymentService) applyTransaction(ctx context.Context, txn model.PaymentTransaction, source model.OrderEventSource) error {
+	if txn.TransactionID == "" || txn.OrderID == "" {
+		return errors.New("payment transaction id and order id are required")
+	}
+
+	status, err := s.registerTransaction(ctx, txn)
+	if err != nil {
+		return err
+	}
+	if status != model.PaymentTransactionReceived {
+		s.deps.Logger.Printf("duplicate payment notification provider=%s transaction_id=%s status=%s", txn.Provider, txn.TransactionID, status)
+		return nil
+	}
+
+	var total model.Money
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT total FROM orders WHERE id = ?`, txn.OrderID).Scan(&total); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.flagTransaction(ctx, txn, fmt.Sprintf("order %s not found", txn.OrderID))
+		}
+		return err
+	}
+	if total != txn.Amount {
+		return s.flagTransaction(ctx, txn, fmt.Sprintf("paid amount %s does not match order total %s", txn.Amount, total))
+	}
+
+	paidCtx := WithOperator(ctx, Operator{Actor: txn.Provider + ":" + txn.TransactionID, Source: source})
+	if err := s.orderService.MarkPaid(paidCtx, txn.OrderID); err != nil {
+		code, ok := model.ErrorCodeOf(err)
+		if !ok || code != model.ErrCodeInvalidTransition {
+			return err
+		}
+
+		paidByOther, lookupErr := s.orderPaidByOtherTransaction(ctx, txn)
+		if lookupErr != nil {
+			return lookupErr
+		}
+		if paidByOther {
+			return s.flagTransaction(ctx, txn, "order already paid by another transaction")
+		}
+
+		var current model.OrderStatus
+		if err := s.deps.DB.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ?`, txn.OrderID).Scan(&current); err != nil {
+			return err
+		}
+		if current == model.OrderStatusPendingPayment || current == model.OrderStatusCancelled {
+			return s.flagTransaction(ctx, txn, fmt.Sprintf("order is %s and cannot be marked paid", current))
+		}
+		// 订单已由同一笔交易的并发通知入账，此处只需补记状态。
+	}
+
+	if err := markPaymentPaid(ctx, s.deps.DB, txn.OrderID, txn.Provider, txn.TransactionID); err != nil {
+		return err
+	}
+
+	return s.setTransactionStatus(ctx, txn, model.PaymentTransactionApplied, "")
+}
+
+// registerTransaction 登记交易并返回其当前处理状态，已存在的记录不会被覆盖。
+func (s *paymentService) registerTransaction(ctx context.Context, txn model.PaymentTransaction) (model.PaymentTransactionStatus, error) {
+	const insert = `INSERT INTO payment_transactions (provider, transaction_id, order_id, trade_state, amount, payer_open_id, status, paid_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = id`
+	var paidAt any
+	if txn.PaidAt != nil {
+		paidAt = *txn.PaidAt
+	}
+	if _, err := s.deps.DB.ExecContext(ctx, insert, txn.Provider, txn.TransactionID, txn.OrderID, txn.TradeState, txn.Amount, nullableString(txn.PayerOpenID), model.PaymentTransactionReceived, paidAt); err != nil {
+		return "", err
+	}
+
+	var status model.PaymentTransactionStatus
+	const query = `SELECT status FROM payment_transactions WHERE provider = ? AND transaction_id = ?`
+	if err := s.deps.DB.QueryRowContext(ctx, query, txn.Provider, txn.TransactionID).Scan(&status); err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+func (s *paymentService) orderPaidByOtherTransaction(ctx context.Context, txn model.PaymentTransaction) (bool, error) {
+	const query = `SELECT COUNT(*) FROM payment_transactions WHERE order_id = ? AND status = ? AND NOT (provider = ? AND transaction_id = ?)`
+	var count int
+	if err := s.deps.DB.QueryRowContext(ctx, query, txn.OrderID, model.PaymentTransactionApplied, txn.Provider, txn.TransactionID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// flagTransaction 将交易转入人工复核，并视为已受理以免渠道反复重试。
+func (s *paymentService) flagTransaction(ctx context.Context, txn model.PaymentTransaction, note string) error {
+	s.deps.Logger.Printf("payment transaction flagged for review provider=%s transaction_id=%s order_id=%s: %s", txn.Provider, txn.TransactionID, txn.OrderID, note)
+	return s.setTransactionStatus(ctx, txn, model.PaymentTransactionReview, note)
+}
+
+func (s *paymentService) setTransactionStatus(ctx context.Context, txn model.PaymentTransaction, status model.PaymentTransactionStatus, note string) error {
+	const stmt = `UPDATE payment_transactions SET status = ?, note = ?, updated_at = NOW() WHERE provider = ? AND transaction_id = ? AND status = ?`
+	_, err := s.deps.DB.ExecContext(ctx, stmt, status, nullableString(note), txn.Provider, txn.TransactionID, model.PaymentTransactionReceived)
+	return err
+}
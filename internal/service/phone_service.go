@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/pkg/wechat"
+)
+
+// PhoneService 负责顾客手机号的验证与绑定。
+type PhoneService interface {
+	// SendBindingCode 向手机号下发绑定验证码，返回验证码有效期。
+	SendBindingCode(ctx context.Context, userID, phone, clientIP string) (time.Duration, error)
+	// BindPhone 校验验证码并将手机号绑定到用户。
+	BindPhone(ctx context.Context, userID, phone, code string) error
+	// BindWeChatPhone 用登录时保存的 session_key 解密小程序 getPhoneNumber 返回的数据并绑定手机号。
+	BindWeChatPhone(ctx context.Context, userID, encryptedData, iv string) (string, error)
+	// BindWeChatPhoneCode 用新版小程序 getPhoneNumber 返回的 code 换取并绑定手机号。
+	BindWeChatPhoneCode(ctx context.Context, userID, code string) (string, error)
+}
+
+const (
+	defaultCodeTTL            = 5 * time.Minute
+	defaultResendInterval     = time.Minute
+	defaultMaxCodeAttempts    = 5
+	defaultDailyLimitPerPhone = 10
+	defaultHourlyLimitPerIP   = 20
+
+	verificationCodeDigits = 6
+)
+
+// mainlandPhonePattern 匹配中国大陆 11 位手机号。
+var mainlandPhonePattern = regexp.MustCompile(`^1[3-9]\d{9}$`)
+
+var errPhoneDBUnavailable = errors.New("phone service database is not configured")
+
+// phonePolicy 是验证码有效期与限流参数。
+type phonePolicy struct {
+	codeTTL            time.Duration
+	resendInterval     time.Duration
+	maxAttempts        int
+	dailyLimitPerPhone int
+	hourlyLimitPerIP   int
+}
+
+// phoneVerificationPolicy 读取 phone_verification 配置，未配置的项使用默认值。
+func phoneVerificationPolicy(deps Dependencies) (phonePolicy, error) {
+	policy := phonePolicy{
+		codeTTL:            defaultCodeTTL,
+		resendInterval:     defaultResendInterval,
+		maxAttempts:        defaultMaxCodeAttempts,
+		dailyLimitPerPhone: defaultDailyLimitPerPhone,
+		hourlyLimitPerIP:   defaultHourlyLimitPerIP,
+	}
+	if deps.Config == nil {
+		return policy, nil
+	}
+
+	cfg := deps.Config.Phone
+	if cfg.CodeTTL != "" {
+		dur, err := time.ParseDuration(cfg.CodeTTL)
+		if err != nil {
+			return phonePolicy{}, fmt.Errorf("parse phone_verification code_ttl: %w", err)
+		}
+		policy.codeTTL = dur
+	}
+	if cfg.ResendInterval != "" {
+		dur, err := time.ParseDuration(cfg.ResendInterval)
+		if err != nil {
+			return phonePolicy{}, fmt.Errorf("parse phone_verification resend_interval: %w", err)
+		}
+		policy.resendInterval = dur
+	}
+	if cfg.MaxAttempts > 0 {
+		policy.maxAttempts = cfg.MaxAttempts
+	}
+	if cfg.DailyLimitPerPhone > 0 {
+		policy.dailyLimitPerPhone = cfg.DailyLimitPerPhone
+	}
+	if cfg.HourlyLimitPerIP > 0 {
+		policy.hourlyLimitPerIP = cfg.HourlyLimitPerIP
+	}
+	return policy, nil
+}
+
+type phoneService struct {
+	deps Dependencies
+}
+
+// NewPhoneService 创建 PhoneService 实例。
+func NewPhoneService(deps Dependencies) PhoneService {
+	return &phoneService{deps: deps}
+}
+
+func (s *phoneService) SendBindingCode(ctx context.Context, userID, phone, clientIP string) (time.Duration, error) {
+	if s.deps.DB == nil {
+		return 0, errPhoneDBUnavailable
+	}
+	if s.deps.SMS == nil {
+		return 0, errors.New("sms sender is not configured")
+	}
+	if !mainlandPhonePattern.MatchString(phone) {
+		return 0, model.NewError(model.ErrCodeInvalidParameter, "invalid phone number")
+	}
+	policy, err := phoneVerificationPolicy(s.deps)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.ensurePhoneAvailable(ctx, userID, phone); err != nil {
+		return 0, err
+	}
+
+	code, err := newVerificationCode()
+	if err != nil {
+		return 0, err
+	}
+
+	// 限流计数与写入新验证码在同一事务中完成，并先锁定号码与来源 IP 对应的锁行，
+	// 同一号码或 IP 的并发请求依次执行，不会同时通过限流检查。
+	err = runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		if err := lockVerificationKeyTx(ctx, tx, "phone:"+phone); err != nil {
+			return err
+		}
+		if err := lockVerificationKeyTx(ctx, tx, "ip:"+clientIP); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var (
+			lastSent   sql.NullTime
+			dailyCount int
+		)
+		const phoneQuery = `SELECT MAX(created_at), COUNT(*) FROM phone_verification_codes WHERE phone = ? AND created_at > ?`
+		if err := tx.QueryRowContext(ctx, phoneQuery, phone, now.Add(-24*time.Hour)).Scan(&lastSent, &dailyCount); err != nil {
+			return err
+		}
+		if lastSent.Valid {
+			if wait := lastSent.Time.Add(policy.resendInterval).Sub(now); wait > 0 {
+				return model.NewError(model.ErrCodeTooManyRequests, "verification code was sent recently, retry in %d seconds", int(wait.Seconds())+1).
+					WithDetails(map[string]int{"retry_after": int(wait.Seconds()) + 1})
+			}
+		}
+		if dailyCount >= policy.dailyLimitPerPhone {
+			return model.NewError(model.ErrCodeTooManyRequests, "daily verification code limit reached for this phone number")
+		}
+
+		var ipCount int
+		const ipQuery = `SELECT COUNT(*) FROM phone_verification_codes WHERE client_ip = ? AND created_at > ?`
+		if err := tx.QueryRowContext(ctx, ipQuery, clientIP, now.Add(-time.Hour)).Scan(&ipCount); err != nil {
+			return err
+		}
+		if ipCount >= policy.hourlyLimitPerIP {
+			return model.NewError(model.ErrCodeTooManyRequests, "too many verification code requests, retry later")
+		}
+
+		// 新验证码下发后，该用户此前为同一号码申请的验证码全部作废。
+		const invalidate = `UPDATE phone_verification_codes SET consumed_at = ? WHERE user_id = ? AND phone = ? AND consumed_at IS NULL`
+		if _, err := tx.ExecContext(ctx, invalidate, now, userID, phone); err != nil {
+			return err
+		}
+		const insert = `INSERT INTO phone_verification_codes (user_id, phone, code_hash, client_ip, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+		_, err := tx.ExecContext(ctx, insert, userID, phone, hashVerificationCode(phone, code), clientIP, now.Add(policy.codeTTL), now)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	content := fmt.Sprintf("您的验证码为 %s，%d 分钟内有效，用于绑定手机号，请勿泄露给他人。", code, int(policy.codeTTL.Minutes()))
+	if err := s.deps.SMS.Send(ctx, phone, content); err != nil {
+		return 0, fmt.Errorf("send verification sms: %w", err)
+	}
+	return policy.codeTTL, nil
+}
+
+func (s *phoneService) BindPhone(ctx context.Context, userID, phone, code string) error {
+	if s.deps.DB == nil {
+		return errPhoneDBUnavailable
+	}
+	if !mainlandPhonePattern.MatchString(phone) {
+		return model.NewError(model.ErrCodeInvalidParameter, "invalid phone number")
+	}
+	policy, err := phoneVerificationPolicy(s.deps)
+	if err != nil {
+		return err
+	}
+
+	invalid := model.NewError(model.ErrCodeInvalidCode, "verification code is invalid or expired")
+	var verified bool
+	err = runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		const query = `SELECT id, code_hash, attempts, expires_at FROM phone_verification_codes
+			WHERE user_id = ? AND phone = ? AND consumed_at IS NULL ORDER BY created_at DESC LIMIT 1 FOR UPDATE`
+		var (
+			id        int64
+			codeHash  string
+			attempts  int
+			expiresAt time.Time
+		)
+		err := tx.QueryRowContext(ctx, query, userID, phone).Scan(&id, &codeHash, &attempts, &expiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return invalid
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if !now.Before(expiresAt) || attempts >= policy.maxAttempts {
+			return invalid
+		}
+		if subtle.ConstantTimeCompare([]byte(codeHash), []byte(hashVerificationCode(phone, code))) != 1 {
+			// 校验失败也要提交事务，以便累计尝试次数，达到上限后验证码作废。
+			var consumedAt any
+			if attempts+1 >= policy.maxAttempts {
+				consumedAt = now
+			}
+			const fail = `UPDATE phone_verification_codes SET attempts = attempts + 1, consumed_at = ? WHERE id = ?`
+			_, err := tx.ExecContext(ctx, fail, consumedAt, id)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE phone_verification_codes SET consumed_at = ? WHERE id = ?`, now, id); err != nil {
+			return err
+		}
+		verified = true
+		return s.bindPhoneTx(ctx, tx, userID, phone)
+	})
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return invalid
+	}
+	return nil
+}
+
+func (s *phoneService) BindWeChatPhone(ctx context.Context, userID, encryptedData, iv string) (string, error) {
+	if s.deps.DB == nil {
+		return "", errPhoneDBUnavailable
+	}
+	if s.deps.WeChat == nil {
+		return "", errors.New("wechat mini program client is not configured")
+	}
+
+	var sessionKey sql.NullString
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT session_key FROM users WHERE id = ?`, userID).Scan(&sessionKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", model.NewError(model.ErrCodeUserNotFound, "user %s not found", userID)
+		}
+		return "", err
+	}
+	if sessionKey.String == "" {
+		return "", model.NewError(model.ErrCodeUnauthorized, "wechat session is missing, login again")
+	}
+
+	phone, err := s.deps.WeChat.DecryptPhoneNumber(sessionKey.String, encryptedData, iv)
+	if err != nil {
+		if errors.Is(err, wechat.ErrDecryptFailed) {
+			return "", model.NewError(model.ErrCodeUnauthorized, "wechat session has expired, login again")
+		}
+		return "", model.NewError(model.ErrCodeInvalidParameter, "%s", err.Error())
+	}
+	return s.bindWeChatPhone(ctx, userID, phone)
+}
+
+func (s *phoneService) BindWeChatPhoneCode(ctx context.Context, userID, code string) (string, error) {
+	if s.deps.DB == nil {
+		return "", errPhoneDBUnavailable
+	}
+	if s.deps.WeChat == nil {
+		return "", errors.New("wechat mini program client is not configured")
+	}
+
+	phone, err := s.deps.WeChat.GetPhoneNumber(ctx, code)
+	if err != nil {
+		if wechat.InvalidCode(err) {
+			return "", model.NewError(model.ErrCodeInvalidParameter, "wechat phone code is invalid or expired")
+		}
+		return "", err
+	}
+	return s.bindWeChatPhone(ctx, userID, phone)
+}
+
+// bindWeChatPhone 校验微信返回的手机号并绑定到用户。
+func (s *phoneService) bindWeChatPhone(ctx context.Context, userID string, phone wechat.PhoneNumber) (string, error) {
+	if phone.CountryCode != "86" || !mainlandPhonePattern.MatchString(phone.PurePhoneNumber) {
+		return "", model.NewError(model.ErrCodeInvalidParameter, "only mainland China phone numbers are supported")
+	}
+
+	err := runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		return s.bindPhoneTx(ctx, tx, userID, phone.PurePhoneNumber)
+	})
+	if err != nil {
+		return "", err
+	}
+	return phone.PurePhoneNumber, nil
+}
+
+// lockVerificationKeyTx 锁定限流键对应的锁行，锁行不存在时先插入，事务结束前其他请求在此等待。
+func lockVerificationKeyTx(ctx context.Context, tx *sql.Tx, key string) error {
+	const upsert = `INSERT INTO phone_verification_locks (lock_key) VALUES (?) ON DUPLICATE KEY UPDATE lock_key = lock_key`
+	if _, err := tx.ExecContext(ctx, upsert, key); err != nil {
+		return err
+	}
+	var locked string
+	return tx.QueryRowContext(ctx, `SELECT lock_key FROM phone_verification_locks WHERE lock_key = ? FOR UPDATE`, key).Scan(&locked)
+}
+
+// ensurePhoneAvailable 拒绝为已绑定到其他用户的手机号下发验证码。
+func (s *phoneService) ensurePhoneAvailable(ctx context.Context, userID, phone string) error {
+	var owner string
+	err := s.deps.DB.QueryRowContext(ctx, `SELECT id FROM users WHERE phone = ? AND id <> ? LIMIT 1`, phone, userID).Scan(&owner)
+	if err == nil {
+		return model.NewError(model.ErrCodePhoneInUse, "phone number is bound to another account")
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+func (s *phoneService) bindPhoneTx(ctx context.Context, tx *sql.Tx, userID, phone string) error {
+	var owner string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM users WHERE phone = ? AND id <> ? LIMIT 1 FOR UPDATE`, phone, userID).Scan(&owner)
+	if err == nil {
+		return model.NewError(model.ErrCodePhoneInUse, "phone number is bound to another account")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET phone = ?, updated_at = NOW() WHERE id = ?`, phone, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// 手机号未变化时 MySQL 也返回 0 行，需确认用户确实存在。
+		var exists int
+		if err := tx.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ?`, userID).Scan(&exists); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.NewError(model.ErrCodeUserNotFound, "user %s not found", userID)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// newVerificationCode 生成 6 位数字验证码。
+func newVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", verificationCodeDigits, n.Int64()), nil
+}
+
+// hashVerificationCode 以手机号为盐计算验证码摘要，库中不保存明文验证码。
+func hashVerificationCode(phone, code string) string {
+	sum := sha256.Sum256([]byte(phone + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,578 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/pkg/payment"
+)
+
+const (
+	defaultReportPageSize = 30
+	maxReportPageSize     = 100
+)
+
+// ReconciliationService 将支付渠道的日交易账单与本地已支付记录、已完成退款逐笔核对，生成差异报告。
+// 目前只有微信支付提供账单。
+type ReconciliationService interface {
+	// ReconcileDay 从渠道下载账单日的交易账单并对账。
+	ReconcileDay(ctx context.Context, billDate time.Time) (*model.ReconciliationReport, error)
+	// ImportBill 使用财务人员从商户平台导出的账单文件对账。
+	ImportBill(ctx context.Context, billDate time.Time, bill io.Reader) (*model.ReconciliationReport, error)
+	HasReport(ctx context.Context, billDate time.Time) (bool, error)
+	ListReports(ctx context.Context, limit int) ([]model.ReconciliationReport, error)
+	// GetReport 返回报告及其全部差异明细。
+	GetReport(ctx context.Context, reportID int64) (*model.ReconciliationReport, error)
+}
+
+var errReconciliationDBUnavailable = errors.New("reconciliation service database is not configured")
+
+type reconciliationService struct {
+	deps Dependencies
+}
+
+// NewReconciliationService 创建 ReconciliationService 实例。
+func NewReconciliationService(deps Dependencies) ReconciliationService {
+	return &reconciliationService{deps: deps}
+}
+
+// localPayment 是参与对账的一笔本地已支付记录。
+type localPayment struct {
+	OrderID       string
+	TransactionID string
+	Amount        model.Money
+}
+
+// localRefund 是参与对账的一笔本地成功退款，TransactionID 为原支付交易号。
+type localRefund struct {
+	RefundID      string
+	OrderID       string
+	TransactionID string
+	Amount        model.Money
+}
+
+// refundTransactionColumn 查询退款所属订单的支付交易号，首个占位符为已支付状态。
+const refundTransactionColumn = `COALESCE((SELECT p.transaction_id FROM payments p WHERE p.order_id = r.order_id AND p.provider = r.provider AND p.status = ? AND p.transaction_id IS NOT NULL LIMIT 1), '')`
+
+func (s *reconciliationService) ReconcileDay(ctx context.Context, billDate time.Time) (*model.ReconciliationReport, error) {
+	if s.deps.DB == nil {
+		return nil, errReconciliationDBUnavailable
+	}
+
+	gateway, ok := s.deps.Payments.Get(payment.ProviderWeChat)
+	if !ok {
+		return nil, model.NewError(model.ErrCodePaymentFailed, "payment provider %s is not configured", payment.ProviderWeChat)
+	}
+	downloader, ok := gateway.(payment.BillDownloader)
+	if !ok {
+		return nil, model.NewError(model.ErrCodePaymentFailed, "payment provider %s does not provide bills", payment.ProviderWeChat)
+	}
+
+	billDate = billDay(billDate)
+	data, err := downloader.DownloadTradeBill(ctx, billDate)
+	if err != nil {
+		return nil, model.NewError(model.ErrCodePaymentFailed, "download %s bill failed: %v", billDate.Format(time.DateOnly), err)
+	}
+	records, err := payment.ParseTradeBill(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse downloaded bill: %w", err)
+	}
+
+	return s.reconcile(ctx, payment.ProviderWeChat, billDate, model.ReconciliationSourceDownload, records)
+}
+
+func (s *reconciliationService) ImportBill(ctx context.Context, billDate time.Time, bill io.Reader) (*model.ReconciliationReport, error) {
+	if s.deps.DB == nil {
+		return nil, errReconciliationDBUnavailable
+	}
+
+	records, err := payment.ParseTradeBill(bill)
+	if err != nil {
+		return nil, model.NewError(model.ErrCodeInvalidParameter, "invalid bill file: %v", err)
+	}
+
+	return s.reconcile(ctx, payment.ProviderWeChat, billDay(billDate), model.ReconciliationSourceImport, records)
+}
+
+func (s *reconciliationService) HasReport(ctx context.Context, billDate time.Time) (bool, error) {
+	if s.deps.DB == nil {
+		return false, errReconciliationDBUnavailable
+	}
+
+	const query = `SELECT COUNT(*) FROM reconciliation_reports WHERE provider = ? AND bill_date = ?`
+	var count int
+	if err := s.deps.DB.QueryRowContext(ctx, query, payment.ProviderWeChat, billDay(billDate).Format(time.DateOnly)).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+const reportColumns = `id, provider, DATE_FORMAT(bill_date, '%Y-%m-%d'), source, remote_count, remote_amount, local_count, local_amount, matched_count, refund_remote_count, refund_remote_amount, refund_local_count, refund_local_amount, refund_matched_count, discrepancy_count, created_at`
+
+func scanReportRow(scanner interface {
+	Scan(dest ...any) error
+}) (*model.ReconciliationReport, error) {
+	var r model.ReconciliationReport
+	if err := scanner.Scan(&r.ID, &r.Provider, &r.BillDate, &r.Source, &r.RemoteCount, &r.RemoteAmount, &r.LocalCount, &r.LocalAmount, &r.MatchedCount,
+		&r.RefundRemoteCount, &r.RefundRemoteAmount, &r.RefundLocalCount, &r.RefundLocalAmount, &r.RefundMatchedCount, &r.DiscrepancyCount, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListReports 按账单日倒序返回最近的对账报告，不含差异明细。
+func (s *reconciliationService) ListReports(ctx context.Context, limit int) ([]model.ReconciliationReport, error) {
+	if s.deps.DB == nil {
+		return nil, errReconciliationDBUnavailable
+	}
+	if limit <= 0 {
+		limit = defaultReportPageSize
+	}
+	if limit > maxReportPageSize {
+		limit = maxReportPageSize
+	}
+
+	const query = `SELECT ` + reportColumns + ` FROM reconciliation_reports ORDER BY bill_date DESC, provider LIMIT ?`
+	rows, err := s.deps.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]model.ReconciliationReport, 0)
+	for rows.Next() {
+		report, err := scanReportRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
+
+func (s *reconciliationService) GetReport(ctx context.Context, reportID int64) (*model.ReconciliationReport, error) {
+	if s.deps.DB == nil {
+		return nil, errReconciliationDBUnavailable
+	}
+
+	const query = `SELECT ` + reportColumns + ` FROM reconciliation_reports WHERE id = ?`
+	report, err := scanReportRow(s.deps.DB.QueryRowContext(ctx, query, reportID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeReportNotFound, "reconciliation report %d not found", reportID)
+		}
+		return nil, err
+	}
+
+	const items = `SELECT id, type, order_id, transaction_id, refund_id, local_amount, remote_amount, note FROM reconciliation_discrepancies WHERE report_id = ? ORDER BY id`
+	rows, err := s.deps.DB.QueryContext(ctx, items, reportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report.Discrepancies = make([]model.ReconciliationDiscrepancy, 0, report.DiscrepancyCount)
+	for rows.Next() {
+		var (
+			d    model.ReconciliationDiscrepancy
+			note sql.NullString
+		)
+		if err := rows.Scan(&d.ID, &d.Type, &d.OrderID, &d.TransactionID, &d.RefundID, &d.LocalAmount, &d.RemoteAmount, &note); err != nil {
+			return nil, err
+		}
+		d.Note = note.String
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+	return report, rows.Err()
+}
+
+// reconcile 核对账单中的成功交易与退款，并替换该账单日已有的报告。
+func (s *reconciliationService) reconcile(ctx context.Context, provider string, billDate time.Time, source model.ReconciliationSource, records []payment.BillRecord) (*model.ReconciliationReport, error) {
+	report := &model.ReconciliationReport{
+		Provider:      provider,
+		BillDate:      billDate.Format(time.DateOnly),
+		Source:        source,
+		CreatedAt:     time.Now(),
+		Discrepancies: make([]model.ReconciliationDiscrepancy, 0),
+	}
+
+	var trades, refunds []payment.BillRecord
+	for _, record := range records {
+		switch record.TradeState {
+		case payment.TradeStateSuccess:
+			trades = append(trades, record)
+		case payment.TradeStateRefund:
+			// 账单中的退款行以退款状态为准，未成功的退款尚未出账，不参与核对。
+			if record.RefundStatus == "" || record.RefundStatus == payment.RefundStatusSuccess {
+				refunds = append(refunds, record)
+			}
+		}
+	}
+	if err := s.reconcilePayments(ctx, report, billDate, trades); err != nil {
+		return nil, err
+	}
+	if err := s.reconcileRefunds(ctx, report, billDate, refunds); err != nil {
+		return nil, err
+	}
+	report.DiscrepancyCount = len(report.Discrepancies)
+
+	if err := s.saveReport(ctx, report); err != nil {
+		return nil, err
+	}
+
+	s.deps.Logger.Printf("reconciled %s bill %s: remote=%d local=%d matched=%d refund_remote=%d refund_local=%d refund_matched=%d discrepancies=%d",
+		provider, report.BillDate, report.RemoteCount, report.LocalCount, report.MatchedCount,
+		report.RefundRemoteCount, report.RefundLocalCount, report.RefundMatchedCount, report.DiscrepancyCount)
+	return report, nil
+}
+
+// reconcilePayments 以渠道交易号匹配账单中的成功交易与本地已支付记录。
+// 本地记录按渠道确认的支付时间归入账单日；账单中的交易若在当日本地记录中找不到，
+// 会再按交易号查找全部已支付记录，以免零点前后的时间差被误报为缺失。
+// 支付记录在退款后仍保持 PAID，已退款订单的原交易照常参与核对。
+func (s *reconciliationService) reconcilePayments(ctx context.Context, report *model.ReconciliationReport, billDate time.Time, remote []payment.BillRecord) error {
+	local, err := s.localPayments(ctx, report.Provider, billDate)
+	if err != nil {
+		return err
+	}
+	report.LocalCount = len(local)
+	for _, p := range local {
+		report.LocalAmount += p.Amount
+	}
+
+	sort.SliceStable(remote, func(i, j int) bool { return remote[i].TradeTime.Before(remote[j].TradeTime) })
+	for _, record := range remote {
+		remoteAmount := model.Money(record.Amount)
+		report.RemoteCount++
+		report.RemoteAmount += remoteAmount
+
+		matched, ok := local[record.TransactionID]
+		if ok {
+			delete(local, record.TransactionID)
+		} else {
+			found, err := s.findPaidPayment(ctx, report.Provider, record.TransactionID)
+			if err != nil {
+				return err
+			}
+			if found == nil {
+				note, err := s.missingLocalNote(ctx, report.Provider, record.OrderID, record.TransactionID)
+				if err != nil {
+					return err
+				}
+				report.Discrepancies = append(report.Discrepancies, model.ReconciliationDiscrepancy{
+					Type:          model.DiscrepancyMissingLocal,
+					OrderID:       record.OrderID,
+					TransactionID: record.TransactionID,
+					RemoteAmount:  remoteAmount,
+					Note:          note,
+				})
+				continue
+			}
+			matched = *found
+		}
+
+		if matched.Amount != remoteAmount || matched.OrderID != record.OrderID {
+			note := ""
+			if matched.OrderID != record.OrderID {
+				note = fmt.Sprintf("bill order %s is recorded locally as order %s", record.OrderID, matched.OrderID)
+			}
+			report.Discrepancies = append(report.Discrepancies, model.ReconciliationDiscrepancy{
+				Type:          model.DiscrepancyAmountMismatch,
+				OrderID:       record.OrderID,
+				TransactionID: record.TransactionID,
+				LocalAmount:   matched.Amount,
+				RemoteAmount:  remoteAmount,
+				Note:          note,
+			})
+			continue
+		}
+		report.MatchedCount++
+	}
+
+	missingRemote := make([]localPayment, 0, len(local))
+	for _, p := range local {
+		missingRemote = append(missingRemote, p)
+	}
+	sort.Slice(missingRemote, func(i, j int) bool { return missingRemote[i].OrderID < missingRemote[j].OrderID })
+	for _, p := range missingRemote {
+		report.Discrepancies = append(report.Discrepancies, model.ReconciliationDiscrepancy{
+			Type:          model.DiscrepancyMissingRemote,
+			OrderID:       p.OrderID,
+			TransactionID: p.TransactionID,
+			LocalAmount:   p.Amount,
+		})
+	}
+	return nil
+}
+
+// reconcileRefunds 以商户退款单号匹配账单中的成功退款与本地已完成的退款，
+// 本地退款按渠道确认的完成时间归入账单日，跨日规则与支付交易相同。
+func (s *reconciliationService) reconcileRefunds(ctx context.Context, report *model.ReconciliationReport, billDate time.Time, remote []payment.BillRecord) error {
+	local, err := s.localRefunds(ctx, report.Provider, billDate)
+	if err != nil {
+		return err
+	}
+	report.RefundLocalCount = len(local)
+	for _, r := range local {
+		report.RefundLocalAmount += r.Amount
+	}
+
+	sort.SliceStable(remote, func(i, j int) bool { return remote[i].TradeTime.Before(remote[j].TradeTime) })
+	for _, record := range remote {
+		remoteAmount := model.Money(record.RefundAmount)
+		report.RefundRemoteCount++
+		report.RefundRemoteAmount += remoteAmount
+
+		matched, ok := local[record.RefundID]
+		if ok {
+			delete(local, record.RefundID)
+		} else {
+			found, err := s.findSucceededRefund(ctx, report.Provider, record.RefundID)
+			if err != nil {
+				return err
+			}
+			if found == nil {
+				note, err := s.missingLocalRefundNote(ctx, report.Provider, record.RefundID)
+				if err != nil {
+					return err
+				}
+				report.Discrepancies = append(report.Discrepancies, model.ReconciliationDiscrepancy{
+					Type:          model.DiscrepancyRefundMissingLocal,
+					OrderID:       record.OrderID,
+					TransactionID: record.TransactionID,
+					RefundID:      record.RefundID,
+					RemoteAmount:  remoteAmount,
+					Note:          note,
+				})
+				continue
+			}
+			matched = *found
+		}
+
+		if matched.Amount != remoteAmount || matched.OrderID != record.OrderID {
+			note := ""
+			if matched.OrderID != record.OrderID {
+				note = fmt.Sprintf("bill order %s is recorded locally as order %s", record.OrderID, matched.OrderID)
+			}
+			report.Discrepancies = append(report.Discrepancies, model.ReconciliationDiscrepancy{
+				Type:          model.DiscrepancyRefundAmountMismatch,
+				OrderID:       record.OrderID,
+				TransactionID: record.TransactionID,
+				RefundID:      record.RefundID,
+				LocalAmount:   matched.Amount,
+				RemoteAmount:  remoteAmount,
+				Note:          note,
+			})
+			continue
+		}
+		report.RefundMatchedCount++
+	}
+
+	missingRemote := make([]localRefund, 0, len(local))
+	for _, r := range local {
+		missingRemote = append(missingRemote, r)
+	}
+	sort.Slice(missingRemote, func(i, j int) bool { return missingRemote[i].RefundID < missingRemote[j].RefundID })
+	for _, r := range missingRemote {
+		report.Discrepancies = append(report.Discrepancies, model.ReconciliationDiscrepancy{
+			Type:          model.DiscrepancyRefundMissingRemote,
+			OrderID:       r.OrderID,
+			TransactionID: r.TransactionID,
+			RefundID:      r.RefundID,
+			LocalAmount:   r.Amount,
+		})
+	}
+	return nil
+}
+
+// localPayments 返回支付时间落在账单日内的已支付记录，以渠道交易号为键。
+// 支付时间优先取渠道通知中的 success_time，缺失时退回支付记录的更新时间。
+func (s *reconciliationService) localPayments(ctx context.Context, provider string, billDate time.Time) (map[string]localPayment, error) {
+	const query = `SELECT p.order_id, COALESCE(p.transaction_id, ''), p.amount
+		FROM payments p
+		LEFT JOIN payment_transactions t ON t.provider = p.provider AND t.transaction_id = p.transaction_id
+		WHERE p.provider = ? AND p.status = ? AND COALESCE(t.paid_at, p.updated_at) >= ? AND COALESCE(t.paid_at, p.updated_at) < ?`
+
+	rows, err := s.deps.DB.QueryContext(ctx, query, provider, model.PaymentStatusPaid, billDate, billDate.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make(map[string]localPayment)
+	for rows.Next() {
+		var p localPayment
+		if err := rows.Scan(&p.OrderID, &p.TransactionID, &p.Amount); err != nil {
+			return nil, err
+		}
+		key := p.TransactionID
+		if key == "" {
+			key = "order:" + p.OrderID
+		}
+		payments[key] = p
+	}
+	return payments, rows.Err()
+}
+
+// findPaidPayment 按渠道交易号查找任意日期的已支付记录，没有时返回 nil。
+func (s *reconciliationService) findPaidPayment(ctx context.Context, provider, transactionID string) (*localPayment, error) {
+	const query = `SELECT order_id, transaction_id, amount FROM payments WHERE provider = ? AND transaction_id = ? AND status = ? LIMIT 1`
+	var p localPayment
+	err := s.deps.DB.QueryRowContext(ctx, query, provider, transactionID, model.PaymentStatusPaid).Scan(&p.OrderID, &p.TransactionID, &p.Amount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// localRefunds 返回完成时间落在账单日内的成功退款，以商户退款单号为键。
+// 交易号取订单的已支付记录，便于在差异中定位原交易。
+func (s *reconciliationService) localRefunds(ctx context.Context, provider string, billDate time.Time) (map[string]localRefund, error) {
+	const query = `SELECT r.id, r.order_id, ` + refundTransactionColumn + `, r.amount
+		FROM refunds r
+		WHERE r.provider = ? AND r.status = ? AND r.completed_at >= ? AND r.completed_at < ?`
+
+	rows, err := s.deps.DB.QueryContext(ctx, query, model.PaymentStatusPaid, provider, model.RefundStatusSucceeded, billDate, billDate.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := make(map[string]localRefund)
+	for rows.Next() {
+		var r localRefund
+		if err := rows.Scan(&r.RefundID, &r.OrderID, &r.TransactionID, &r.Amount); err != nil {
+			return nil, err
+		}
+		refunds[r.RefundID] = r
+	}
+	return refunds, rows.Err()
+}
+
+// findSucceededRefund 按商户退款单号查找任意日期的成功退款，没有时返回 nil。
+func (s *reconciliationService) findSucceededRefund(ctx context.Context, provider, refundID string) (*localRefund, error) {
+	if refundID == "" {
+		return nil, nil
+	}
+	const query = `SELECT r.id, r.order_id, ` + refundTransactionColumn + `, r.amount FROM refunds r WHERE r.id = ? AND r.provider = ? AND r.status = ?`
+	var r localRefund
+	err := s.deps.DB.QueryRowContext(ctx, query, model.PaymentStatusPaid, refundID, provider, model.RefundStatusSucceeded).Scan(&r.RefundID, &r.OrderID, &r.TransactionID, &r.Amount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// missingLocalRefundNote 说明渠道退款在本地缺失的原因线索：本地是否有该退款及其当前状态。
+func (s *reconciliationService) missingLocalRefundNote(ctx context.Context, provider, refundID string) (string, error) {
+	if refundID == "" {
+		return "bill refund has no merchant refund number", nil
+	}
+	const query = `SELECT provider, status FROM refunds WHERE id = ?`
+	var refundProvider, status string
+	err := s.deps.DB.QueryRowContext(ctx, query, refundID).Scan(&refundProvider, &status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "refund not found", nil
+	case err != nil:
+		return "", err
+	case refundProvider != provider:
+		return "refund recorded with provider " + refundProvider, nil
+	default:
+		return "refund " + status, nil
+	}
+}
+
+// missingLocalNote 说明本地缺失的原因线索：交易是否收到过通知、订单当前状态。
+func (s *reconciliationService) missingLocalNote(ctx context.Context, provider, orderID, transactionID string) (string, error) {
+	var notes []string
+
+	const txnQuery = `SELECT status, note FROM payment_transactions WHERE provider = ? AND transaction_id = ?`
+	var (
+		txnStatus string
+		txnNote   sql.NullString
+	)
+	err := s.deps.DB.QueryRowContext(ctx, txnQuery, provider, transactionID).Scan(&txnStatus, &txnNote)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		notes = append(notes, "no payment notification received")
+	case err != nil:
+		return "", err
+	default:
+		note := "transaction " + txnStatus
+		if txnNote.String != "" {
+			note += ": " + txnNote.String
+		}
+		notes = append(notes, note)
+	}
+
+	const orderQuery = `SELECT status FROM orders WHERE id = ?`
+	var orderStatus string
+	err = s.deps.DB.QueryRowContext(ctx, orderQuery, orderID).Scan(&orderStatus)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		notes = append(notes, "order not found")
+	case err != nil:
+		return "", err
+	default:
+		notes = append(notes, "order "+orderStatus)
+	}
+
+	return strings.Join(notes, "; "), nil
+}
+
+// saveReport 在事务中替换同一账单日的旧报告并写入差异明细。
+func (s *reconciliationService) saveReport(ctx context.Context, report *model.ReconciliationReport) error {
+	return runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		const remove = `DELETE FROM reconciliation_reports WHERE provider = ? AND bill_date = ?`
+		if _, err := tx.ExecContext(ctx, remove, report.Provider, report.BillDate); err != nil {
+			return err
+		}
+
+		const insert = `INSERT INTO reconciliation_reports (provider, bill_date, source, remote_count, remote_amount, local_count, local_amount, matched_count,
+			refund_remote_count, refund_remote_amount, refund_local_count, refund_local_amount, refund_matched_count, discrepancy_count, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		result, err := tx.ExecContext(ctx, insert, report.Provider, report.BillDate, report.Source, report.RemoteCount, report.RemoteAmount,
+			report.LocalCount, report.LocalAmount, report.MatchedCount, report.RefundRemoteCount, report.RefundRemoteAmount,
+			report.RefundLocalCount, report.RefundLocalAmount, report.RefundMatchedCount, report.DiscrepancyCount, report.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if report.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		const item = `INSERT INTO reconciliation_discrepancies (report_id, type, order_id, transaction_id, refund_id, local_amount, remote_amount, note) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		for i := range report.Discrepancies {
+			d := &report.Discrepancies[i]
+			result, err := tx.ExecContext(ctx, item, report.ID, d.Type, d.OrderID, d.TransactionID, d.RefundID, d.LocalAmount, d.RemoteAmount, nullableString(d.Note))
+			if err != nil {
+				return err
+			}
+			if d.ID, err = result.LastInsertId(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// billDay 将时间归一为账单时区当日零点。
+func billDay(t time.Time) time.Time {
+	y, m, d := t.In(payment.BillLocation).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, payment.BillLocation)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"convenienceStore/pkg/payment"
+)
+
+const (
+	defaultReconciliationRunAt         = 10 * time.Hour
+	defaultReconciliationCheckInterval = 30 * time.Minute
+)
+
+// ReconciliationWorker 每天在渠道生成账单后自动对账前一日的交易，失败时在下次检查时重试。
+type ReconciliationWorker struct {
+	logger        *log.Logger
+	service       ReconciliationService
+	runAt         time.Duration
+	checkInterval time.Duration
+}
+
+// NewReconciliationWorker 根据 reconciliation 配置构建每日对账任务，未配置的项使用默认值。
+func NewReconciliationWorker(deps Dependencies, service ReconciliationService) (*ReconciliationWorker, error) {
+	worker := &ReconciliationWorker{
+		logger:        deps.Logger,
+		service:       service,
+		runAt:         defaultReconciliationRunAt,
+		checkInterval: defaultReconciliationCheckInterval,
+	}
+	if deps.Config == nil {
+		return worker, nil
+	}
+
+	cfg := deps.Config.Reconciliation
+	if cfg.RunAt != "" {
+		runAt, err := time.Parse("15:04", cfg.RunAt)
+		if err != nil {
+			return nil, fmt.Errorf("parse reconciliation run_at: %w", err)
+		}
+		worker.runAt = time.Duration(runAt.Hour())*time.Hour + time.Duration(runAt.Minute())*time.Minute
+	}
+	if cfg.CheckInterval != "" {
+		dur, err := time.ParseDuration(cfg.CheckInterval)
+		if err != nil {
+			return nil, fmt.Errorf("parse reconciliation check_interval: %w", err)
+		}
+		worker.checkInterval = dur
+	}
+	if worker.checkInterval <= 0 {
+		return nil, errors.New("reconciliation check_interval must be positive")
+	}
+
+	return worker, nil
+}
+
+// Run 阻塞执行检查循环，直到 ctx 被取消。
+func (w *ReconciliationWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		w.reconcile(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile 在当日对账时刻之后检查前一日是否已有报告，没有则下载账单对账。
+func (w *ReconciliationWorker) reconcile(ctx context.Context, now time.Time) {
+	today := billDay(now)
+	if now.In(payment.BillLocation).Before(today.Add(w.runAt)) {
+		return
+	}
+
+	billDate := today.AddDate(0, 0, -1)
+	exists, err := w.service.HasReport(ctx, billDate)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Printf("check reconciliation report for %s failed: %v", billDate.Format(time.DateOnly), err)
+		}
+		return
+	}
+	if exists {
+		return
+	}
+
+	if _, err := w.service.ReconcileDay(ctx, billDate); err != nil && ctx.Err() == nil {
+		w.logger.Printf("reconcile bill %s failed: %v", billDate.Format(time.DateOnly), err)
+	}
+}
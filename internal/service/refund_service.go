@@ -0,0 +1,763 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/pkg/payment"
+	"convenienceStore/pkg/uid"
+)
+
+// RefundService 负责已支付订单的全额与按商品部分退款。
+// 顾客申请的退款除未发货订单外需由员工审核，员工确认商品已退回后才归还库存。
+type RefundService interface {
+	RequestRefund(ctx context.Context, request RefundRequest) (*model.Refund, error)
+	RefundOrder(ctx context.Context, request RefundRequest, goodsReturned bool) (*model.Refund, error)
+	ApproveRefund(ctx context.Context, refundID string, restock bool) (*model.Refund, error)
+	RejectRefund(ctx context.Context, refundID, reason string) (*model.Refund, error)
+	ListPendingRefunds(ctx context.Context) ([]model.Refund, error)
+	ListRefunds(ctx context.Context, orderID string) ([]model.Refund, error)
+	HandleWeChatRefundCallback(ctx context.Context, headers http.Header, payload []byte) error
+	// SettleProcessingRefunds 向渠道查询最近更新早于 before 的处理中退款并结束已有结果的退款，返回结束的数量。
+	SettleProcessingRefunds(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// RefundRequest 描述一次退款申请，Items 为空表示退还订单剩余的全部商品与金额。
+type RefundRequest struct {
+	OrderID string
+	Items   []model.RefundItem
+	Reason  string
+}
+
+var (
+	errRefundDBUnavailable = errors.New("refund service database is not configured")
+	// errRefundSettled 表示退款已由其他通知或请求处理完毕。
+	errRefundSettled = errors.New("refund already settled")
+)
+
+type refundService struct {
+	deps Dependencies
+}
+
+// NewRefundService 创建 RefundService 实例。
+func NewRefundService(deps Dependencies) RefundService {
+	return &refundService{deps: deps}
+}
+
+// RequestRefund 将订单置为退款中并登记退款单。
+// 以线上渠道支付且尚未发货的订单直接向渠道提交退款并归还库存；其余订单（已发货、已完成或货到付款）
+// 登记为待审核，由员工通过 ApproveRefund 确认退货后再退款。
+// 同一订单同时只能有一笔退款在处理，渠道受理结果未知时退款保持处理中，由退款通知或 SettleProcessingRefunds 查询结束。
+func (s *refundService) RequestRefund(ctx context.Context, request RefundRequest) (*model.Refund, error) {
+	return s.createRefund(ctx, request, false, false)
+}
+
+// RefundOrder 由有退款权限的员工直接为订单退款，无需审核；goodsReturned 表示已发货的商品已退回入库。
+func (s *refundService) RefundOrder(ctx context.Context, request RefundRequest, goodsReturned bool) (*model.Refund, error) {
+	return s.createRefund(ctx, request, true, goodsReturned)
+}
+
+// createRefund 登记退款单，byStaff 为 true 时视为已审核并立即提交渠道。
+func (s *refundService) createRefund(ctx context.Context, request RefundRequest, byStaff, goodsReturned bool) (*model.Refund, error) {
+	if s.deps.DB == nil {
+		return nil, errRefundDBUnavailable
+	}
+	if request.OrderID == "" {
+		return nil, model.NewError(model.ErrCodeInvalidParameter, "order id is required")
+	}
+
+	provider, err := s.paidProvider(ctx, request.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.deps.Payments.Get(provider); !ok {
+		return nil, model.NewError(model.ErrCodePaymentFailed, "payment provider %s of order %s is not configured", provider, request.OrderID)
+	}
+
+	now := time.Now()
+	refund := &model.Refund{
+		ID:        uid.New("rf_"),
+		OrderID:   request.OrderID,
+		Provider:  provider,
+		Status:    model.RefundStatusPending,
+		Reason:    request.Reason,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	change := statusChange{Source: model.OrderEventSourceCustomer, Reason: request.Reason}
+	if byStaff {
+		change.Source = model.OrderEventSourceAdmin
+	}
+	err = updateOrderStatus(ctx, s.deps.DB, request.OrderID, model.OrderStatusRefunding, change, func(tx *sql.Tx, from model.OrderStatus) error {
+		refund.PreviousStatus = from
+		// 未发货的商品仍在店内，可以直接退款并归还库存；线下收款需店员当面退还现金，顾客申请一律审核。
+		if byStaff || (from == model.OrderStatusPaid && provider != model.PaymentProviderOffline) {
+			refund.Status = model.RefundStatusProcessing
+			refund.Restock = goodsReturned || from == model.OrderStatusPaid
+		}
+
+		var total, discount model.Money
+		if err := tx.QueryRowContext(ctx, `SELECT total, points_discount FROM orders WHERE id = ?`, request.OrderID).Scan(&total, &discount); err != nil {
+			return err
+		}
+		items, amount, err := s.refundableItemsTx(ctx, tx, request.OrderID, total, discount, request.Items)
+		if err != nil {
+			return err
+		}
+		refund.Items = items
+		refund.Amount = amount
+
+		return insertRefundTx(ctx, tx, refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.Status == model.RefundStatusPending {
+		return refund, nil
+	}
+	return s.submitRefund(ctx, refund)
+}
+
+// ApproveRefund 由员工审核通过待审核的退款并提交渠道退款，restock 表示员工已确认退回的商品入库。
+// 未发货订单的商品始终归还库存。
+func (s *refundService) ApproveRefund(ctx context.Context, refundID string, restock bool) (*model.Refund, error) {
+	if s.deps.DB == nil {
+		return nil, errRefundDBUnavailable
+	}
+
+	refund, err := s.loadRefund(ctx, refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != model.RefundStatusPending {
+		return nil, refundNotPendingError(refund)
+	}
+	restock = restock || refund.PreviousStatus == model.OrderStatusPaid
+
+	err = runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		const stmt = `UPDATE refunds SET status = ?, restock = ?, updated_at = NOW() WHERE id = ? AND status = ?`
+		res, err := tx.ExecContext(ctx, stmt, model.RefundStatusProcessing, restock, refund.ID, model.RefundStatusPending)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return refundNotPendingError(refund)
+		}
+		// 审核不改变订单状态，仍记录一条事件以便在时间线中追溯审核人。
+		return insertOrderEventTx(ctx, tx, model.OrderEvent{
+			OrderID:    refund.OrderID,
+			FromStatus: model.OrderStatusRefunding,
+			ToStatus:   model.OrderStatusRefunding,
+			Reason:     fmt.Sprintf("refund %s approved", refund.ID),
+		}, resolveOperator(ctx, model.OrderEventSourceAdmin))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refund.Status = model.RefundStatusProcessing
+	refund.Restock = restock
+	return s.submitRefund(ctx, refund)
+}
+
+// RejectRefund 由员工驳回待审核的退款，订单回到申请退款前的状态。
+func (s *refundService) RejectRefund(ctx context.Context, refundID, reason string) (*model.Refund, error) {
+	if s.deps.DB == nil {
+		return nil, errRefundDBUnavailable
+	}
+
+	refund, err := s.loadRefund(ctx, refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != model.RefundStatusPending {
+		return nil, refundNotPendingError(refund)
+	}
+
+	change := statusChange{Source: model.OrderEventSourceAdmin, Reason: fmt.Sprintf("refund %s rejected: %s", refund.ID, reason)}
+	err = updateOrderStatus(ctx, s.deps.DB, refund.OrderID, refund.PreviousStatus, change,
+		requireFromStatus(refund.OrderID, refund.PreviousStatus, model.OrderStatusRefunding),
+		func(tx *sql.Tx, from model.OrderStatus) error {
+			const stmt = `UPDATE refunds SET status = ?, completed_at = NOW(), updated_at = NOW() WHERE id = ? AND status = ?`
+			res, err := tx.ExecContext(ctx, stmt, model.RefundStatusRejected, refund.ID, model.RefundStatusPending)
+			if err != nil {
+				return err
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				return refundNotPendingError(refund)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.loadRefund(ctx, refund.ID)
+}
+
+func refundNotPendingError(refund *model.Refund) error {
+	return model.NewError(model.ErrCodeInvalidTransition, "refund %s is %s, not pending approval", refund.ID, refund.Status).
+		WithDetails(map[string]any{"status": refund.Status})
+}
+
+// ListPendingRefunds 按申请时间先后返回全部待审核的退款，供员工处理。
+func (s *refundService) ListPendingRefunds(ctx context.Context) ([]model.Refund, error) {
+	if s.deps.DB == nil {
+		return nil, errRefundDBUnavailable
+	}
+
+	const query = `SELECT ` + refundColumns + ` FROM refunds WHERE status = ? ORDER BY created_at, id`
+	return s.queryRefunds(ctx, query, model.RefundStatusPending)
+}
+
+// submitRefund 向支付渠道提交处理中的退款，并按渠道的同步结果结束退款。
+// 渠道明确拒绝时退款失败、订单回到原状态；网络超时等结果未知时退款保持处理中，留待查询。
+func (s *refundService) submitRefund(ctx context.Context, refund *model.Refund) (*model.Refund, error) {
+	gateway, ok := s.deps.Payments.Get(refund.Provider)
+	if !ok {
+		return nil, model.NewError(model.ErrCodePaymentFailed, "payment provider %s of order %s is not configured", refund.Provider, refund.OrderID)
+	}
+
+	var total model.Money
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT total FROM orders WHERE id = ?`, refund.OrderID).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	resp, err := gateway.Refund(ctx, payment.RefundRequest{
+		OrderID:  refund.OrderID,
+		RefundID: refund.ID,
+		Amount:   refund.Amount.Fen(),
+		Total:    total.Fen(),
+		Reason:   refund.Reason,
+	})
+	if err != nil {
+		if !payment.Rejected(err) {
+			s.deps.Logger.Printf("refund submission outcome unknown refund_id=%s order_id=%s: %v", refund.ID, refund.OrderID, err)
+			return refund, nil
+		}
+		if settleErr := s.settleRefund(ctx, refund.ID, false, "", time.Time{}, model.OrderEventSourceSystem); settleErr != nil {
+			return nil, settleErr
+		}
+		return nil, model.NewError(model.ErrCodePaymentFailed, "refund order %s: %v", refund.OrderID, err)
+	}
+
+	refund.ProviderRefundID = resp.ProviderRefundID
+	switch resp.Status {
+	case payment.RefundStatusSuccess:
+		if err := s.settleRefund(ctx, refund.ID, true, resp.ProviderRefundID, resp.SuccessTime, model.OrderEventSourceSystem); err != nil {
+			return nil, err
+		}
+	case payment.RefundStatusClosed, payment.RefundStatusAbnormal:
+		if err := s.settleRefund(ctx, refund.ID, false, resp.ProviderRefundID, time.Time{}, model.OrderEventSourceSystem); err != nil {
+			return nil, err
+		}
+	default:
+		const stmt = `UPDATE refunds SET provider_refund_id = ?, updated_at = NOW() WHERE id = ? AND status = ?`
+		if _, err := s.deps.DB.ExecContext(ctx, stmt, nullableString(resp.ProviderRefundID), refund.ID, model.RefundStatusProcessing); err != nil {
+			return nil, err
+		}
+		return refund, nil
+	}
+
+	return s.loadRefund(ctx, refund.ID)
+}
+
+func (s *refundService) SettleProcessingRefunds(ctx context.Context, before time.Time, limit int) (int, error) {
+	if s.deps.DB == nil {
+		return 0, errRefundDBUnavailable
+	}
+
+	const query = `SELECT ` + refundColumns + ` FROM refunds WHERE status = ? AND updated_at < ? ORDER BY updated_at, id LIMIT ?`
+	refunds, err := s.queryRefunds(ctx, query, model.RefundStatusProcessing, before, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for i := range refunds {
+		refund := &refunds[i]
+		done, err := s.settleProcessingRefund(ctx, refund)
+		if err != nil {
+			if ctx.Err() != nil {
+				return settled, ctx.Err()
+			}
+			s.deps.Logger.Printf("settle processing refund failed refund_id=%s order_id=%s: %v", refund.ID, refund.OrderID, err)
+		}
+		if done {
+			settled++
+			continue
+		}
+
+		// 仍无结果的退款刷新更新时间排到队尾，避免反复查询同一批退款。
+		const touch = `UPDATE refunds SET updated_at = NOW() WHERE id = ? AND status = ?`
+		if _, err := s.deps.DB.ExecContext(ctx, touch, refund.ID, model.RefundStatusProcessing); err != nil {
+			return settled, err
+		}
+	}
+	return settled, nil
+}
+
+// settleProcessingRefund 按渠道查询结果结束退款，返回退款是否已结束。
+// 渠道没有该退款单说明申请未送达，以同一退款单号重新提交，渠道按单号保证不会重复退款。
+func (s *refundService) settleProcessingRefund(ctx context.Context, refund *model.Refund) (bool, error) {
+	gateway, ok := s.deps.Payments.Get(refund.Provider)
+	if !ok {
+		return false, fmt.Errorf("payment provider %s is not configured", refund.Provider)
+	}
+
+	resp, err := gateway.QueryRefund(ctx, refund.OrderID, refund.ID)
+	if errors.Is(err, payment.ErrRefundNotFound) {
+		s.deps.Logger.Printf("refund %s of order %s not found at %s, resubmitting", refund.ID, refund.OrderID, refund.Provider)
+		if _, err := s.submitRefund(ctx, refund); err != nil {
+			if code, ok := model.ErrorCodeOf(err); !ok || code != model.ErrCodePaymentFailed {
+				return false, err
+			}
+		}
+		latest, err := s.loadRefund(ctx, refund.ID)
+		if err != nil {
+			return false, err
+		}
+		return latest.Status != model.RefundStatusProcessing, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ctx = WithOperator(ctx, Operator{Actor: refund.Provider + ":" + resp.ProviderRefundID, Source: model.OrderEventSourceSystem})
+	switch resp.Status {
+	case payment.RefundStatusSuccess:
+		err = s.settleRefund(ctx, refund.ID, true, resp.ProviderRefundID, resp.SuccessTime, model.OrderEventSourceSystem)
+	case payment.RefundStatusClosed, payment.RefundStatusAbnormal:
+		err = s.settleRefund(ctx, refund.ID, false, resp.ProviderRefundID, time.Time{}, model.OrderEventSourceSystem)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// paidProvider 返回订单实际完成支付的支付方式，早于支付记录上线的订单按微信支付处理。
+func (s *refundService) paidProvider(ctx context.Context, orderID string) (string, error) {
+	const query = `SELECT provider FROM payments WHERE order_id = ? AND status = ? ORDER BY updated_at DESC LIMIT 1`
+	var provider string
+	if err := s.deps.DB.QueryRowContext(ctx, query, orderID, model.PaymentStatusPaid).Scan(&provider); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.PaymentProviderWeChat, nil
+		}
+		return "", err
+	}
+	return provider, nil
+}
+
+// refundableItemsTx 校验退款商品数量不超过尚未退款的数量，并按成交单价计算退款金额；
+// 订单使用了积分抵扣时，抵扣金额按商品金额比例分摊，只退还商品的实付部分。
+// 退还全部剩余商品时以订单总额减去已退金额，保证累计退款与实付金额一致。
+func (s *refundService) refundableItemsTx(ctx context.Context, tx *sql.Tx, orderID string, total, discount model.Money, requested []model.RefundItem) ([]model.RefundItem, model.Money, error) {
+	type purchase struct {
+		quantity int
+		price    model.Money
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT product_id, quantity, price FROM order_items WHERE order_id = ?`, orderID)
+	if err != nil {
+		return nil, 0, err
+	}
+	purchases := make(map[string]*purchase)
+	var productIDs []string
+	for rows.Next() {
+		var (
+			productID string
+			quantity  int
+			price     model.Money
+		)
+		if err := rows.Scan(&productID, &quantity, &price); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		if p, ok := purchases[productID]; ok {
+			p.quantity += quantity
+			continue
+		}
+		purchases[productID] = &purchase{quantity: quantity, price: price}
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	sort.Strings(productIDs)
+
+	const refundedQuery = `SELECT ri.product_id, SUM(ri.quantity) FROM refund_items ri JOIN refunds r ON r.id = ri.refund_id WHERE r.order_id = ? AND r.status IN (?, ?, ?) GROUP BY ri.product_id`
+	rows, err = tx.QueryContext(ctx, refundedQuery, orderID, model.RefundStatusPending, model.RefundStatusProcessing, model.RefundStatusSucceeded)
+	if err != nil {
+		return nil, 0, err
+	}
+	remaining := make(map[string]int, len(purchases))
+	for id, p := range purchases {
+		remaining[id] = p.quantity
+	}
+	for rows.Next() {
+		var (
+			productID string
+			quantity  int
+		)
+		if err := rows.Scan(&productID, &quantity); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		remaining[productID] -= quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var refunded model.Money
+	const amountQuery = `SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE order_id = ? AND status IN (?, ?, ?)`
+	if err := tx.QueryRowContext(ctx, amountQuery, orderID, model.RefundStatusPending, model.RefundStatusProcessing, model.RefundStatusSucceeded).Scan(&refunded); err != nil {
+		return nil, 0, err
+	}
+
+	var items []model.RefundItem
+	if len(requested) == 0 {
+		for _, productID := range productIDs {
+			if remaining[productID] > 0 {
+				items = append(items, model.RefundItem{ProductID: productID, Quantity: remaining[productID]})
+			}
+		}
+	} else {
+		for _, item := range requested {
+			if item.Quantity <= 0 {
+				return nil, 0, model.NewError(model.ErrCodeInvalidParameter, "refund quantity for %s must be positive", item.ProductID)
+			}
+		}
+		productIDs, quantities := aggregateRefundQuantities(requested)
+		for _, productID := range productIDs {
+			quantity := quantities[productID]
+			if _, ok := purchases[productID]; !ok {
+				return nil, 0, model.NewError(model.ErrCodeInvalidParameter, "product %s is not part of order %s", productID, orderID)
+			}
+			if quantity > remaining[productID] {
+				return nil, 0, model.NewError(model.ErrCodeRefundExceeded, "refund quantity for %s exceeds the %d remaining", productID, remaining[productID]).
+					WithDetails(model.InventoryShortage{ProductID: productID, Requested: quantity, Available: remaining[productID]})
+			}
+			items = append(items, model.RefundItem{ProductID: productID, Quantity: quantity})
+			remaining[productID] -= quantity
+		}
+	}
+
+	var amount model.Money
+	for i := range items {
+		items[i].Amount = purchases[items[i].ProductID].price.Mul(items[i].Quantity)
+		if discount > 0 {
+			items[i].Amount = model.Money(items[i].Amount.Fen() * total.Fen() / (total + discount).Fen())
+		}
+		amount += items[i].Amount
+	}
+
+	fullyReturned := true
+	for _, quantity := range remaining {
+		if len(requested) > 0 && quantity > 0 {
+			fullyReturned = false
+			break
+		}
+	}
+	if fullyReturned {
+		amount = total - refunded
+	}
+	if amount <= 0 || refunded+amount > total {
+		return nil, 0, model.NewError(model.ErrCodeRefundExceeded, "order %s has no refundable amount left", orderID)
+	}
+
+	return items, amount, nil
+}
+
+// aggregateRefundQuantities 合并同一商品的退款数量，商品 ID 按字典序返回。
+func aggregateRefundQuantities(items []model.RefundItem) ([]string, map[string]int) {
+	orderItems := make([]model.OrderItem, 0, len(items))
+	for _, item := range items {
+		orderItems = append(orderItems, model.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return aggregateItemQuantities(orderItems)
+}
+
+func insertRefundTx(ctx context.Context, tx *sql.Tx, refund *model.Refund) error {
+	const stmt = `INSERT INTO refunds (id, order_id, provider, amount, status, reason, previous_status, restock, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, stmt, refund.ID, refund.OrderID, refund.Provider, refund.Amount, refund.Status, nullableString(refund.Reason), refund.PreviousStatus, refund.Restock, refund.CreatedAt, refund.UpdatedAt); err != nil {
+		return err
+	}
+
+	const itemStmt = `INSERT INTO refund_items (refund_id, product_id, quantity, amount) VALUES (?, ?, ?, ?)`
+	for _, item := range refund.Items {
+		if _, err := tx.ExecContext(ctx, itemStmt, refund.ID, item.ProductID, item.Quantity, item.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleWeChatRefundCallback 处理微信支付退款结果通知，重复通知不会重复退款或归还库存。
+func (s *refundService) HandleWeChatRefundCallback(ctx context.Context, headers http.Header, payload []byte) error {
+	if s.deps.DB == nil {
+		return errRefundDBUnavailable
+	}
+
+	gateway, ok := s.deps.Payments.Get(model.PaymentProviderWeChat)
+	if !ok {
+		return errors.New("wechat pay is not configured")
+	}
+	notifier, ok := gateway.(payment.RefundNotifier)
+	if !ok {
+		return errors.New("wechat pay provider does not support refund notifications")
+	}
+	result, err := notifier.HandleRefundCallback(ctx, headers, payload)
+	if err != nil {
+		return err
+	}
+
+	var success bool
+	switch result.RefundStatus {
+	case payment.RefundStatusSuccess:
+		success = true
+	case payment.RefundStatusClosed, payment.RefundStatusAbnormal:
+		success = false
+	default:
+		s.deps.Logger.Printf("wechat refund callback ignored refund_id=%s status=%s", result.RefundID, result.RefundStatus)
+		return nil
+	}
+
+	refund, err := s.loadRefund(ctx, result.RefundID)
+	if err != nil {
+		if code, ok := model.ErrorCodeOf(err); ok && code == model.ErrCodeRefundNotFound {
+			s.deps.Logger.Printf("wechat refund callback for unknown refund_id=%s order_id=%s", result.RefundID, result.OrderID)
+			return nil
+		}
+		return err
+	}
+	if refund.OrderID != result.OrderID || refund.Amount.Fen() != result.Amount {
+		s.deps.Logger.Printf("wechat refund callback mismatch refund_id=%s order_id=%s amount=%d, expected order_id=%s amount=%d",
+			result.RefundID, result.OrderID, result.Amount, refund.OrderID, refund.Amount.Fen())
+		return nil
+	}
+
+	ctx = WithOperator(ctx, Operator{Actor: refund.Provider + ":" + result.ProviderRefundID, Source: model.OrderEventSourceCallback})
+	return s.settleRefund(ctx, refund.ID, success, result.ProviderRefundID, result.SuccessTime, model.OrderEventSourceCallback)
+}
+
+// settleRefund 结束处理中的退款：成功时按退款金额扣回积分，员工确认退货的退款同时归还库存，
+// 累计退款达到订单总额则订单转为已退款，否则订单回到发起退款前的状态。
+func (s *refundService) settleRefund(ctx context.Context, refundID string, success bool, providerRefundID string, completedAt time.Time, source model.OrderEventSource) error {
+	refund, err := s.loadRefund(ctx, refundID)
+	if err != nil {
+		return err
+	}
+	if refund.Status != model.RefundStatusProcessing {
+		return nil
+	}
+
+	status := model.RefundStatusFailed
+	target := refund.PreviousStatus
+	reason := fmt.Sprintf("refund %s failed", refund.ID)
+	if success {
+		status = model.RefundStatusSucceeded
+		reason = fmt.Sprintf("refund %s of %s succeeded", refund.ID, refund.Amount)
+
+		var total, refunded model.Money
+		const query = `SELECT o.total, COALESCE((SELECT SUM(r.amount) FROM refunds r WHERE r.order_id = o.id AND r.status = ?), 0) FROM orders o WHERE o.id = ?`
+		if err := s.deps.DB.QueryRowContext(ctx, query, model.RefundStatusSucceeded, refund.OrderID).Scan(&total, &refunded); err != nil {
+			return err
+		}
+		if refunded+refund.Amount >= total {
+			target = model.OrderStatusRefunded
+		}
+	}
+	if completedAt.IsZero() {
+		completedAt = time.Now()
+	}
+
+	change := statusChange{Source: source, Reason: reason}
+	err = updateOrderStatus(ctx, s.deps.DB, refund.OrderID, target, change,
+		requireFromStatus(refund.OrderID, target, model.OrderStatusRefunding),
+		func(tx *sql.Tx, from model.OrderStatus) error {
+			const stmt = `UPDATE refunds SET status = ?, provider_refund_id = COALESCE(?, provider_refund_id), completed_at = ?, updated_at = NOW() WHERE id = ? AND status = ?`
+			res, err := tx.ExecContext(ctx, stmt, status, nullableString(providerRefundID), completedAt, refund.ID, model.RefundStatusProcessing)
+			if err != nil {
+				return err
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				return errRefundSettled
+			}
+			if !success {
+				return nil
+			}
+
+			if refund.Restock {
+				items := make([]model.OrderItem, 0, len(refund.Items))
+				for _, item := range refund.Items {
+					items = append(items, model.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+				}
+				if err := restockItemsTx(ctx, tx, items); err != nil {
+					return err
+				}
+			}
+			return reverseRefundPointsTx(ctx, tx, refund, target == model.OrderStatusRefunded)
+		},
+	)
+	if errors.Is(err, errRefundSettled) {
+		return nil
+	}
+	if code, ok := model.ErrorCodeOf(err); ok && code == model.ErrCodeInvalidTransition {
+		latest, loadErr := s.loadRefund(ctx, refundID)
+		if loadErr != nil {
+			return loadErr
+		}
+		if latest.Status != model.RefundStatusProcessing {
+			return nil
+		}
+	}
+	return err
+}
+
+// ListRefunds 按发起时间倒序返回订单的全部退款及退回商品。
+func (s *refundService) ListRefunds(ctx context.Context, orderID string) ([]model.Refund, error) {
+	if s.deps.DB == nil {
+		return nil, errRefundDBUnavailable
+	}
+	if orderID == "" {
+		return nil, model.NewError(model.ErrCodeInvalidParameter, "order id is required")
+	}
+
+	var exists int
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT 1 FROM orders WHERE id = ?`, orderID).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeOrderNotFound, "order %s not found", orderID)
+		}
+		return nil, err
+	}
+
+	const query = `SELECT ` + refundColumns + ` FROM refunds WHERE order_id = ? ORDER BY created_at DESC, id DESC`
+	return s.queryRefunds(ctx, query, orderID)
+}
+
+// queryRefunds 执行退款查询并批量补齐退回商品。
+func (s *refundService) queryRefunds(ctx context.Context, query string, args ...any) ([]model.Refund, error) {
+	rows, err := s.deps.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []model.Refund{}
+	for rows.Next() {
+		refund, err := scanRefundRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, *refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.attachRefundItems(ctx, refunds); err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+const refundColumns = `id, order_id, provider, provider_refund_id, amount, status, reason, previous_status, restock, completed_at, created_at, updated_at`
+
+func scanRefundRow(scanner interface {
+	Scan(dest ...any) error
+}) (*model.Refund, error) {
+	var (
+		refund           model.Refund
+		providerRefundID sql.NullString
+		reason           sql.NullString
+		completedAt      sql.NullTime
+	)
+	if err := scanner.Scan(&refund.ID, &refund.OrderID, &refund.Provider, &providerRefundID, &refund.Amount, &refund.Status, &reason, &refund.PreviousStatus, &refund.Restock, &completedAt, &refund.CreatedAt, &refund.UpdatedAt); err != nil {
+		return nil, err
+	}
+	refund.ProviderRefundID = providerRefundID.String
+	refund.Reason = reason.String
+	if completedAt.Valid {
+		refund.CompletedAt = &completedAt.Time
+	}
+	refund.Items = []model.RefundItem{}
+	return &refund, nil
+}
+
+func (s *refundService) loadRefund(ctx context.Context, refundID string) (*model.Refund, error) {
+	const query = `SELECT ` + refundColumns + ` FROM refunds WHERE id = ?`
+	refund, err := scanRefundRow(s.deps.DB.QueryRowContext(ctx, query, refundID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeRefundNotFound, "refund %s not found", refundID)
+		}
+		return nil, err
+	}
+
+	refunds := []model.Refund{*refund}
+	if err := s.attachRefundItems(ctx, refunds); err != nil {
+		return nil, err
+	}
+	return &refunds[0], nil
+}
+
+// attachRefundItems 批量加载退款的退回商品。
+func (s *refundService) attachRefundItems(ctx context.Context, refunds []model.Refund) error {
+	if len(refunds) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(refunds))
+	index := make(map[string]int, len(refunds))
+	for i, refund := range refunds {
+		args = append(args, refund.ID)
+		index[refund.ID] = i
+	}
+
+	query := `SELECT refund_id, product_id, quantity, amount FROM refund_items WHERE refund_id IN (` + placeholders(len(args)) + `) ORDER BY product_id`
+	rows, err := s.deps.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			refundID string
+			item     model.RefundItem
+		)
+		if err := rows.Scan(&refundID, &item.ProductID, &item.Quantity, &item.Amount); err != nil {
+			return err
+		}
+		i := index[refundID]
+		refunds[i].Items = append(refunds[i].Items, item)
+	}
+	return rows.Err()
+}
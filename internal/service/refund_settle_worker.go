@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	defaultRefundQueryInterval  = 5 * time.Minute
+	defaultRefundQueryDelay     = 10 * time.Minute
+	defaultRefundQueryBatchSize = 50
+)
+
+// RefundSettleWorker 周期性向支付渠道查询长时间处于处理中的退款，补偿丢失的退款通知与结果未知的退款申请。
+type RefundSettleWorker struct {
+	logger        *log.Logger
+	refundService RefundService
+	interval      time.Duration
+	delay         time.Duration
+	batchSize     int
+}
+
+// NewRefundSettleWorker 根据 order 配置构建退款查询任务，未配置的项使用默认值。
+func NewRefundSettleWorker(deps Dependencies, refundService RefundService) (*RefundSettleWorker, error) {
+	worker := &RefundSettleWorker{
+		logger:        deps.Logger,
+		refundService: refundService,
+		interval:      defaultRefundQueryInterval,
+		delay:         defaultRefundQueryDelay,
+		batchSize:     defaultRefundQueryBatchSize,
+	}
+	if deps.Config == nil {
+		return worker, nil
+	}
+
+	cfg := deps.Config.Order
+	if cfg.RefundQueryInterval != "" {
+		dur, err := time.ParseDuration(cfg.RefundQueryInterval)
+		if err != nil {
+			return nil, fmt.Errorf("parse order refund_query_interval: %w", err)
+		}
+		worker.interval = dur
+	}
+	if cfg.RefundQueryDelay != "" {
+		dur, err := time.ParseDuration(cfg.RefundQueryDelay)
+		if err != nil {
+			return nil, fmt.Errorf("parse order refund_query_delay: %w", err)
+		}
+		worker.delay = dur
+	}
+	if cfg.RefundQueryBatchSize > 0 {
+		worker.batchSize = cfg.RefundQueryBatchSize
+	}
+	if worker.interval <= 0 || worker.delay < 0 {
+		return nil, errors.New("order refund_query_interval must be positive and refund_query_delay must not be negative")
+	}
+
+	return worker, nil
+}
+
+// Run 阻塞执行退款查询循环，直到 ctx 被取消。仍无结果的退款会在之后的轮次中按上次查询时间依次重试。
+func (w *RefundSettleWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.settle(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *RefundSettleWorker) settle(ctx context.Context) {
+	cutoff := time.Now().Add(-w.delay)
+	settled, err := w.refundService.SettleProcessingRefunds(ctx, cutoff, w.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Printf("settle processing refunds failed: %v", err)
+		}
+		return
+	}
+	if settled > 0 {
+		w.logger.Printf("settled %d processing refunds", settled)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/pkg/region"
+)
+
+// RegionService 提供行政区划查询，供客户端级联选择省市区。
+type RegionService interface {
+	// ListRegions 返回 parentCode 的直接下级区划，parentCode 为空时返回全部省级行政区。
+	ListRegions(ctx context.Context, parentCode string) ([]model.Region, error)
+	// Tree 返回完整的行政区划树。
+	Tree(ctx context.Context) ([]model.Region, error)
+}
+
+var errRegionsUnavailable = errors.New("region dataset is not configured")
+
+type regionService struct {
+	deps Dependencies
+}
+
+// NewRegionService 创建 RegionService 实例。
+func NewRegionService(deps Dependencies) RegionService {
+	return &regionService{deps: deps}
+}
+
+func (s *regionService) ListRegions(ctx context.Context, parentCode string) ([]model.Region, error) {
+	if s.deps.Regions == nil {
+		return nil, errRegionsUnavailable
+	}
+	children, ok := s.deps.Regions.Children(parentCode)
+	if !ok {
+		return nil, model.NewError(model.ErrCodeRegionNotFound, "region %s not found", parentCode)
+	}
+	return toModelRegions(children, false), nil
+}
+
+func (s *regionService) Tree(ctx context.Context) ([]model.Region, error) {
+	if s.deps.Regions == nil {
+		return nil, errRegionsUnavailable
+	}
+	return toModelRegions(s.deps.Regions.Provinces(), true), nil
+}
+
+func toModelRegions(nodes []*region.Region, recursive bool) []model.Region {
+	regions := make([]model.Region, 0, len(nodes))
+	for _, node := range nodes {
+		r := model.Region{Code: node.Code, Name: node.Name, HasChildren: node.HasChildren()}
+		if recursive && node.HasChildren() {
+			r.Children = toModelRegions(node.Children, true)
+		}
+		regions = append(regions, r)
+	}
+	return regions
+}
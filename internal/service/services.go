@@ -4,42 +4,76 @@ import (
 	"database/sql"
 	"log"
 
+	"convenienceStore/pkg/auth"
 	"convenienceStore/pkg/config"
 	"convenienceStore/pkg/payment"
+	"convenienceStore/pkg/region"
+	"convenienceStore/pkg/sms"
+	"convenienceStore/pkg/wechat"
 )
 
 // Dependencies 汇集服务层所需的横切依赖。
 type Dependencies struct {
-	Config  *config.AppConfig
-	Logger  *log.Logger
-	DB      *sql.DB
-	Payment payment.WeChatClient
+	Config *config.AppConfig
+	Logger *log.Logger
+	DB     *sql.DB
+	// Payments 按名称登记可用的支付方式。
+	Payments *payment.Registry
+	// WeChat 调用小程序服务端接口，如登录凭证校验。
+	WeChat wechat.Client
+	// SMS 发送短信验证码。
+	SMS sms.Sender
+	// Tokens 签发与校验会话令牌。
+	Tokens *auth.TokenManager
+	// Regions 为行政区划数据，用于校验收货地址与区划选择器。
+	Regions *region.Dataset
 }
 
 // Services 对外暴露各领域的服务单例。
 type Services struct {
-	User         UserService
-	Product      ProductService
-	AdminProduct AdminProductService
-	Upload       UploadService
-	Cart         CartService
-	Order        OrderService
-	Payment      PaymentService
-	Delivery     DeliveryService
+	User           UserService
+	Account        AccountService
+	Auth           AuthService
+	Phone          PhoneService
+	Loyalty        LoyaltyService
+	Region         RegionService
+	Staff          StaffService
+	Product        ProductService
+	AdminProduct   AdminProductService
+	Upload         UploadService
+	Cart           CartService
+	Order          OrderService
+	Payment        PaymentService
+	Refund         RefundService
+	Delivery       DeliveryService
+	Reconciliation ReconciliationService
 }
 
 // NewServices 负责装配整个服务层依赖关系。
 func NewServices(deps Dependencies) Services {
+	userService := NewUserService(deps)
+	cartService := NewCartService(deps)
 	orderService := NewOrderService(deps)
+	paymentService := NewPaymentService(deps, orderService)
+	refundService := NewRefundService(deps)
+	loyaltyService := NewLoyaltyService(deps)
 
 	return Services{
-		User:         NewUserService(deps),
-		Product:      NewProductService(deps),
-		AdminProduct: NewAdminProductService(deps),
-		Upload:       NewUploadService(deps),
-		Cart:         NewCartService(deps),
-		Order:        orderService,
-		Payment:      NewPaymentService(deps, orderService),
-		Delivery:     NewDeliveryService(deps, orderService),
+		User:           userService,
+		Account:        NewAccountService(deps, userService, cartService, orderService, paymentService, refundService, loyaltyService),
+		Auth:           NewAuthService(deps),
+		Phone:          NewPhoneService(deps),
+		Loyalty:        loyaltyService,
+		Region:         NewRegionService(deps),
+		Staff:          NewStaffService(deps),
+		Product:        NewProductService(deps),
+		AdminProduct:   NewAdminProductService(deps),
+		Upload:         NewUploadService(deps),
+		Cart:           cartService,
+		Order:          orderService,
+		Payment:        paymentService,
+		Refund:         refundService,
+		Delivery:       NewDeliveryService(deps, orderService),
+		Reconciliation: NewReconciliationService(deps),
 	}
 }
@@ -0,0 +1,562 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"convenienceStore/internal/model"
+	"convenienceStore/pkg/auth"
+	"convenienceStore/pkg/uid"
+)
+
+// StaffService 管理门店员工账号、登录凭证与 API Key。
+type StaffService interface {
+	// Login 校验用户名与口令并签发员工令牌，同一用户名或来源 IP 失败过多时返回 429。
+	Login(ctx context.Context, username, password, clientIP string) (*model.Staff, auth.TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (auth.TokenPair, error)
+	// AuthenticateToken 校验员工访问令牌并返回在职员工。
+	AuthenticateToken(ctx context.Context, accessToken string) (*model.Staff, error)
+	// AuthenticateAPIKey 校验 API Key 并返回在职员工。
+	AuthenticateAPIKey(ctx context.Context, key string) (*model.Staff, error)
+	// EnsureManager 在尚无店长账号时创建初始店长，已存在店长时不做任何修改。
+	EnsureManager(ctx context.Context, username, password string) error
+	ListStaff(ctx context.Context) ([]model.Staff, error)
+	GetStaff(ctx context.Context, staffID string) (*model.Staff, error)
+	CreateStaff(ctx context.Context, staff *model.Staff, password string) error
+	// UpdateStaff 修改员工姓名、角色与状态，不允许停用或降级最后一名在职店长。
+	UpdateStaff(ctx context.Context, staff *model.Staff) error
+	ResetPassword(ctx context.Context, staffID, password string) error
+	ListAPIKeys(ctx context.Context, staffID string) ([]model.StaffAPIKey, error)
+	// CreateAPIKey 为员工生成 API Key，返回值中的明文 Key 仅此一次可见。
+	CreateAPIKey(ctx context.Context, staffID, name string) (*model.StaffAPIKey, error)
+	RevokeAPIKey(ctx context.Context, staffID, keyID string) error
+}
+
+var errStaffDBUnavailable = errors.New("staff service database is not configured")
+
+// minBootstrapPasswordLength 是初始店长口令的最小长度，该口令写在配置文件中，要求高于普通口令。
+const minBootstrapPasswordLength = 12
+
+// placeholderPasswords 是示例配置与常见的弱口令，不允许用作初始店长口令。
+var placeholderPasswords = []string{"change-me-now", "change-me", "changeme", "password", "admin", "123456"}
+
+// dummyPasswordHash 是用户名不存在时参与校验的固定哈希，使其与口令错误的耗时一致，无法据此探测用户名。
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("convenience-store-dummy-password")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+const (
+	defaultLoginFailureWindow          = 15 * time.Minute
+	defaultMaxLoginFailuresPerUsername = 5
+	defaultMaxLoginFailuresPerIP       = 20
+
+	// maxStaffUsernameLength 与 staff.username 列宽一致。
+	maxStaffUsernameLength = 64
+	// loginAttemptCleanupBatch 是每次登录时顺带清理的过期尝试记录上限。
+	loginAttemptCleanupBatch = 100
+)
+
+// loginPolicy 是员工登录失败限流参数。
+type loginPolicy struct {
+	window         time.Duration
+	maxPerUsername int
+	maxPerIP       int
+}
+
+// staffLoginPolicy 读取 staff 登录限流配置，未配置的项使用默认值。
+func staffLoginPolicy(deps Dependencies) (loginPolicy, error) {
+	policy := loginPolicy{
+		window:         defaultLoginFailureWindow,
+		maxPerUsername: defaultMaxLoginFailuresPerUsername,
+		maxPerIP:       defaultMaxLoginFailuresPerIP,
+	}
+	if deps.Config == nil {
+		return policy, nil
+	}
+
+	cfg := deps.Config.Staff
+	if cfg.LoginFailureWindow != "" {
+		dur, err := time.ParseDuration(cfg.LoginFailureWindow)
+		if err != nil {
+			return loginPolicy{}, fmt.Errorf("parse staff login_failure_window: %w", err)
+		}
+		policy.window = dur
+	}
+	if cfg.MaxLoginFailuresPerUsername > 0 {
+		policy.maxPerUsername = cfg.MaxLoginFailuresPerUsername
+	}
+	if cfg.MaxLoginFailuresPerIP > 0 {
+		policy.maxPerIP = cfg.MaxLoginFailuresPerIP
+	}
+	return policy, nil
+}
+
+type staffService struct {
+	deps Dependencies
+}
+
+// NewStaffService 创建 StaffService 实例。
+func NewStaffService(deps Dependencies) StaffService {
+	return &staffService{deps: deps}
+}
+
+const staffColumns = `id, username, name, role, status, created_at, updated_at`
+
+func scanStaffRow(scanner interface {
+	Scan(dest ...any) error
+}) (*model.Staff, error) {
+	var staff model.Staff
+	if err := scanner.Scan(&staff.ID, &staff.Username, &staff.Name, &staff.Role, &staff.Status, &staff.CreatedAt, &staff.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &staff, nil
+}
+
+func (s *staffService) Login(ctx context.Context, username, password, clientIP string) (*model.Staff, auth.TokenPair, error) {
+	if s.deps.DB == nil {
+		return nil, auth.TokenPair{}, errStaffDBUnavailable
+	}
+	if s.deps.Tokens == nil {
+		return nil, auth.TokenPair{}, errAuthTokensUnavailable
+	}
+	policy, err := staffLoginPolicy(s.deps)
+	if err != nil {
+		return nil, auth.TokenPair{}, err
+	}
+
+	username = strings.TrimSpace(username)
+	// 失败记录按列宽截断用户名，查询账号仍使用完整用户名。
+	attemptKey := username
+	if len(attemptKey) > maxStaffUsernameLength {
+		attemptKey = attemptKey[:maxStaffUsernameLength]
+	}
+	if err := s.recordLoginAttempt(ctx, attemptKey, clientIP, policy); err != nil {
+		return nil, auth.TokenPair{}, err
+	}
+
+	const query = `SELECT ` + staffColumns + `, password_hash FROM staff WHERE username = ?`
+	var (
+		staff        model.Staff
+		passwordHash string
+	)
+	err = s.deps.DB.QueryRowContext(ctx, query, username).Scan(
+		&staff.ID, &staff.Username, &staff.Name, &staff.Role, &staff.Status, &staff.CreatedAt, &staff.UpdatedAt, &passwordHash,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.TokenPair{}, err
+	}
+	found := err == nil
+	if !found {
+		passwordHash = dummyPasswordHash()
+	}
+	// 账号不存在、口令错误与账号停用返回相同的错误且耗时相当，避免被用来探测用户名。
+	if !auth.VerifyPassword(passwordHash, password) || !found || staff.Status != model.StaffStatusActive {
+		return nil, auth.TokenPair{}, model.NewError(model.ErrCodeUnauthorized, "username or password is incorrect")
+	}
+
+	// 登录成功后清除该用户名的失败记录，此前的失败不再计入限流。
+	if _, err := s.deps.DB.ExecContext(ctx, `DELETE FROM staff_login_attempts WHERE username = ?`, attemptKey); err != nil {
+		return nil, auth.TokenPair{}, err
+	}
+
+	tokens, err := s.deps.Tokens.Issue(auth.AudienceStaff, staff.ID)
+	if err != nil {
+		return nil, auth.TokenPair{}, err
+	}
+	return &staff, tokens, nil
+}
+
+// recordLoginAttempt 先登记本次尝试再统计窗口内的尝试次数，并发请求都会计入彼此的统计，
+// 不会同时越过限额；校验成功的尝试随后被清除，因此留下的记录即为失败次数。
+func (s *staffService) recordLoginAttempt(ctx context.Context, username, clientIP string, policy loginPolicy) error {
+	now := time.Now()
+	const insert = `INSERT INTO staff_login_attempts (username, client_ip, created_at) VALUES (?, ?, ?)`
+	if _, err := s.deps.DB.ExecContext(ctx, insert, username, clientIP, now); err != nil {
+		return err
+	}
+	// 顺带清理一批窗口外的记录，清理失败不影响本次登录。
+	s.deps.DB.ExecContext(ctx, `DELETE FROM staff_login_attempts WHERE created_at < ? LIMIT ?`, now.Add(-policy.window), loginAttemptCleanupBatch)
+
+	since := now.Add(-policy.window)
+	var usernameCount, ipCount int
+	const query = `SELECT
+		(SELECT COUNT(*) FROM staff_login_attempts WHERE username = ? AND created_at > ?),
+		(SELECT COUNT(*) FROM staff_login_attempts WHERE client_ip = ? AND created_at > ?)`
+	if err := s.deps.DB.QueryRowContext(ctx, query, username, since, clientIP, since).Scan(&usernameCount, &ipCount); err != nil {
+		return err
+	}
+	// 计数包含本次尝试，因此超过上限才拒绝。
+	if usernameCount > policy.maxPerUsername || ipCount > policy.maxPerIP {
+		return model.NewError(model.ErrCodeTooManyRequests, "too many failed login attempts, retry later")
+	}
+	return nil
+}
+
+func (s *staffService) RefreshTokens(ctx context.Context, refreshToken string) (auth.TokenPair, error) {
+	if s.deps.Tokens == nil {
+		return auth.TokenPair{}, errAuthTokensUnavailable
+	}
+
+	claims, err := s.deps.Tokens.Parse(refreshToken, auth.AudienceStaff, auth.TokenTypeRefresh)
+	if err != nil {
+		return auth.TokenPair{}, unauthorized(err)
+	}
+	if _, err := s.activeStaff(ctx, claims.Subject); err != nil {
+		return auth.TokenPair{}, err
+	}
+
+	return s.deps.Tokens.Issue(auth.AudienceStaff, claims.Subject)
+}
+
+func (s *staffService) AuthenticateToken(ctx context.Context, accessToken string) (*model.Staff, error) {
+	if s.deps.Tokens == nil {
+		return nil, errAuthTokensUnavailable
+	}
+
+	claims, err := s.deps.Tokens.Parse(accessToken, auth.AudienceStaff, auth.TokenTypeAccess)
+	if err != nil {
+		return nil, unauthorized(err)
+	}
+	return s.activeStaff(ctx, claims.Subject)
+}
+
+func (s *staffService) AuthenticateAPIKey(ctx context.Context, key string) (*model.Staff, error) {
+	if s.deps.DB == nil {
+		return nil, errStaffDBUnavailable
+	}
+	if !strings.HasPrefix(key, auth.APIKeyPrefix) {
+		return nil, model.NewError(model.ErrCodeUnauthorized, "api key is invalid")
+	}
+
+	const query = `SELECT k.id, s.id, s.username, s.name, s.role, s.status, s.created_at, s.updated_at
+		FROM staff_api_keys k JOIN staff s ON s.id = k.staff_id
+		WHERE k.key_hash = ? AND k.revoked_at IS NULL`
+	var (
+		keyID string
+		staff model.Staff
+	)
+	err := s.deps.DB.QueryRowContext(ctx, query, auth.HashAPIKey(key)).Scan(
+		&keyID, &staff.ID, &staff.Username, &staff.Name, &staff.Role, &staff.Status, &staff.CreatedAt, &staff.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeUnauthorized, "api key is invalid")
+		}
+		return nil, err
+	}
+	if staff.Status != model.StaffStatusActive {
+		return nil, model.NewError(model.ErrCodeUnauthorized, "staff account is disabled")
+	}
+
+	// 最近使用时间精确到分钟即可，避免每个请求都写库。
+	const touch = `UPDATE staff_api_keys SET last_used_at = NOW() WHERE id = ? AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL 1 MINUTE)`
+	if _, err := s.deps.DB.ExecContext(ctx, touch, keyID); err != nil {
+		s.deps.Logger.Printf("update api key %s last_used_at failed: %v", keyID, err)
+	}
+
+	return &staff, nil
+}
+
+// activeStaff 返回在职员工，账号不存在或已停用时视为未认证。
+func (s *staffService) activeStaff(ctx context.Context, staffID string) (*model.Staff, error) {
+	staff, err := s.GetStaff(ctx, staffID)
+	if err != nil {
+		if code, ok := model.ErrorCodeOf(err); ok && code == model.ErrCodeStaffNotFound {
+			return nil, model.NewError(model.ErrCodeUnauthorized, "staff account no longer exists")
+		}
+		return nil, err
+	}
+	if staff.Status != model.StaffStatusActive {
+		return nil, model.NewError(model.ErrCodeUnauthorized, "staff account is disabled")
+	}
+	return staff, nil
+}
+
+func (s *staffService) EnsureManager(ctx context.Context, username, password string) error {
+	if s.deps.DB == nil {
+		return errStaffDBUnavailable
+	}
+	if err := checkBootstrapPassword(username, password); err != nil {
+		return err
+	}
+
+	var count int
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM staff WHERE role = ?`, model.StaffRoleManager).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	manager := &model.Staff{Username: username, Name: username, Role: model.StaffRoleManager}
+	if err := s.CreateStaff(ctx, manager, password); err != nil {
+		return fmt.Errorf("create initial manager: %w", err)
+	}
+	s.deps.Logger.Printf("created initial manager account %s", manager.Username)
+	return nil
+}
+
+// checkBootstrapPassword 拒绝示例口令、与用户名相同或过短的初始店长口令，
+// 即使已存在店长也会校验，避免弱口令长期留在配置文件中。
+func checkBootstrapPassword(username, password string) error {
+	if len(password) < minBootstrapPasswordLength {
+		return fmt.Errorf("bootstrap password must be at least %d characters", minBootstrapPasswordLength)
+	}
+	for _, placeholder := range placeholderPasswords {
+		if strings.EqualFold(password, placeholder) {
+			return errors.New("bootstrap password is a placeholder, set a strong random password")
+		}
+	}
+	if strings.EqualFold(password, username) {
+		return errors.New("bootstrap password must not equal the username")
+	}
+	return nil
+}
+
+func (s *staffService) ListStaff(ctx context.Context) ([]model.Staff, error) {
+	if s.deps.DB == nil {
+		return nil, errStaffDBUnavailable
+	}
+
+	const query = `SELECT ` + staffColumns + ` FROM staff ORDER BY created_at, id`
+	rows, err := s.deps.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	staffList := make([]model.Staff, 0)
+	for rows.Next() {
+		staff, err := scanStaffRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		staffList = append(staffList, *staff)
+	}
+	return staffList, rows.Err()
+}
+
+func (s *staffService) GetStaff(ctx context.Context, staffID string) (*model.Staff, error) {
+	if s.deps.DB == nil {
+		return nil, errStaffDBUnavailable
+	}
+
+	const query = `SELECT ` + staffColumns + ` FROM staff WHERE id = ?`
+	staff, err := scanStaffRow(s.deps.DB.QueryRowContext(ctx, query, staffID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NewError(model.ErrCodeStaffNotFound, "staff %s not found", staffID)
+		}
+		return nil, err
+	}
+	return staff, nil
+}
+
+func (s *staffService) CreateStaff(ctx context.Context, staff *model.Staff, password string) error {
+	if s.deps.DB == nil {
+		return errStaffDBUnavailable
+	}
+	if staff == nil {
+		return errors.New("staff is nil")
+	}
+	staff.Username = strings.TrimSpace(staff.Username)
+	if staff.Username == "" {
+		return model.NewError(model.ErrCodeInvalidParameter, "username is required")
+	}
+	if !staff.Role.Valid() {
+		return model.NewError(model.ErrCodeInvalidParameter, "invalid role %q", staff.Role)
+	}
+	if staff.Name == "" {
+		staff.Name = staff.Username
+	}
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return model.NewError(model.ErrCodeInvalidParameter, "%s", err.Error())
+	}
+
+	var exists int
+	err = s.deps.DB.QueryRowContext(ctx, `SELECT 1 FROM staff WHERE username = ?`, staff.Username).Scan(&exists)
+	if err == nil {
+		return model.NewError(model.ErrCodeStaffExists, "username %s is already taken", staff.Username)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	staff.ID = uid.New("stf_")
+	staff.Status = model.StaffStatusActive
+	const stmt = `INSERT INTO staff (id, username, password_hash, name, role, status) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := s.deps.DB.ExecContext(ctx, stmt, staff.ID, staff.Username, passwordHash, staff.Name, staff.Role, staff.Status); err != nil {
+		return err
+	}
+
+	created, err := s.GetStaff(ctx, staff.ID)
+	if err != nil {
+		return err
+	}
+	*staff = *created
+	return nil
+}
+
+func (s *staffService) UpdateStaff(ctx context.Context, staff *model.Staff) error {
+	if s.deps.DB == nil {
+		return errStaffDBUnavailable
+	}
+	if staff == nil {
+		return errors.New("staff is nil")
+	}
+	if !staff.Role.Valid() {
+		return model.NewError(model.ErrCodeInvalidParameter, "invalid role %q", staff.Role)
+	}
+	if staff.Status != model.StaffStatusActive && staff.Status != model.StaffStatusDisabled {
+		return model.NewError(model.ErrCodeInvalidParameter, "invalid status %q", staff.Status)
+	}
+
+	return runInTx(ctx, s.deps.DB, func(tx *sql.Tx) error {
+		var role model.StaffRole
+		var status model.StaffStatus
+		err := tx.QueryRowContext(ctx, `SELECT role, status FROM staff WHERE id = ? FOR UPDATE`, staff.ID).Scan(&role, &status)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.NewError(model.ErrCodeStaffNotFound, "staff %s not found", staff.ID)
+			}
+			return err
+		}
+
+		stillManager := staff.Role == model.StaffRoleManager && staff.Status == model.StaffStatusActive
+		if role == model.StaffRoleManager && status == model.StaffStatusActive && !stillManager {
+			var others int
+			const query = `SELECT COUNT(*) FROM staff WHERE role = ? AND status = ? AND id <> ? FOR UPDATE`
+			if err := tx.QueryRowContext(ctx, query, model.StaffRoleManager, model.StaffStatusActive, staff.ID).Scan(&others); err != nil {
+				return err
+			}
+			if others == 0 {
+				return model.NewError(model.ErrCodeInvalidParameter, "cannot disable or demote the last active manager")
+			}
+		}
+
+		const stmt = `UPDATE staff SET name = COALESCE(?, name), role = ?, status = ?, updated_at = NOW() WHERE id = ?`
+		_, err = tx.ExecContext(ctx, stmt, nullableString(staff.Name), staff.Role, staff.Status, staff.ID)
+		return err
+	})
+}
+
+func (s *staffService) ResetPassword(ctx context.Context, staffID, password string) error {
+	if s.deps.DB == nil {
+		return errStaffDBUnavailable
+	}
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return model.NewError(model.ErrCodeInvalidParameter, "%s", err.Error())
+	}
+
+	res, err := s.deps.DB.ExecContext(ctx, `UPDATE staff SET password_hash = ?, updated_at = NOW() WHERE id = ?`, passwordHash, staffID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.NewError(model.ErrCodeStaffNotFound, "staff %s not found", staffID)
+	}
+	return nil
+}
+
+func (s *staffService) ListAPIKeys(ctx context.Context, staffID string) ([]model.StaffAPIKey, error) {
+	if s.deps.DB == nil {
+		return nil, errStaffDBUnavailable
+	}
+	if _, err := s.GetStaff(ctx, staffID); err != nil {
+		return nil, err
+	}
+
+	const query = `SELECT id, staff_id, name, key_prefix, last_used_at, revoked_at, created_at FROM staff_api_keys WHERE staff_id = ? ORDER BY created_at DESC, id`
+	rows, err := s.deps.DB.QueryContext(ctx, query, staffID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]model.StaffAPIKey, 0)
+	for rows.Next() {
+		var (
+			key        model.StaffAPIKey
+			lastUsedAt sql.NullTime
+			revokedAt  sql.NullTime
+		)
+		if err := rows.Scan(&key.ID, &key.StaffID, &key.Name, &key.Prefix, &lastUsedAt, &revokedAt, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		if lastUsedAt.Valid {
+			key.LastUsedAt = &lastUsedAt.Time
+		}
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *staffService) CreateAPIKey(ctx context.Context, staffID, name string) (*model.StaffAPIKey, error) {
+	if s.deps.DB == nil {
+		return nil, errStaffDBUnavailable
+	}
+	if name == "" {
+		return nil, model.NewError(model.ErrCodeInvalidParameter, "api key name is required")
+	}
+	if _, err := s.GetStaff(ctx, staffID); err != nil {
+		return nil, err
+	}
+
+	plain, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	key := &model.StaffAPIKey{
+		ID:      uid.New("key_"),
+		StaffID: staffID,
+		Name:    name,
+		Prefix:  prefix,
+		Key:     plain,
+	}
+	const stmt = `INSERT INTO staff_api_keys (id, staff_id, name, key_prefix, key_hash) VALUES (?, ?, ?, ?, ?)`
+	if _, err := s.deps.DB.ExecContext(ctx, stmt, key.ID, key.StaffID, key.Name, key.Prefix, hash); err != nil {
+		return nil, err
+	}
+	if err := s.deps.DB.QueryRowContext(ctx, `SELECT created_at FROM staff_api_keys WHERE id = ?`, key.ID).Scan(&key.CreatedAt); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *staffService) RevokeAPIKey(ctx context.Context, staffID, keyID string) error {
+	if s.deps.DB == nil {
+		return errStaffDBUnavailable
+	}
+
+	const stmt = `UPDATE staff_api_keys SET revoked_at = NOW() WHERE id = ? AND staff_id = ? AND revoked_at IS NULL`
+	res, err := s.deps.DB.ExecContext(ctx, stmt, keyID, staffID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.NewError(model.ErrCodeAPIKeyNotFound, "api key %s not found", keyID)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"convenienceStore/internal/model"
 	"convenienceStore/pkg/uid"
+	"convenienceStore/pkg/wechat"
 )
 
 // UserService 定义与用户账号及地址相关的业务行为。
@@ -17,7 +18,7 @@ type UserService interface {
 	ListAddresses(ctx context.Context, userID string) ([]model.Address, error)
 	CreateAddress(ctx context.Context, address *model.Address) error
 	UpdateAddress(ctx context.Context, address *model.Address) error
-	DeleteAddress(ctx context.Context, addressID string) error
+	DeleteAddress(ctx context.Context, userID, addressID string) error
 }
 
 var errUserDBUnavailable = errors.New("user service database is not configured")
@@ -31,21 +32,35 @@ func NewUserService(deps Dependencies) UserService {
 	return &userService{deps: deps}
 }
 
+// WeChatLogin 通过 code2session 校验小程序登录凭证，按 openid 查找或创建用户，并保存最新的 session_key 与 unionid。
 func (s *userService) WeChatLogin(ctx context.Context, code string) (*model.User, error) {
 	if s.deps.DB == nil {
 		return nil, errUserDBUnavailable
 	}
+	if s.deps.WeChat == nil {
+		return nil, errors.New("wechat mini program client is not configured")
+	}
 	if code == "" {
-		return nil, errors.New("wechat auth code is required")
+		return nil, model.NewError(model.ErrCodeInvalidParameter, "wechat auth code is required")
 	}
 
-	openID := code
+	session, err := s.deps.WeChat.Code2Session(ctx, code)
+	if err != nil {
+		if wechat.InvalidCode(err) {
+			return nil, model.NewError(model.ErrCodeUnauthorized, "wechat auth code is invalid or expired")
+		}
+		return nil, fmt.Errorf("wechat code2session: %w", err)
+	}
 
-	const query = `SELECT id, wechat_open_id, nickname, avatar_url, phone, default_address_id FROM users WHERE wechat_open_id = ?`
-	var user model.User
-	err := s.deps.DB.QueryRowContext(ctx, query, openID).Scan(
+	const query = `SELECT id, wechat_open_id, wechat_union_id, nickname, avatar_url, phone, default_address_id FROM users WHERE wechat_open_id = ?`
+	var (
+		user    model.User
+		unionID sql.NullString
+	)
+	err = s.deps.DB.QueryRowContext(ctx, query, session.OpenID).Scan(
 		&user.ID,
 		&user.WeChatOpenID,
+		&unionID,
 		&user.Nickname,
 		&user.AvatarURL,
 		&user.Phone,
@@ -53,16 +68,26 @@ func (s *userService) WeChatLogin(ctx context.Context, code string) (*model.User
 	)
 	switch {
 	case err == nil:
+		// unionid 仅在小程序绑定开放平台后返回，未返回时保留已有值。
+		const update = `UPDATE users SET session_key = ?, wechat_union_id = COALESCE(?, wechat_union_id), updated_at = NOW() WHERE id = ?`
+		if _, err := s.deps.DB.ExecContext(ctx, update, session.SessionKey, nullableString(session.UnionID), user.ID); err != nil {
+			return nil, err
+		}
+		user.WeChatUnionID = unionID.String
+		if session.UnionID != "" {
+			user.WeChatUnionID = session.UnionID
+		}
 		return &user, nil
 	case errors.Is(err, sql.ErrNoRows):
 		user = model.User{
-			ID:           uid.New("usr_"),
-			WeChatOpenID: openID,
-			Nickname:     fmt.Sprintf("微信用户%s", openID),
-			AvatarURL:    "https://example.com/avatar.png",
+			ID:            uid.New("usr_"),
+			WeChatOpenID:  session.OpenID,
+			WeChatUnionID: session.UnionID,
+			Nickname:      defaultNickname(session.OpenID),
+			AvatarURL:     "https://example.com/avatar.png",
 		}
-		const insert = `INSERT INTO users (id, wechat_open_id, nickname, avatar_url) VALUES (?, ?, ?, ?)`
-		if _, err := s.deps.DB.ExecContext(ctx, insert, user.ID, user.WeChatOpenID, user.Nickname, user.AvatarURL); err != nil {
+		const insert = `INSERT INTO users (id, wechat_open_id, wechat_union_id, session_key, nickname, avatar_url) VALUES (?, ?, ?, ?, ?, ?)`
+		if _, err := s.deps.DB.ExecContext(ctx, insert, user.ID, user.WeChatOpenID, nullableString(user.WeChatUnionID), session.SessionKey, user.Nickname, user.AvatarURL); err != nil {
 			return nil, err
 		}
 		return &user, nil
@@ -71,6 +96,15 @@ func (s *userService) WeChatLogin(ctx context.Context, code string) (*model.User
 	}
 }
 
+// defaultNickname 以 openid 末尾几位生成新用户的默认昵称。
+func defaultNickname(openID string) string {
+	const suffixLen = 6
+	if len(openID) > suffixLen {
+		openID = openID[len(openID)-suffixLen:]
+	}
+	return fmt.Sprintf("微信用户%s", openID)
+}
+
 func (s *userService) BindUser(ctx context.Context, user *model.User) error {
 	if s.deps.DB == nil {
 		return errUserDBUnavailable
@@ -82,8 +116,9 @@ func (s *userService) BindUser(ctx context.Context, user *model.User) error {
 		return errors.New("user id is required")
 	}
 
-	const stmt = `UPDATE users SET nickname = ?, avatar_url = ?, phone = ?, default_address_id = ?, updated_at = NOW() WHERE id = ?`
-	res, err := s.deps.DB.ExecContext(ctx, stmt, user.Nickname, user.AvatarURL, user.Phone, user.DefaultAddrID, user.ID)
+	// 手机号须经短信验证码或微信授权绑定，此处不予修改。
+	const stmt = `UPDATE users SET nickname = ?, avatar_url = ?, default_address_id = ?, updated_at = NOW() WHERE id = ?`
+	res, err := s.deps.DB.ExecContext(ctx, stmt, user.Nickname, user.AvatarURL, user.DefaultAddrID, user.ID)
 	if err != nil {
 		return err
 	}
@@ -107,7 +142,7 @@ func (s *userService) ListAddresses(ctx context.Context, userID string) ([]model
 		return nil, errors.New("user id is required")
 	}
 
-	const query = `SELECT id, user_id, recipient, phone, province, city, district, detail, postal_code, is_default FROM addresses WHERE user_id = ? ORDER BY updated_at DESC`
+	const query = `SELECT id, user_id, recipient, phone, province, province_code, city, city_code, district, district_code, detail, postal_code, is_default FROM addresses WHERE user_id = ? ORDER BY updated_at DESC`
 	rows, err := s.deps.DB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
@@ -117,7 +152,7 @@ func (s *userService) ListAddresses(ctx context.Context, userID string) ([]model
 	var addresses []model.Address
 	for rows.Next() {
 		var addr model.Address
-		if err := rows.Scan(&addr.ID, &addr.UserID, &addr.Recipient, &addr.Phone, &addr.Province, &addr.City, &addr.District, &addr.Detail, &addr.PostalCode, &addr.IsDefault); err != nil {
+		if err := rows.Scan(&addr.ID, &addr.UserID, &addr.Recipient, &addr.Phone, &addr.Province, &addr.ProvinceCode, &addr.City, &addr.CityCode, &addr.District, &addr.DistrictCode, &addr.Detail, &addr.PostalCode, &addr.IsDefault); err != nil {
 			return nil, err
 		}
 		addresses = append(addresses, addr)
@@ -140,6 +175,9 @@ func (s *userService) CreateAddress(ctx context.Context, address *model.Address)
 	if address.UserID == "" {
 		return errors.New("user id is required")
 	}
+	if err := s.normalizeRegion(address); err != nil {
+		return err
+	}
 	if address.ID == "" {
 		address.ID = uid.New("addr_")
 	}
@@ -154,8 +192,8 @@ func (s *userService) CreateAddress(ctx context.Context, address *model.Address)
 		}
 	}()
 
-	const insert = `INSERT INTO addresses (id, user_id, recipient, phone, province, city, district, detail, postal_code, is_default) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	if _, err = tx.ExecContext(ctx, insert, address.ID, address.UserID, address.Recipient, address.Phone, address.Province, address.City, address.District, address.Detail, address.PostalCode, address.IsDefault); err != nil {
+	const insert = `INSERT INTO addresses (id, user_id, recipient, phone, province, province_code, city, city_code, district, district_code, detail, postal_code, is_default) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err = tx.ExecContext(ctx, insert, address.ID, address.UserID, address.Recipient, address.Phone, address.Province, address.ProvinceCode, address.City, address.CityCode, address.District, address.DistrictCode, address.Detail, address.PostalCode, address.IsDefault); err != nil {
 		return err
 	}
 
@@ -172,6 +210,7 @@ func (s *userService) CreateAddress(ctx context.Context, address *model.Address)
 	return err
 }
 
+// UpdateAddress 更新 address.UserID 名下的地址，地址不属于该用户时视为不存在。
 func (s *userService) UpdateAddress(ctx context.Context, address *model.Address) (err error) {
 	if s.deps.DB == nil {
 		return errUserDBUnavailable
@@ -182,6 +221,12 @@ func (s *userService) UpdateAddress(ctx context.Context, address *model.Address)
 	if address.ID == "" {
 		return errors.New("address id is required")
 	}
+	if address.UserID == "" {
+		return errors.New("user id is required")
+	}
+	if err := s.normalizeRegion(address); err != nil {
+		return err
+	}
 
 	tx, err := s.deps.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -193,20 +238,10 @@ func (s *userService) UpdateAddress(ctx context.Context, address *model.Address)
 		}
 	}()
 
-	var userID string
-	if address.UserID != "" {
-		userID = address.UserID
-	} else {
-		if err = tx.QueryRowContext(ctx, `SELECT user_id FROM addresses WHERE id = ?`, address.ID).Scan(&userID); err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return fmt.Errorf("address %s not found", address.ID)
-			}
-			return err
-		}
-	}
+	userID := address.UserID
 
-	const update = `UPDATE addresses SET recipient = ?, phone = ?, province = ?, city = ?, district = ?, detail = ?, postal_code = ?, is_default = ?, updated_at = NOW() WHERE id = ?`
-	res, execErr := tx.ExecContext(ctx, update, address.Recipient, address.Phone, address.Province, address.City, address.District, address.Detail, address.PostalCode, address.IsDefault, address.ID)
+	const update = `UPDATE addresses SET recipient = ?, phone = ?, province = ?, province_code = ?, city = ?, city_code = ?, district = ?, district_code = ?, detail = ?, postal_code = ?, is_default = ?, updated_at = NOW() WHERE id = ? AND user_id = ?`
+	res, execErr := tx.ExecContext(ctx, update, address.Recipient, address.Phone, address.Province, address.ProvinceCode, address.City, address.CityCode, address.District, address.DistrictCode, address.Detail, address.PostalCode, address.IsDefault, address.ID, userID)
 	if execErr != nil {
 		err = execErr
 		return err
@@ -218,7 +253,7 @@ func (s *userService) UpdateAddress(ctx context.Context, address *model.Address)
 		return err
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("address %s not found", address.ID)
+		return model.NewError(model.ErrCodeAddressNotFound, "address %s not found", address.ID)
 	}
 
 	if address.IsDefault {
@@ -238,12 +273,28 @@ func (s *userService) UpdateAddress(ctx context.Context, address *model.Address)
 	return err
 }
 
-func (s *userService) DeleteAddress(ctx context.Context, addressID string) (err error) {
+// normalizeRegion 按行政区划数据校验地址的省市区，并改写为标准名称与区划代码。
+func (s *userService) normalizeRegion(address *model.Address) error {
+	if s.deps.Regions == nil {
+		return errors.New("region dataset is not configured")
+	}
+	resolved, err := s.deps.Regions.Resolve(address.Province, address.City, address.District)
+	if err != nil {
+		return model.NewError(model.ErrCodeInvalidParameter, "invalid address region: %v", err)
+	}
+	address.Province, address.ProvinceCode = resolved.Province, resolved.ProvinceCode
+	address.City, address.CityCode = resolved.City, resolved.CityCode
+	address.District, address.DistrictCode = resolved.District, resolved.DistrictCode
+	return nil
+}
+
+// DeleteAddress 删除 userID 名下的地址，地址不属于该用户时视为不存在。
+func (s *userService) DeleteAddress(ctx context.Context, userID, addressID string) (err error) {
 	if s.deps.DB == nil {
 		return errUserDBUnavailable
 	}
-	if addressID == "" {
-		return errors.New("address id is required")
+	if userID == "" || addressID == "" {
+		return errors.New("user id and address id are required")
 	}
 
 	tx, err := s.deps.DB.BeginTx(ctx, nil)
@@ -256,15 +307,7 @@ func (s *userService) DeleteAddress(ctx context.Context, addressID string) (err
 		}
 	}()
 
-	var userID string
-	if err = tx.QueryRowContext(ctx, `SELECT user_id FROM addresses WHERE id = ?`, addressID).Scan(&userID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("address %s not found", addressID)
-		}
-		return err
-	}
-
-	res, execErr := tx.ExecContext(ctx, `DELETE FROM addresses WHERE id = ?`, addressID)
+	res, execErr := tx.ExecContext(ctx, `DELETE FROM addresses WHERE id = ? AND user_id = ?`, addressID, userID)
 	if execErr != nil {
 		err = execErr
 		return err
@@ -276,7 +319,7 @@ func (s *userService) DeleteAddress(ctx context.Context, addressID string) (err
 		return err
 	}
 	if affected == 0 {
-		return fmt.Errorf("address %s not found", addressID)
+		return model.NewError(model.ErrCodeAddressNotFound, "address %s not found", addressID)
 	}
 
 	if _, err = tx.ExecContext(ctx, `UPDATE users SET default_address_id = NULL WHERE id = ? AND default_address_id = ?`, userID, addressID); err != nil {
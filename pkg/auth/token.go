@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAccessTokenTTL  = 2 * time.Hour
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	// minSecretLength 是 HMAC 签名密钥的最小字节数。
+	minSecretLength = 32
+	// minSecretDistinctBytes 用于拒绝由少数字符重复组成的密钥。
+	minSecretDistinctBytes = 16
+)
+
+// placeholderSecretMarkers 出现在示例配置或文档中的占位密钥里，包含这些片段的密钥视为未替换。
+var placeholderSecretMarkers = []string{"change-me", "changeme", "replace-me", "your-secret", "random-secret"}
+
+// TokenType 区分访问令牌与刷新令牌，二者不可互换使用。
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+)
+
+// 令牌受众，区分顾客与门店员工，防止一方的令牌用于另一方的接口。
+const (
+	AudienceCustomer = "customer"
+	AudienceStaff    = "staff"
+)
+
+var (
+	// ErrInvalidToken 表示令牌格式错误、签名不符或类型、受众不匹配。
+	ErrInvalidToken = errors.New("token is invalid")
+	// ErrExpiredToken 表示令牌已过期。
+	ErrExpiredToken = errors.New("token is expired")
+
+	// tokenHeader 是固定的 JWT 头部 {"alg":"HS256","typ":"JWT"}，校验时要求完全一致以拒绝其他算法。
+	tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+)
+
+// Config 描述令牌签发参数，时长使用 duration 字符串。
+type Config struct {
+	// TokenSecret 为 HMAC-SHA256 签名密钥，至少 32 字节，更换后已签发的令牌全部失效。
+	TokenSecret     string `mapstructure:"token_secret"`
+	AccessTokenTTL  string `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL string `mapstructure:"refresh_token_ttl"`
+}
+
+// Claims 是令牌携带的声明，字段命名与 JWT 保持一致。
+type Claims struct {
+	Subject   string    `json:"sub"`
+	Audience  string    `json:"aud"`
+	Type      TokenType `json:"typ"`
+	ID        string    `json:"jti"`
+	IssuedAt  int64     `json:"iat"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// TokenPair 是登录或刷新后下发给客户端的令牌，ExpiresIn 为访问令牌的有效秒数。
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// TokenManager 以 HS256 签发与校验 JWT 格式的令牌。
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+// checkSecret 拒绝未配置、过短、仍为示例占位值或字符过于单一的签名密钥。
+func checkSecret(secret string) error {
+	if secret == "" {
+		return errors.New("auth token_secret is not configured, generate one with `openssl rand -base64 48`")
+	}
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("auth token_secret must be at least %d bytes", minSecretLength)
+	}
+	lower := strings.ToLower(secret)
+	for _, marker := range placeholderSecretMarkers {
+		if strings.Contains(lower, marker) {
+			return errors.New("auth token_secret is a placeholder, replace it with a random secret")
+		}
+	}
+	distinct := make(map[byte]struct{})
+	for i := 0; i < len(secret); i++ {
+		distinct[secret[i]] = struct{}{}
+	}
+	if len(distinct) < minSecretDistinctBytes {
+		return errors.New("auth token_secret is too predictable, use a random secret")
+	}
+	return nil
+}
+
+// NewTokenManager 根据配置创建 TokenManager，未配置的时长使用默认值。
+func NewTokenManager(cfg Config) (*TokenManager, error) {
+	if err := checkSecret(cfg.TokenSecret); err != nil {
+		return nil, err
+	}
+
+	m := &TokenManager{
+		secret:     []byte(cfg.TokenSecret),
+		accessTTL:  defaultAccessTokenTTL,
+		refreshTTL: defaultRefreshTokenTTL,
+		now:        time.Now,
+	}
+	if cfg.AccessTokenTTL != "" {
+		dur, err := time.ParseDuration(cfg.AccessTokenTTL)
+		if err != nil {
+			return nil, fmt.Errorf("parse auth access_token_ttl: %w", err)
+		}
+		m.accessTTL = dur
+	}
+	if cfg.RefreshTokenTTL != "" {
+		dur, err := time.ParseDuration(cfg.RefreshTokenTTL)
+		if err != nil {
+			return nil, fmt.Errorf("parse auth refresh_token_ttl: %w", err)
+		}
+		m.refreshTTL = dur
+	}
+	if m.accessTTL <= 0 || m.refreshTTL < m.accessTTL {
+		return nil, errors.New("auth access_token_ttl must be positive and not longer than refresh_token_ttl")
+	}
+
+	return m, nil
+}
+
+// Issue 为指定受众的 subject 签发一对访问令牌与刷新令牌。
+func (m *TokenManager) Issue(audience, subject string) (TokenPair, error) {
+	if audience == "" || subject == "" {
+		return TokenPair{}, errors.New("token audience and subject are required")
+	}
+
+	access, err := m.sign(audience, subject, TokenTypeAccess, m.accessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := m.sign(audience, subject, TokenTypeRefresh, m.refreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(m.accessTTL / time.Second),
+	}, nil
+}
+
+// Parse 校验令牌签名、受众、类型与有效期并返回其声明。
+func (m *TokenManager) Parse(token, audience string, typ TokenType) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, m.mac(parts[0]+"."+parts[1])) {
+		return Claims{}, ErrInvalidToken
+	}
+	if parts[0] != tokenHeader {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if claims.Audience != audience || claims.Type != typ || claims.Subject == "" {
+		return Claims{}, ErrInvalidToken
+	}
+	if m.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpiredToken
+	}
+
+	return claims, nil
+}
+
+func (m *TokenManager) sign(audience, subject string, typ TokenType, ttl time.Duration) (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+
+	now := m.now()
+	payload, err := json.Marshal(Claims{
+		Subject:   subject,
+		Audience:  audience,
+		Type:      typ,
+		ID:        hex.EncodeToString(id[:]),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(m.mac(unsigned)), nil
+}
+
+func (m *TokenManager) mac(message string) []byte {
+	h := hmac.New(sha256.New, m.secret)
+	h.Write([]byte(message))
+	return h.Sum(nil)
+}
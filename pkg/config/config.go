@@ -5,21 +5,35 @@ import (
 
 	"github.com/spf13/viper"
 
+	"convenienceStore/pkg/auth"
 	"convenienceStore/pkg/payment"
+	"convenienceStore/pkg/region"
+	"convenienceStore/pkg/sms"
+	"convenienceStore/pkg/wechat"
 )
 
 // AppConfig 包含整个应用的配置结构。
 type AppConfig struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Payment  payment.Config `mapstructure:"payment"`
+	Server         ServerConfig         `mapstructure:"server"`
+	Logging        LoggingConfig        `mapstructure:"logging"`
+	Database       DatabaseConfig       `mapstructure:"database"`
+	Auth           auth.Config          `mapstructure:"auth"`
+	Staff          StaffConfig          `mapstructure:"staff"`
+	WeChat         wechat.Config        `mapstructure:"wechat"`
+	SMS            sms.Config           `mapstructure:"sms"`
+	Phone          PhoneConfig          `mapstructure:"phone_verification"`
+	Region         region.Config        `mapstructure:"region"`
+	Loyalty        LoyaltyConfig        `mapstructure:"loyalty"`
+	Payment        payment.Config       `mapstructure:"payment"`
+	Order          OrderConfig          `mapstructure:"order"`
+	Reconciliation ReconciliationConfig `mapstructure:"reconciliation"`
 }
 
 // ServerConfig 定义 HTTP 服务器的运行时选项。
 type ServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port int    `mapstructure:"port"`
+	Host           string   `mapstructure:"host"`
+	Port           int      `mapstructure:"port"`
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // Address 返回 gin.Engine.Run 所需的监听地址字符串。
@@ -51,6 +65,59 @@ type DatabaseConfig struct {
 	ConnMaxLifetime string `mapstructure:"conn_max_lifetime"`
 }
 
+// OrderConfig 描述订单超时取消、支付状态对账等后台任务的参数。
+type OrderConfig struct {
+	PaymentTimeout       string `mapstructure:"payment_timeout"`
+	SweepInterval        string `mapstructure:"sweep_interval"`
+	SweepBatchSize       int    `mapstructure:"sweep_batch_size"`
+	ReconcileInterval    string `mapstructure:"reconcile_interval"`
+	ReconcileDelay       string `mapstructure:"reconcile_delay"`
+	ReconcileBatchSize   int    `mapstructure:"reconcile_batch_size"`
+	RefundQueryInterval  string `mapstructure:"refund_query_interval"`
+	RefundQueryDelay     string `mapstructure:"refund_query_delay"`
+	RefundQueryBatchSize int    `mapstructure:"refund_query_batch_size"`
+}
+
+// ReconciliationConfig 描述每日账单对账任务的参数。
+type ReconciliationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RunAt 为每天开始对账前一日账单的时刻（北京时间，HH:MM），需晚于渠道生成账单的时间。
+	RunAt         string `mapstructure:"run_at"`
+	CheckInterval string `mapstructure:"check_interval"`
+}
+
+// PhoneConfig 描述手机号短信验证码的有效期与限流参数。
+type PhoneConfig struct {
+	CodeTTL        string `mapstructure:"code_ttl"`
+	ResendInterval string `mapstructure:"resend_interval"`
+	// MaxAttempts 为单个验证码允许的校验次数，超过后验证码作废。
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// DailyLimitPerPhone 为同一手机号每 24 小时可下发的验证码数量。
+	DailyLimitPerPhone int `mapstructure:"daily_limit_per_phone"`
+	// HourlyLimitPerIP 为同一来源 IP 每小时可申请的验证码数量。
+	HourlyLimitPerIP int `mapstructure:"hourly_limit_per_ip"`
+}
+
+// LoyaltyConfig 描述会员积分的发放与抵扣比例，会员等级门槛与权益见 model.TierRules。
+type LoyaltyConfig struct {
+	// EarnPointsPerYuan 为订单实付每 1 元发放的基础积分，再按会员等级倍率放大。
+	EarnPointsPerYuan int64 `mapstructure:"earn_points_per_yuan"`
+	// RedeemPointsPerYuan 为结算时抵扣 1 元所需的积分。
+	RedeemPointsPerYuan int64 `mapstructure:"redeem_points_per_yuan"`
+}
+
+// StaffConfig 描述员工账号的初始化参数。
+type StaffConfig struct {
+	// BootstrapUsername 与 BootstrapPassword 用于在尚无店长时创建初始店长账号，创建后应尽快修改口令并清空此配置。
+	BootstrapUsername string `mapstructure:"bootstrap_username"`
+	BootstrapPassword string `mapstructure:"bootstrap_password"`
+	// LoginFailureWindow 为统计登录失败次数的时间窗口，MaxLoginFailuresPerUsername / MaxLoginFailuresPerIP
+	// 为窗口内同一用户名、同一来源 IP 允许的失败次数，超过后拒绝登录直至窗口滑过。
+	LoginFailureWindow          string `mapstructure:"login_failure_window"`
+	MaxLoginFailuresPerUsername int    `mapstructure:"max_login_failures_per_username"`
+	MaxLoginFailuresPerIP       int    `mapstructure:"max_login_failures_per_ip"`
+}
+
 // Load 从磁盘读取配置并填充 AppConfig。
 func Load(path string) (*AppConfig, error) {
 	v := viper.New()
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// nonceCleanupBatch 是每次登记新随机串时顺带清理的过期记录上限。
+const nonceCleanupBatch = 100
+
+// NonceStore 基于 notification_nonces 表登记支付回调随机串，多个实例共享同一张表，
+// 任一实例处理过的随机串在有效期内都会被其他实例识别为重放。
+type NonceStore struct {
+	db *sql.DB
+}
+
+// NewNonceStore 创建基于 MySQL 的回调随机串存储。
+func NewNonceStore(db *sql.DB) *NonceStore {
+	return &NonceStore{db: db}
+}
+
+// Remember 登记随机串，有效期内已登记过时返回 false。随机串唯一键保证并发登记时只有一方成功；
+// 已过期的同名记录会被刷新有效期并视为首次登记。
+func (s *NonceStore) Remember(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO notification_nonces (nonce, expires_at, created_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE expires_at = IF(expires_at < VALUES(created_at), VALUES(expires_at), expires_at)`,
+		nonce, expiresAt, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	// 新插入时影响 1 行，刷新过期记录时影响 2 行，有效期内重复登记不修改任何行。
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	// 顺带清理一批过期记录，清理失败不影响本次登记，下次登记时会再次尝试。
+	s.db.ExecContext(ctx, `DELETE FROM notification_nonces WHERE expires_at < ? LIMIT ?`, now, nonceCleanupBatch)
+	return true, nil
+}
@@ -0,0 +1,515 @@
+package payment
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAlipayGatewayURL 是支付宝开放平台的正式环境网关。
+const DefaultAlipayGatewayURL = "https://openapi.alipay.com/gateway.do"
+
+// alipayTimeLayout 是支付宝接口使用的北京时间格式。
+const alipayTimeLayout = "2006-01-02 15:04:05"
+
+var alipayLocation = time.FixedZone("CST", 8*60*60)
+
+// AlipayConfig 定义接入支付宝当面付所需的凭据。
+type AlipayConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	AppID   string `mapstructure:"app_id"`
+	// PrivateKeyPath 为应用私钥，用于请求签名。
+	PrivateKeyPath string `mapstructure:"private_key_path"`
+	// PublicKeyPath 为支付宝公钥，用于校验响应与异步通知签名。
+	PublicKeyPath string `mapstructure:"public_key_path"`
+	GatewayURL    string `mapstructure:"gateway_url"`
+	NotifyURL     string `mapstructure:"notify_url"`
+}
+
+// AlipayError 表示支付宝接口返回的业务错误。
+type AlipayError struct {
+	Code    string `json:"code"`
+	Msg     string `json:"msg"`
+	SubCode string `json:"sub_code"`
+	SubMsg  string `json:"sub_msg"`
+}
+
+func (e *AlipayError) Error() string {
+	return fmt.Sprintf("alipay api error code=%s msg=%s sub_code=%s sub_msg=%s", e.Code, e.Msg, e.SubCode, e.SubMsg)
+}
+
+// Rejected 报告请求是否被支付宝明确拒绝，20000 服务不可用与 ACQ.SYSTEM_ERROR 表示结果未知，需查询确认。
+func (e *AlipayError) Rejected() bool {
+	return e.Code != "20000" && e.SubCode != "ACQ.SYSTEM_ERROR"
+}
+
+// AlipayOption 用于定制支付宝客户端，便于测试时替换网络与密钥。
+type AlipayOption func(*alipayClient)
+
+// WithAlipayHTTPClient 替换底层 HTTP 客户端。
+func WithAlipayHTTPClient(httpClient *http.Client) AlipayOption {
+	return func(c *alipayClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAlipayKeys 直接注入应用私钥与支付宝公钥，优先于配置中的文件路径。
+func WithAlipayKeys(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey) AlipayOption {
+	return func(c *alipayClient) {
+		c.privateKey = privateKey
+		c.publicKey = publicKey
+	}
+}
+
+type alipayClient struct {
+	cfg        AlipayConfig
+	logger     *log.Logger
+	httpClient *http.Client
+	gatewayURL string
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
+
+// NewAlipayClient 创建基于支付宝当面付（扫码支付）的支付方式。
+func NewAlipayClient(cfg AlipayConfig, logger *log.Logger, opts ...AlipayOption) (Provider, error) {
+	c := &alipayClient{
+		cfg:        cfg,
+		logger:     logger,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		gatewayURL: cfg.GatewayURL,
+	}
+	if c.gatewayURL == "" {
+		c.gatewayURL = DefaultAlipayGatewayURL
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if cfg.AppID == "" {
+		return nil, errors.New("alipay app_id is required")
+	}
+	if c.privateKey == nil {
+		if cfg.PrivateKeyPath == "" {
+			return nil, errors.New("alipay private_key_path is required")
+		}
+		key, err := LoadPrivateKey(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		c.privateKey = key
+	}
+	if c.publicKey == nil {
+		if cfg.PublicKeyPath == "" {
+			return nil, errors.New("alipay public_key_path is required to verify responses")
+		}
+		key, err := LoadAlipayPublicKey(cfg.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		c.publicKey = key
+	}
+
+	return c, nil
+}
+
+// LoadAlipayPublicKey 读取支付宝公钥，兼容 PEM 与开放平台导出的纯 Base64 格式。
+func LoadAlipayPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read alipay public key: %w", err)
+	}
+
+	der := data
+	if block, _ := pem.Decode(data); block != nil {
+		der = block.Bytes
+	} else if der, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err != nil {
+		return nil, errors.New("alipay public key is neither PEM nor base64")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse alipay public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("alipay public key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func (c *alipayClient) Name() string {
+	return ProviderAlipay
+}
+
+// Prepare 调用 alipay.trade.precreate 生成收款二维码，Reference 为二维码内容。
+func (c *alipayClient) Prepare(ctx context.Context, request OrderRequest) (Checkout, error) {
+	if request.OrderID == "" {
+		return Checkout{}, errors.New("order id is required")
+	}
+	if request.Amount <= 0 {
+		return Checkout{}, errors.New("payment amount must be positive")
+	}
+
+	biz := map[string]string{
+		"out_trade_no": request.OrderID,
+		"total_amount": formatYuan(request.Amount),
+		"subject":      request.Subject,
+	}
+	if !request.ExpireAt.IsZero() {
+		biz["time_expire"] = request.ExpireAt.In(alipayLocation).Format(alipayTimeLayout)
+	}
+
+	var resp struct {
+		QRCode string `json:"qr_code"`
+	}
+	if err := c.call(ctx, "alipay.trade.precreate", biz, true, &resp); err != nil {
+		return Checkout{}, err
+	}
+	if resp.QRCode == "" {
+		return Checkout{}, errors.New("alipay returned empty qr_code")
+	}
+
+	c.logger.Printf("alipay precreate order_id=%s amount=%d", request.OrderID, request.Amount)
+	return Checkout{Reference: resp.QRCode, Credentials: map[string]string{"qr_code": resp.QRCode}, ExpiresAt: request.ExpireAt}, nil
+}
+
+// Resume 复用仍有效的收款二维码。
+func (c *alipayClient) Resume(ctx context.Context, reference string) (Checkout, error) {
+	return Checkout{Reference: reference, Credentials: map[string]string{"qr_code": reference}}, nil
+}
+
+type alipayTrade struct {
+	TradeNo     string `json:"trade_no"`
+	OutTradeNo  string `json:"out_trade_no"`
+	TradeStatus string `json:"trade_status"`
+	TotalAmount string `json:"total_amount"`
+	BuyerUserID string `json:"buyer_user_id"`
+	SendPayDate string `json:"send_pay_date"`
+}
+
+// QueryOrder 调用 alipay.trade.query 查询交易状态，顾客尚未扫码时返回未支付。
+func (c *alipayClient) QueryOrder(ctx context.Context, orderID string) (CallbackResult, error) {
+	if orderID == "" {
+		return CallbackResult{}, errors.New("order id is required")
+	}
+
+	var trade alipayTrade
+	if err := c.call(ctx, "alipay.trade.query", map[string]string{"out_trade_no": orderID}, false, &trade); err != nil {
+		var apiErr *AlipayError
+		if errors.As(err, &apiErr) && apiErr.SubCode == "ACQ.TRADE_NOT_EXIST" {
+			return CallbackResult{OrderID: orderID, TradeState: TradeStateNotPay}, nil
+		}
+		return CallbackResult{}, err
+	}
+
+	return alipayResult(trade.OutTradeNo, trade.TradeNo, trade.TradeStatus, trade.TotalAmount, trade.BuyerUserID, trade.SendPayDate)
+}
+
+// CloseOrder 调用 alipay.trade.close 关闭未支付交易，交易不存在时视为已关闭。
+func (c *alipayClient) CloseOrder(ctx context.Context, orderID string) error {
+	if orderID == "" {
+		return errors.New("order id is required")
+	}
+
+	if err := c.call(ctx, "alipay.trade.close", map[string]string{"out_trade_no": orderID}, false, nil); err != nil {
+		var apiErr *AlipayError
+		if errors.As(err, &apiErr) && apiErr.SubCode == "ACQ.TRADE_NOT_EXIST" {
+			return nil
+		}
+		return err
+	}
+
+	c.logger.Printf("alipay trade closed order_id=%s", orderID)
+	return nil
+}
+
+// Refund 调用 alipay.trade.refund 同步退款，out_request_no 保证同一退款单不会重复退款。
+func (c *alipayClient) Refund(ctx context.Context, request RefundRequest) (RefundResponse, error) {
+	if request.OrderID == "" || request.RefundID == "" {
+		return RefundResponse{}, errors.New("order id and refund id are required")
+	}
+	if request.Amount <= 0 || request.Amount > request.Total {
+		return RefundResponse{}, fmt.Errorf("refund amount %d must be positive and not exceed total %d", request.Amount, request.Total)
+	}
+
+	biz := map[string]string{
+		"out_trade_no":   request.OrderID,
+		"out_request_no": request.RefundID,
+		"refund_amount":  formatYuan(request.Amount),
+	}
+	if request.Reason != "" {
+		biz["refund_reason"] = request.Reason
+	}
+
+	var resp struct {
+		TradeNo      string `json:"trade_no"`
+		GmtRefundPay string `json:"gmt_refund_pay"`
+	}
+	if err := c.call(ctx, "alipay.trade.refund", biz, false, &resp); err != nil {
+		return RefundResponse{}, err
+	}
+
+	result := RefundResponse{RefundID: request.RefundID, ProviderRefundID: resp.TradeNo, Status: RefundStatusSuccess}
+	if resp.GmtRefundPay != "" {
+		if refundedAt, err := time.ParseInLocation(alipayTimeLayout, resp.GmtRefundPay, alipayLocation); err == nil {
+			result.SuccessTime = refundedAt
+		}
+	}
+
+	c.logger.Printf("alipay refund succeeded order_id=%s refund_id=%s amount=%d", request.OrderID, request.RefundID, request.Amount)
+	return result, nil
+}
+
+// QueryRefund 调用 alipay.trade.fastpay.refund.query 查询退款，未返回退款状态表示退款单不存在。
+func (c *alipayClient) QueryRefund(ctx context.Context, orderID, refundID string) (RefundResponse, error) {
+	if orderID == "" || refundID == "" {
+		return RefundResponse{}, errors.New("order id and refund id are required")
+	}
+
+	biz := map[string]string{
+		"out_trade_no":   orderID,
+		"out_request_no": refundID,
+	}
+	var resp struct {
+		TradeNo      string `json:"trade_no"`
+		RefundStatus string `json:"refund_status"`
+	}
+	if err := c.call(ctx, "alipay.trade.fastpay.refund.query", biz, false, &resp); err != nil {
+		var apiErr *AlipayError
+		if errors.As(err, &apiErr) && apiErr.SubCode == "ACQ.TRADE_NOT_EXIST" {
+			return RefundResponse{}, ErrRefundNotFound
+		}
+		return RefundResponse{}, err
+	}
+	if resp.RefundStatus != "REFUND_SUCCESS" {
+		return RefundResponse{}, ErrRefundNotFound
+	}
+	return RefundResponse{RefundID: refundID, ProviderRefundID: resp.TradeNo, Status: RefundStatusSuccess}, nil
+}
+
+// HandleCallback 校验支付宝异步通知签名并解析交易结果，签名不合法时返回 ErrInvalidSignature。
+func (c *alipayClient) HandleCallback(ctx context.Context, headers http.Header, payload []byte) (CallbackResult, error) {
+	values, err := url.ParseQuery(string(payload))
+	if err != nil {
+		return CallbackResult{}, fmt.Errorf("decode alipay notification: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(values.Get("sign"))
+	if err != nil || len(signature) == 0 {
+		return CallbackResult{}, fmt.Errorf("%w: malformed alipay signature", ErrInvalidSignature)
+	}
+	params := make(map[string]string, len(values))
+	for key := range values {
+		if key != "sign" && key != "sign_type" {
+			params[key] = values.Get(key)
+		}
+	}
+	if err := c.verify([]byte(alipaySignContent(params)), signature); err != nil {
+		c.logger.Printf("alipay callback rejected notify_id=%s: %v", values.Get("notify_id"), err)
+		return CallbackResult{}, err
+	}
+	if appID := values.Get("app_id"); appID != "" && appID != c.cfg.AppID {
+		return CallbackResult{}, fmt.Errorf("alipay notification app_id %s does not match application", appID)
+	}
+
+	result, err := alipayResult(values.Get("out_trade_no"), values.Get("trade_no"), values.Get("trade_status"), values.Get("total_amount"), values.Get("buyer_id"), values.Get("gmt_payment"))
+	if err != nil {
+		return CallbackResult{}, err
+	}
+	result.NotificationID = values.Get("notify_id")
+	result.EventType = values.Get("notify_type")
+
+	c.logger.Printf("alipay callback verified order_id=%s trade_no=%s state=%s", result.OrderID, result.TransactionID, values.Get("trade_status"))
+	return result, nil
+}
+
+// alipayResult 将支付宝交易状态映射为统一的交易状态。
+func alipayResult(orderID, tradeNo, tradeStatus, totalAmount, buyerID, paidAt string) (CallbackResult, error) {
+	result := CallbackResult{
+		OrderID:       orderID,
+		TransactionID: tradeNo,
+		PayerOpenID:   buyerID,
+	}
+	switch tradeStatus {
+	case "TRADE_SUCCESS", "TRADE_FINISHED":
+		result.TradeState = TradeStateSuccess
+		result.Success = true
+	case "TRADE_CLOSED":
+		result.TradeState = TradeStateClosed
+	default:
+		result.TradeState = TradeStateNotPay
+	}
+
+	if totalAmount != "" {
+		amount, err := parseYuan(totalAmount)
+		if err != nil {
+			return CallbackResult{}, err
+		}
+		result.Amount = amount
+		result.PayerTotal = amount
+	}
+	if paidAt != "" {
+		if successTime, err := time.ParseInLocation(alipayTimeLayout, paidAt, alipayLocation); err == nil {
+			result.SuccessTime = successTime
+		}
+	}
+	return result, nil
+}
+
+// call 以 RSA2 签名调用开放平台接口，校验响应签名后将业务数据解码到 out。
+func (c *alipayClient) call(ctx context.Context, method string, biz map[string]string, notify bool, out any) error {
+	bizContent, err := json.Marshal(biz)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		"app_id":      c.cfg.AppID,
+		"method":      method,
+		"format":      "JSON",
+		"charset":     "utf-8",
+		"sign_type":   "RSA2",
+		"timestamp":   time.Now().In(alipayLocation).Format(alipayTimeLayout),
+		"version":     "1.0",
+		"biz_content": string(bizContent),
+	}
+	if notify && c.cfg.NotifyURL != "" {
+		params["notify_url"] = c.cfg.NotifyURL
+	}
+	signature, err := signSHA256WithRSA(c.privateKey, alipaySignContent(params))
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	for key, value := range params {
+		form.Set(key, value)
+	}
+	form.Set("sign", signature)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.gatewayURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alipay gateway returned status %d", resp.StatusCode)
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("decode alipay response: %w", err)
+	}
+	node := envelope[strings.ReplaceAll(method, ".", "_")+"_response"]
+	if node == nil {
+		node = envelope["error_response"]
+	}
+	if node == nil {
+		return fmt.Errorf("alipay response for %s is missing", method)
+	}
+
+	var status AlipayError
+	if err := json.Unmarshal(node, &status); err != nil {
+		return fmt.Errorf("decode alipay response: %w", err)
+	}
+
+	var responseSign string
+	if raw, ok := envelope["sign"]; ok {
+		if err := json.Unmarshal(raw, &responseSign); err != nil {
+			return fmt.Errorf("decode alipay response sign: %w", err)
+		}
+	}
+	if responseSign != "" || status.Code == "10000" {
+		decoded, err := base64.StdEncoding.DecodeString(responseSign)
+		if err != nil {
+			return fmt.Errorf("%w: malformed alipay response signature", ErrInvalidSignature)
+		}
+		if err := c.verify(node, decoded); err != nil {
+			return err
+		}
+	}
+
+	if status.Code != "10000" {
+		return &status
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(node, out)
+}
+
+func (c *alipayClient) verify(content, signature []byte) error {
+	digest := sha256.Sum256(content)
+	if err := rsa.VerifyPKCS1v15(c.publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// alipaySignContent 按参数名升序以 key=value 形式拼接非空参数，得到待签名串。
+func alipaySignContent(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key, value := range params {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+params[key])
+	}
+	return strings.Join(pairs, "&")
+}
+
+// formatYuan 将分转换为两位小数的元。
+func formatYuan(fen int64) string {
+	return fmt.Sprintf("%d.%02d", fen/100, fen%100)
+}
+
+// parseYuan 将两位小数以内的元精确转换为分。
+func parseYuan(value string) (int64, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(value), ".")
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	yuan, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || yuan < 0 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	fen, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || fen < 0 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return yuan*100 + fen, nil
+}
@@ -0,0 +1,130 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// alipayServer 模拟支付宝网关，按接口名返回预设的业务应答，应答成功时以支付宝私钥签名。
+func alipayServer(t *testing.T, f weChatFixture, responses map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse alipay request: %v", err)
+		}
+		method := r.PostForm.Get("method")
+		node, ok := responses[method]
+		if !ok {
+			t.Errorf("unexpected alipay method %s", method)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var biz map[string]string
+		if err := json.Unmarshal([]byte(r.PostForm.Get("biz_content")), &biz); err != nil {
+			t.Errorf("decode biz_content: %v", err)
+		}
+		if biz["out_trade_no"] != "ord_123" || biz["out_request_no"] != "rf_1" {
+			t.Errorf("unexpected biz_content %v", biz)
+		}
+
+		sign := ""
+		var status AlipayError
+		if err := json.Unmarshal([]byte(node), &status); err == nil && status.Code == "10000" {
+			var err error
+			if sign, err = signSHA256WithRSA(f.platformKey, node); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"%s_response":%s,"sign":%q}`, strings.ReplaceAll(method, ".", "_"), node, sign)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestAlipayClient 以 fixture 的商户密钥作为应用私钥、平台密钥作为支付宝密钥构建客户端。
+func newTestAlipayClient(t *testing.T, f weChatFixture, server *httptest.Server) Provider {
+	t.Helper()
+	client, err := NewAlipayClient(AlipayConfig{AppID: "2021000000000000", GatewayURL: server.URL}, log.New(io.Discard, "", 0),
+		WithAlipayHTTPClient(server.Client()), WithAlipayKeys(f.merchantKey, &f.platformKey.PublicKey))
+	if err != nil {
+		t.Fatalf("NewAlipayClient: %v", err)
+	}
+	return client
+}
+
+func TestAlipayRefundRejectedByProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     string
+		rejected bool
+	}{
+		{name: "trade closed", node: `{"code":"40004","msg":"Business Failed","sub_code":"ACQ.TRADE_HAS_CLOSE","sub_msg":"交易已经关闭"}`, rejected: true},
+		{name: "insufficient balance", node: `{"code":"40004","msg":"Business Failed","sub_code":"ACQ.SELLER_BALANCE_NOT_ENOUGH","sub_msg":"卖家余额不足"}`, rejected: true},
+		{name: "system error", node: `{"code":"40004","msg":"Business Failed","sub_code":"ACQ.SYSTEM_ERROR","sub_msg":"系统错误"}`},
+		{name: "service unavailable", node: `{"code":"20000","msg":"Service Currently Unavailable","sub_code":"isp.unknow-error","sub_msg":"系统繁忙"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newWeChatFixture(t)
+			server := alipayServer(t, f, map[string]string{"alipay.trade.refund": tt.node})
+
+			_, err := newTestAlipayClient(t, f, server).Refund(context.Background(), RefundRequest{OrderID: "ord_123", RefundID: "rf_1", Amount: 500, Total: 1000})
+			var apiErr *AlipayError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Refund error = %v, want *AlipayError", err)
+			}
+			if got := Rejected(err); got != tt.rejected {
+				t.Errorf("Rejected(%v) = %v, want %v", err, got, tt.rejected)
+			}
+		})
+	}
+}
+
+func TestAlipayRefundGatewayFailureIsNotRejected(t *testing.T) {
+	f := newWeChatFixture(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := newTestAlipayClient(t, f, server).Refund(context.Background(), RefundRequest{OrderID: "ord_123", RefundID: "rf_1", Amount: 500, Total: 1000})
+	if err == nil || Rejected(err) {
+		t.Fatalf("Refund error = %v, want an unknown outcome", err)
+	}
+}
+
+func TestAlipayQueryRefund(t *testing.T) {
+	f := newWeChatFixture(t)
+	server := alipayServer(t, f, map[string]string{
+		"alipay.trade.fastpay.refund.query": `{"code":"10000","msg":"Success","trade_no":"2024050122001400000000000001","out_trade_no":"ord_123","out_request_no":"rf_1","refund_amount":"5.00","refund_status":"REFUND_SUCCESS"}`,
+	})
+
+	resp, err := newTestAlipayClient(t, f, server).QueryRefund(context.Background(), "ord_123", "rf_1")
+	if err != nil {
+		t.Fatalf("QueryRefund: %v", err)
+	}
+	if resp.Status != RefundStatusSuccess || resp.RefundID != "rf_1" || resp.ProviderRefundID != "2024050122001400000000000001" {
+		t.Errorf("unexpected refund %+v", resp)
+	}
+}
+
+func TestAlipayQueryRefundMissingRefund(t *testing.T) {
+	f := newWeChatFixture(t)
+	server := alipayServer(t, f, map[string]string{
+		"alipay.trade.fastpay.refund.query": `{"code":"10000","msg":"Success","trade_no":"2024050122001400000000000001","out_trade_no":"ord_123","out_request_no":"rf_1"}`,
+	})
+
+	if _, err := newTestAlipayClient(t, f, server).QueryRefund(context.Background(), "ord_123", "rf_1"); !errors.Is(err, ErrRefundNotFound) {
+		t.Fatalf("QueryRefund error = %v, want ErrRefundNotFound", err)
+	}
+}
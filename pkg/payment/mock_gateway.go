@@ -0,0 +1,778 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 支付运行模式：live 对接真实的微信支付，mock 使用进程内的模拟网关。
+const (
+	ModeLive = "live"
+	ModeMock = "mock"
+)
+
+// MockGatewayPath 是模拟网关在 HTTP 服务上的挂载路径。
+const MockGatewayPath = "/mock-pay"
+
+// 模拟网关支持的支付结果。
+const (
+	MockActionSuccess = "success"
+	MockActionFail    = "fail"
+	MockActionTimeout = "timeout"
+)
+
+const (
+	mockMerchantSerial = "MOCK-MERCHANT-SERIAL"
+	mockPlatformSerial = "MOCK-PLATFORM-SERIAL"
+)
+
+// MockConfig 描述模拟网关的地址，留空时按本机服务端口推导。
+type MockConfig struct {
+	// BaseURL 为模拟网关对外地址，默认 http://127.0.0.1:<port>/mock-pay。
+	BaseURL string `mapstructure:"base_url"`
+	// NotifyURL 与 RefundNotifyURL 为模拟网关投递通知的地址，默认指向本机的回调接口。
+	NotifyURL       string `mapstructure:"notify_url"`
+	RefundNotifyURL string `mapstructure:"refund_notify_url"`
+}
+
+// MockGateway 在进程内模拟微信支付 v3 接口与收银台页面。它使用随机生成的商户密钥、
+// 平台密钥与 APIv3 密钥，按正式环境的格式签名并加密通知，使回调验签与解密流程得到完整执行。
+// 仅用于开发与测试，任何人都能通过收银台将订单置为已支付。
+type MockGateway struct {
+	logger          *log.Logger
+	httpClient      *http.Client
+	baseURL         string
+	notifyURL       string
+	refundNotifyURL string
+	appID           string
+	mchID           string
+	apiV3Key        string
+	merchantKey     *rsa.PrivateKey
+	platformKey     *rsa.PrivateKey
+	mux             *http.ServeMux
+
+	mu      sync.Mutex
+	trades  map[string]*mockTrade
+	prepays map[string]string
+	refunds map[string]*mockRefund
+}
+
+type mockTrade struct {
+	OrderID       string
+	PrepayID      string
+	Description   string
+	PayerOpenID   string
+	TransactionID string
+	State         string
+	Amount        int64
+	Refunded      int64
+	ExpireAt      time.Time
+	SuccessTime   time.Time
+}
+
+type mockRefund struct {
+	RefundID    string
+	OutRefundNo string
+	OrderID     string
+	Amount      int64
+	SuccessTime time.Time
+}
+
+// NewMockWeChat 创建模拟网关以及指向它的微信支付客户端。localURL 为本服务的对外根地址，
+// 例如 http://127.0.0.1:8081，用于推导网关与回调的默认地址。
+// opts 会一并传给客户端，例如 WithNonceStore。
+func NewMockWeChat(cfg Config, localURL string, logger *log.Logger, opts ...Option) (*MockGateway, WeChatClient, error) {
+	localURL = strings.TrimRight(localURL, "/")
+	g := &MockGateway{
+		logger:          logger,
+		httpClient:      &http.Client{Timeout: 10 * time.Second},
+		baseURL:         firstNonEmpty(cfg.Mock.BaseURL, localURL+MockGatewayPath),
+		notifyURL:       firstNonEmpty(cfg.Mock.NotifyURL, localURL+"/api/payments/wechat/callback"),
+		refundNotifyURL: firstNonEmpty(cfg.Mock.RefundNotifyURL, localURL+"/api/payments/wechat/refund-callback"),
+		appID:           firstNonEmpty(cfg.AppID, "wx-mock-app"),
+		mchID:           firstNonEmpty(cfg.MchID, "mock-merchant"),
+		trades:          make(map[string]*mockTrade),
+		prepays:         make(map[string]string),
+		refunds:         make(map[string]*mockRefund),
+	}
+	g.baseURL = strings.TrimRight(g.baseURL, "/")
+
+	var err error
+	if g.merchantKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		return nil, nil, err
+	}
+	if g.platformKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		return nil, nil, err
+	}
+	if g.apiV3Key, err = newNonce(); err != nil {
+		return nil, nil, err
+	}
+
+	clientCfg := cfg
+	clientCfg.AppID = g.appID
+	clientCfg.MchID = g.mchID
+	clientCfg.BaseURL = g.baseURL
+	clientCfg.NotifyURL = g.notifyURL
+	clientCfg.RefundNotifyURL = g.refundNotifyURL
+	clientCfg.CertSerialNo = mockMerchantSerial
+	clientCfg.APIv3Key = g.apiV3Key
+	clientCfg.PrivateKeyPath = ""
+	clientCfg.PlatformCertPaths = nil
+
+	opts = append(opts,
+		WithPrivateKey(g.merchantKey),
+		WithPlatformKey(mockPlatformSerial, &g.platformKey.PublicKey),
+	)
+	client, err := NewWeChatClient(clientCfg, logger, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g.mux = http.NewServeMux()
+	g.mux.HandleFunc("POST /v3/pay/transactions/jsapi", g.handlePrepay)
+	g.mux.HandleFunc("GET /v3/pay/transactions/out-trade-no/{no}", g.handleQuery)
+	g.mux.HandleFunc("POST /v3/pay/transactions/out-trade-no/{no}/close", g.handleClose)
+	g.mux.HandleFunc("POST /v3/refund/domestic/refunds", g.handleRefund)
+	g.mux.HandleFunc("GET /v3/refund/domestic/refunds/{no}", g.handleRefundQuery)
+	g.mux.HandleFunc("GET /v3/bill/tradebill", g.handleTradeBill)
+	g.mux.HandleFunc("GET /v3/billdownload/file", g.handleBillDownload)
+	g.mux.HandleFunc("GET /cashier", g.handleCashier)
+	g.mux.HandleFunc("POST /cashier", g.handleCashierSubmit)
+	g.mux.HandleFunc("POST /trades/{no}/{action}", g.handleTradeAction)
+
+	logger.Printf("mock payment gateway enabled at %s, callbacks go to %s", g.baseURL, g.notifyURL)
+	return g, &mockWeChatClient{WeChatClient: client, cashierURL: g.baseURL + "/cashier"}, nil
+}
+
+// Handler 返回模拟网关的 HTTP 处理器，调用方需去掉 MockGatewayPath 前缀后再交给它。
+func (g *MockGateway) Handler() http.Handler {
+	return g.mux
+}
+
+// mockWeChatClient 在客户端参数中附带模拟收银台地址，便于前端直接打开页面完成支付。
+type mockWeChatClient struct {
+	WeChatClient
+	cashierURL string
+}
+
+func (c *mockWeChatClient) Prepare(ctx context.Context, request OrderRequest) (Checkout, error) {
+	checkout, err := c.WeChatClient.Prepare(ctx, request)
+	if err != nil {
+		return Checkout{}, err
+	}
+	checkout.Credentials["mock_cashier_url"] = c.cashierURL + "?prepay_id=" + url.QueryEscape(checkout.Reference)
+	return checkout, nil
+}
+
+func (c *mockWeChatClient) Resume(ctx context.Context, reference string) (Checkout, error) {
+	checkout, err := c.WeChatClient.Resume(ctx, reference)
+	if err != nil {
+		return Checkout{}, err
+	}
+	checkout.Credentials["mock_cashier_url"] = c.cashierURL + "?prepay_id=" + url.QueryEscape(reference)
+	return checkout, nil
+}
+
+func (g *MockGateway) handlePrepay(w http.ResponseWriter, r *http.Request) {
+	body, ok := g.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req jsapiPrepayRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeMockError(w, http.StatusBadRequest, "PARAM_ERROR", err.Error())
+		return
+	}
+	if req.AppID != g.appID || req.MchID != g.mchID {
+		writeMockError(w, http.StatusBadRequest, "APPID_MCHID_NOT_MATCH", "appid and mchid do not match")
+		return
+	}
+	if req.OutTradeNo == "" || req.Amount.Total <= 0 || req.Payer.OpenID == "" {
+		writeMockError(w, http.StatusBadRequest, "PARAM_ERROR", "out_trade_no, amount and payer are required")
+		return
+	}
+
+	prepayID, err := newNonce()
+	if err != nil {
+		writeMockError(w, http.StatusInternalServerError, "SYSTEM_ERROR", err.Error())
+		return
+	}
+	prepayID = "wx_mock_" + prepayID
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	trade, exists := g.trades[req.OutTradeNo]
+	if exists {
+		switch trade.State {
+		case TradeStateSuccess, TradeStateRefund:
+			writeMockError(w, http.StatusBadRequest, "ORDERPAID", "order has been paid")
+			return
+		case TradeStateClosed:
+			writeMockError(w, http.StatusBadRequest, "ORDER_CLOSED", "order has been closed")
+			return
+		}
+		if trade.Amount != req.Amount.Total {
+			writeMockError(w, http.StatusBadRequest, "OUT_TRADE_NO_USED", "out_trade_no is used with a different amount")
+			return
+		}
+	} else {
+		trade = &mockTrade{OrderID: req.OutTradeNo, State: TradeStateNotPay, Amount: req.Amount.Total}
+		g.trades[req.OutTradeNo] = trade
+	}
+	trade.PrepayID = prepayID
+	trade.Description = req.Description
+	trade.PayerOpenID = req.Payer.OpenID
+	if req.TimeExpire != "" {
+		if expireAt, err := time.Parse(time.RFC3339, req.TimeExpire); err == nil {
+			trade.ExpireAt = expireAt
+		}
+	}
+	g.prepays[prepayID] = req.OutTradeNo
+
+	writeMockJSON(w, http.StatusOK, map[string]string{"prepay_id": prepayID})
+}
+
+func (g *MockGateway) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.authenticate(w, r); !ok {
+		return
+	}
+
+	g.mu.Lock()
+	trade, exists := g.trades[r.PathValue("no")]
+	var resource transactionResource
+	if exists {
+		resource = g.transactionResource(trade)
+	}
+	g.mu.Unlock()
+
+	if !exists {
+		writeMockError(w, http.StatusNotFound, "ORDER_NOT_EXIST", "order does not exist")
+		return
+	}
+	writeMockJSON(w, http.StatusOK, resource)
+}
+
+func (g *MockGateway) handleClose(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.authenticate(w, r); !ok {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	trade, exists := g.trades[r.PathValue("no")]
+	if !exists {
+		writeMockError(w, http.StatusNotFound, "ORDER_NOT_EXIST", "order does not exist")
+		return
+	}
+	if trade.State == TradeStateSuccess || trade.State == TradeStateRefund {
+		writeMockError(w, http.StatusBadRequest, "ORDERPAID", "order has been paid")
+		return
+	}
+	trade.State = TradeStateClosed
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRefund 受理退款后立即返回处理中，并在稍后异步投递退款成功通知，与正式环境的时序一致。
+func (g *MockGateway) handleRefund(w http.ResponseWriter, r *http.Request) {
+	body, ok := g.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req refundCreateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeMockError(w, http.StatusBadRequest, "PARAM_ERROR", err.Error())
+		return
+	}
+
+	g.mu.Lock()
+	refund, exists := g.refunds[req.OutRefundNo]
+	if !exists {
+		trade, ok := g.trades[req.OutTradeNo]
+		switch {
+		case !ok || (trade.State != TradeStateSuccess && trade.State != TradeStateRefund):
+			g.mu.Unlock()
+			writeMockError(w, http.StatusBadRequest, "RESOURCE_NOT_EXISTS", "order has not been paid")
+			return
+		case req.Amount.Total != trade.Amount || req.Amount.Refund <= 0 || trade.Refunded+req.Amount.Refund > trade.Amount:
+			g.mu.Unlock()
+			writeMockError(w, http.StatusBadRequest, "INVALID_REQUEST", "refund amount exceeds the refundable balance")
+			return
+		}
+
+		refundID, err := newNonce()
+		if err != nil {
+			g.mu.Unlock()
+			writeMockError(w, http.StatusInternalServerError, "SYSTEM_ERROR", err.Error())
+			return
+		}
+		refund = &mockRefund{RefundID: "50000" + refundID[:24], OutRefundNo: req.OutRefundNo, OrderID: trade.OrderID, Amount: req.Amount.Refund, SuccessTime: time.Now()}
+		g.refunds[req.OutRefundNo] = refund
+		trade.Refunded += req.Amount.Refund
+		trade.State = TradeStateRefund
+
+		notifyURL := firstNonEmpty(req.NotifyURL, g.refundNotifyURL)
+		resource := refundResource{
+			MchID:         g.mchID,
+			OutTradeNo:    trade.OrderID,
+			TransactionID: trade.TransactionID,
+			OutRefundNo:   refund.OutRefundNo,
+			RefundID:      refund.RefundID,
+			RefundStatus:  RefundStatusSuccess,
+			SuccessTime:   refund.SuccessTime.Format(time.RFC3339),
+		}
+		resource.Amount.Total = trade.Amount
+		resource.Amount.Refund = refund.Amount
+		resource.Amount.PayerTotal = trade.Amount
+		resource.Amount.PayerRefund = refund.Amount
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			if _, err := g.deliver(notifyURL, "REFUND.SUCCESS", "refund", resource); err != nil {
+				g.logger.Printf("mock refund notification for %s failed: %v", refund.OutRefundNo, err)
+			}
+		}()
+	}
+	g.mu.Unlock()
+
+	writeMockJSON(w, http.StatusOK, refundCreateResponse{
+		RefundID:    refund.RefundID,
+		OutRefundNo: refund.OutRefundNo,
+		Status:      RefundStatusProcessing,
+	})
+}
+
+// handleRefundQuery 返回已受理退款的结果，模拟网关受理的退款最终都会成功。
+func (g *MockGateway) handleRefundQuery(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.authenticate(w, r); !ok {
+		return
+	}
+
+	g.mu.Lock()
+	refund, ok := g.refunds[r.PathValue("no")]
+	var resp refundCreateResponse
+	if ok {
+		resp = refundCreateResponse{
+			RefundID:    refund.RefundID,
+			OutRefundNo: refund.OutRefundNo,
+			Status:      RefundStatusSuccess,
+			SuccessTime: refund.SuccessTime.Format(time.RFC3339),
+		}
+	}
+	g.mu.Unlock()
+
+	if !ok {
+		writeMockError(w, http.StatusNotFound, "RESOURCE_NOT_EXISTS", "refund does not exist")
+		return
+	}
+	writeMockJSON(w, http.StatusOK, resp)
+}
+
+func (g *MockGateway) handleTradeBill(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.authenticate(w, r); !ok {
+		return
+	}
+
+	billDate, err := time.ParseInLocation(time.DateOnly, r.URL.Query().Get("bill_date"), BillLocation)
+	if err != nil {
+		writeMockError(w, http.StatusBadRequest, "PARAM_ERROR", "invalid bill_date")
+		return
+	}
+	bill, lines := g.tradeBill(billDate)
+	if lines == 0 {
+		writeMockError(w, http.StatusBadRequest, "NO_STATEMENT_EXIST", "no statement for the bill date")
+		return
+	}
+
+	sum := sha1.Sum(bill)
+	writeMockJSON(w, http.StatusOK, map[string]string{
+		"hash_type":    "SHA1",
+		"hash_value":   hex.EncodeToString(sum[:]),
+		"download_url": g.baseURL + "/v3/billdownload/file?" + url.Values{"bill_date": {billDate.Format(time.DateOnly)}}.Encode(),
+	})
+}
+
+func (g *MockGateway) handleBillDownload(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.authenticate(w, r); !ok {
+		return
+	}
+
+	billDate, err := time.ParseInLocation(time.DateOnly, r.URL.Query().Get("bill_date"), BillLocation)
+	if err != nil {
+		writeMockError(w, http.StatusBadRequest, "PARAM_ERROR", "invalid bill_date")
+		return
+	}
+	bill, _ := g.tradeBill(billDate)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	_, _ = w.Write(bill)
+}
+
+// tradeBill 按微信支付交易账单的格式生成指定日期的支付与退款明细，返回账单内容与明细行数。
+func (g *MockGateway) tradeBill(billDate time.Time) ([]byte, int) {
+	start := billDate
+	end := start.AddDate(0, 0, 1)
+	inDay := func(t time.Time) bool {
+		return !t.IsZero() && !t.Before(start) && t.Before(end)
+	}
+
+	type billLine struct {
+		at     time.Time
+		fields []string
+	}
+	var lines []billLine
+	var paid, refunded int64
+
+	g.mu.Lock()
+	for _, trade := range g.trades {
+		if !inDay(trade.SuccessTime) {
+			continue
+		}
+		paid += trade.Amount
+		lines = append(lines, billLine{at: trade.SuccessTime, fields: []string{
+			trade.SuccessTime.In(BillLocation).Format(time.DateTime), g.appID, g.mchID, "0", "",
+			trade.TransactionID, trade.OrderID, trade.PayerOpenID, "JSAPI", TradeStateSuccess, "OTHERS", "CNY",
+			formatYuan(trade.Amount), "0.00", "0", "0", "0.00", "0.00", "", "",
+			trade.Description, "", "0.00000", "0.00%", formatYuan(trade.Amount), "0.00", "",
+		}})
+	}
+	for _, refund := range g.refunds {
+		trade := g.trades[refund.OrderID]
+		if trade == nil || !inDay(refund.SuccessTime) {
+			continue
+		}
+		refunded += refund.Amount
+		lines = append(lines, billLine{at: refund.SuccessTime, fields: []string{
+			refund.SuccessTime.In(BillLocation).Format(time.DateTime), g.appID, g.mchID, "0", "",
+			trade.TransactionID, trade.OrderID, trade.PayerOpenID, "JSAPI", TradeStateRefund, "OTHERS", "CNY",
+			"0.00", "0.00", refund.RefundID, refund.OutRefundNo, formatYuan(refund.Amount), "0.00", "ORIGINAL", RefundStatusSuccess,
+			trade.Description, "", "0.00000", "0.00%", formatYuan(trade.Amount), formatYuan(refund.Amount), "",
+		}})
+	}
+	g.mu.Unlock()
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i].at.Before(lines[j].at) })
+
+	var buf bytes.Buffer
+	buf.WriteString("交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\r\n")
+	for _, line := range lines {
+		// 账单各列不加引号，字段内的逗号替换为全角以免错列。
+		for i, field := range line.fields {
+			line.fields[i] = strings.ReplaceAll(field, ",", "，")
+		}
+		buf.WriteString("`" + strings.Join(line.fields, ",`") + "\r\n")
+	}
+	buf.WriteString("总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n")
+	fmt.Fprintf(&buf, "`%d,`%s,`%s,`0.00,`0.00000,`%s,`%s\r\n", len(lines), formatYuan(paid), formatYuan(refunded), formatYuan(paid), formatYuan(refunded))
+	return buf.Bytes(), len(lines)
+}
+
+// handleTradeAction 供自动化测试直接驱动支付结果，notify=false 时不投递通知以模拟回调丢失。
+func (g *MockGateway) handleTradeAction(w http.ResponseWriter, r *http.Request) {
+	notify := r.URL.Query().Get("notify") != "false"
+	delivery, err := g.settle(r.PathValue("no"), r.PathValue("action"), notify)
+	if err != nil {
+		writeMockError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	writeMockJSON(w, http.StatusOK, map[string]any{"order_id": r.PathValue("no"), "action": r.PathValue("action"), "notification": delivery})
+}
+
+func (g *MockGateway) handleCashier(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	orderID := g.prepays[r.URL.Query().Get("prepay_id")]
+	trade, exists := g.trades[orderID]
+	var view mockTrade
+	if exists {
+		view = *trade
+	}
+	g.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "unknown prepay_id", http.StatusNotFound)
+		return
+	}
+	g.renderCashier(w, view, "")
+}
+
+func (g *MockGateway) handleCashierSubmit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	g.mu.Lock()
+	orderID, exists := g.prepays[r.PostForm.Get("prepay_id")]
+	g.mu.Unlock()
+	if !exists {
+		http.Error(w, "unknown prepay_id", http.StatusNotFound)
+		return
+	}
+
+	message, err := g.settle(orderID, r.PostForm.Get("action"), r.PostForm.Get("skip_notify") == "")
+	if err != nil {
+		message = err.Error()
+	}
+
+	g.mu.Lock()
+	view := *g.trades[orderID]
+	g.mu.Unlock()
+	g.renderCashier(w, view, message)
+}
+
+// settle 将交易置为指定结果，需要时投递支付通知并返回投递结果。
+func (g *MockGateway) settle(orderID, action string, notify bool) (string, error) {
+	g.mu.Lock()
+	trade, exists := g.trades[orderID]
+	if !exists {
+		g.mu.Unlock()
+		return "", fmt.Errorf("order %s does not exist", orderID)
+	}
+	if trade.State != TradeStateNotPay && trade.State != TradeStateUserPaying {
+		g.mu.Unlock()
+		return "", fmt.Errorf("order %s is already %s", orderID, trade.State)
+	}
+
+	eventType := "TRANSACTION.FAIL"
+	switch action {
+	case MockActionSuccess:
+		transactionID, err := newNonce()
+		if err != nil {
+			g.mu.Unlock()
+			return "", err
+		}
+		trade.State = TradeStateSuccess
+		trade.TransactionID = "42000" + transactionID[:23]
+		trade.SuccessTime = time.Now()
+		eventType = "TRANSACTION.SUCCESS"
+	case MockActionFail:
+		trade.State = TradeStatePayError
+	case MockActionTimeout:
+		trade.State = TradeStateClosed
+	default:
+		g.mu.Unlock()
+		return "", fmt.Errorf("unknown action %s", action)
+	}
+	resource := g.transactionResource(trade)
+	g.mu.Unlock()
+
+	if !notify {
+		return "notification skipped", nil
+	}
+	status, err := g.deliver(g.notifyURL, eventType, "transaction", resource)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("notification delivered, callback responded %d", status), nil
+}
+
+// transactionResource 生成与查单接口、支付通知一致的交易详情，调用方需持有锁。
+func (g *MockGateway) transactionResource(trade *mockTrade) transactionResource {
+	resource := transactionResource{
+		AppID:      g.appID,
+		MchID:      g.mchID,
+		OutTradeNo: trade.OrderID,
+		TradeType:  "JSAPI",
+		TradeState: trade.State,
+	}
+	resource.TransactionID = trade.TransactionID
+	resource.Payer.OpenID = trade.PayerOpenID
+	resource.Amount.Total = trade.Amount
+	resource.Amount.Currency = "CNY"
+	if trade.State == TradeStateSuccess || trade.State == TradeStateRefund {
+		resource.Amount.PayerTotal = trade.Amount
+		resource.SuccessTime = trade.SuccessTime.Format(time.RFC3339)
+	}
+	return resource
+}
+
+// deliver 按正式环境的格式加密通知资源并以平台私钥签名，投递到 notifyURL。
+func (g *MockGateway) deliver(notifyURL, eventType, associatedData string, resource any) (int, error) {
+	plaintext, err := json.Marshal(resource)
+	if err != nil {
+		return 0, err
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return 0, err
+	}
+	nonce = nonce[:12]
+	block, err := aes.NewCipher([]byte(g.apiV3Key))
+	if err != nil {
+		return 0, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return 0, err
+	}
+	ciphertext := gcm.Seal(nil, []byte(nonce), plaintext, []byte(associatedData))
+
+	notificationID, err := newNonce()
+	if err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(map[string]any{
+		"id":            notificationID,
+		"create_time":   time.Now().Format(time.RFC3339),
+		"event_type":    eventType,
+		"resource_type": "encrypt-resource",
+		"resource": map[string]string{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      base64.StdEncoding.EncodeToString(ciphertext),
+			"associated_data": associatedData,
+			"original_type":   associatedData,
+			"nonce":           nonce,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	headerNonceValue, err := newNonce()
+	if err != nil {
+		return 0, err
+	}
+	signature, err := signSHA256WithRSA(g.platformKey, buildMessage(timestamp, headerNonceValue, string(body)))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, notifyURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerNonce, headerNonceValue)
+	req.Header.Set(headerSignature, signature)
+	req.Header.Set(headerSerial, mockPlatformSerial)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	g.logger.Printf("mock gateway delivered %s to %s status=%d", eventType, notifyURL, resp.StatusCode)
+	return resp.StatusCode, nil
+}
+
+// authenticate 按 v3 规范校验商户请求签名，失败时直接写出 401 应答。
+func (g *MockGateway) authenticate(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeMockError(w, http.StatusBadRequest, "PARAM_ERROR", err.Error())
+		return nil, false
+	}
+
+	schema, params, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || schema != authSchema {
+		writeMockError(w, http.StatusUnauthorized, "SIGN_ERROR", "missing authorization")
+		return nil, false
+	}
+	fields := make(map[string]string)
+	for _, pair := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(pair, "=")
+		fields[key] = strings.Trim(value, `"`)
+	}
+	if fields["mchid"] != g.mchID || fields["serial_no"] != mockMerchantSerial {
+		writeMockError(w, http.StatusUnauthorized, "SIGN_ERROR", "unknown merchant certificate")
+		return nil, false
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(fields["signature"])
+	if err != nil {
+		writeMockError(w, http.StatusUnauthorized, "SIGN_ERROR", "malformed signature")
+		return nil, false
+	}
+	// RequestURI 保留挂载前缀，与客户端签名时使用的路径一致。
+	digest := sha256.Sum256([]byte(buildMessage(r.Method, r.RequestURI, fields["timestamp"], fields["nonce_str"], string(body))))
+	if err := rsa.VerifyPKCS1v15(&g.merchantKey.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		writeMockError(w, http.StatusUnauthorized, "SIGN_ERROR", "signature verification failed")
+		return nil, false
+	}
+	return body, true
+}
+
+var mockCashierTemplate = template.Must(template.New("cashier").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>模拟收银台</title></head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 2em auto;">
+<h2>模拟微信支付收银台</h2>
+<p>仅用于开发与测试，不会产生真实扣款。</p>
+<table>
+<tr><td>订单号</td><td>{{.Trade.OrderID}}</td></tr>
+<tr><td>商品</td><td>{{.Trade.Description}}</td></tr>
+<tr><td>金额</td><td>¥{{.Amount}}</td></tr>
+<tr><td>状态</td><td>{{.Trade.State}}</td></tr>
+{{if .Trade.TransactionID}}<tr><td>交易号</td><td>{{.Trade.TransactionID}}</td></tr>{{end}}
+</table>
+{{if .Message}}<p><strong>{{.Message}}</strong></p>{{end}}
+{{if .Pending}}
+<form method="post">
+<input type="hidden" name="prepay_id" value="{{.Trade.PrepayID}}">
+<p><label><input type="checkbox" name="skip_notify" value="1"> 不投递回调（模拟回调丢失）</label></p>
+<button name="action" value="success">支付成功</button>
+<button name="action" value="fail">支付失败</button>
+<button name="action" value="timeout">支付超时</button>
+</form>
+{{end}}
+</body>
+</html>`))
+
+func (g *MockGateway) renderCashier(w http.ResponseWriter, trade mockTrade, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := mockCashierTemplate.Execute(w, map[string]any{
+		"Trade":   trade,
+		"Amount":  formatYuan(trade.Amount),
+		"Message": message,
+		"Pending": trade.State == TradeStateNotPay || trade.State == TradeStateUserPaying,
+	})
+	if err != nil {
+		g.logger.Printf("render mock cashier failed: %v", err)
+	}
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeMockError(w http.ResponseWriter, status int, code, message string) {
+	writeMockJSON(w, status, map[string]string{"code": code, "message": message})
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
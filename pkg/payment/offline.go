@@ -0,0 +1,94 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultOfflineInstructions 是未配置说明时展示给顾客的付款提示。
+	defaultOfflineInstructions = "请在送货上门或到店取货时向店员付款"
+	// defaultOfflineHoldTimeout 是未配置时线下付款订单等待店员确认收款的时限。
+	defaultOfflineHoldTimeout = 48 * time.Hour
+)
+
+// OfflineConfig 描述货到付款 / 到店付款方式。
+type OfflineConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Instructions string `mapstructure:"instructions"`
+	// HoldTimeout 为选择线下付款后等待店员确认收款的时限，超时未确认的订单会被取消并归还库存。
+	HoldTimeout string `mapstructure:"hold_timeout"`
+}
+
+// offlineProvider 不与任何渠道交互，收款由店员在后台确认，退款须经店员审核并当面退还现金。
+type offlineProvider struct {
+	instructions string
+	holdTimeout  time.Duration
+}
+
+// NewOfflineProvider 创建货到付款 / 到店付款的支付方式。
+func NewOfflineProvider(cfg OfflineConfig) (Provider, error) {
+	p := &offlineProvider{instructions: cfg.Instructions, holdTimeout: defaultOfflineHoldTimeout}
+	if p.instructions == "" {
+		p.instructions = defaultOfflineInstructions
+	}
+	if cfg.HoldTimeout != "" {
+		dur, err := time.ParseDuration(cfg.HoldTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("parse offline hold_timeout: %w", err)
+		}
+		if dur <= 0 {
+			return nil, errors.New("offline hold_timeout must be positive")
+		}
+		p.holdTimeout = dur
+	}
+	return p, nil
+}
+
+func (p *offlineProvider) Name() string {
+	return ProviderOffline
+}
+
+// Prepare 返回付款说明，并以确认收款时限作为支付记录的过期时间。
+func (p *offlineProvider) Prepare(ctx context.Context, request OrderRequest) (Checkout, error) {
+	checkout := p.checkout()
+	checkout.ExpiresAt = time.Now().Add(p.holdTimeout)
+	return checkout, nil
+}
+
+func (p *offlineProvider) Resume(ctx context.Context, reference string) (Checkout, error) {
+	return p.checkout(), nil
+}
+
+func (p *offlineProvider) checkout() Checkout {
+	return Checkout{Credentials: map[string]string{
+		"method":       ProviderOffline,
+		"instructions": p.instructions,
+	}}
+}
+
+func (p *offlineProvider) HandleCallback(ctx context.Context, headers http.Header, payload []byte) (CallbackResult, error) {
+	return CallbackResult{}, ErrUnsupported
+}
+
+// QueryOrder 始终返回未支付，线下收款只能由店员确认。
+func (p *offlineProvider) QueryOrder(ctx context.Context, orderID string) (CallbackResult, error) {
+	return CallbackResult{OrderID: orderID, TradeState: TradeStateNotPay}, nil
+}
+
+func (p *offlineProvider) CloseOrder(ctx context.Context, orderID string) error {
+	return nil
+}
+
+// Refund 视为立即成功：线下订单的退款只能由店员审核通过后提交，审核即表示现金已当面退还。
+func (p *offlineProvider) Refund(ctx context.Context, request RefundRequest) (RefundResponse, error) {
+	return RefundResponse{RefundID: request.RefundID, Status: RefundStatusSuccess}, nil
+}
+
+// QueryRefund 与 Refund 一致，线下退款提交即完成。
+func (p *offlineProvider) QueryRefund(ctx context.Context, orderID, refundID string) (RefundResponse, error) {
+	return RefundResponse{RefundID: refundID, Status: RefundStatusSuccess}, nil
+}
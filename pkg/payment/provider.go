@@ -0,0 +1,105 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// 内置支付方式的名称，同时作为注册表的键与支付记录中的 provider 字段。
+const (
+	ProviderWeChat  = "wechat"
+	ProviderAlipay  = "alipay"
+	ProviderOffline = "offline"
+)
+
+var (
+	// ErrUnsupported 表示支付方式不支持该操作，例如线下支付没有渠道回调。
+	ErrUnsupported = errors.New("operation is not supported by this payment provider")
+	// ErrRefundNotFound 表示渠道没有该退款单，通常是退款申请未送达渠道，可用同一退款单号重新提交。
+	ErrRefundNotFound = errors.New("refund does not exist at the payment provider")
+)
+
+// rejection 由渠道业务错误实现，用于区分渠道明确拒绝的请求与结果未知的失败。
+type rejection interface {
+	Rejected() bool
+}
+
+// Rejected 报告 err 是否表示渠道已明确拒绝请求，此时可以确定请求没有生效；
+// 网络错误、超时与渠道系统繁忙等结果未知的失败返回 false，调用方需稍后查询确认。
+func Rejected(err error) bool {
+	var r rejection
+	return errors.As(err, &r) && r.Rejected()
+}
+
+// Checkout 是发起支付后交给客户端的结果。
+// Reference 为渠道侧的预支付标识（如微信 prepay_id、支付宝交易号），线下支付为空。
+type Checkout struct {
+	Reference   string
+	Credentials map[string]string
+	ExpiresAt   time.Time
+}
+
+// Provider 抽象一种支付方式，金额单位均为分，商户订单号即本地订单号。
+type Provider interface {
+	Name() string
+	// Prepare 在渠道侧创建交易并返回客户端拉起支付所需的参数。
+	Prepare(ctx context.Context, request OrderRequest) (Checkout, error)
+	// Resume 基于仍有效的 Reference 重新生成客户端参数，避免重复创建交易。
+	Resume(ctx context.Context, reference string) (Checkout, error)
+	// HandleCallback 校验并解析渠道的支付结果通知。
+	HandleCallback(ctx context.Context, headers http.Header, payload []byte) (CallbackResult, error)
+	QueryOrder(ctx context.Context, orderID string) (CallbackResult, error)
+	CloseOrder(ctx context.Context, orderID string) error
+	Refund(ctx context.Context, request RefundRequest) (RefundResponse, error)
+	// QueryRefund 按商户退款单号查询退款结果，渠道没有该退款单时返回 ErrRefundNotFound。
+	QueryRefund(ctx context.Context, orderID, refundID string) (RefundResponse, error)
+}
+
+// RefundNotifier 由以异步通知告知退款结果的支付方式实现。
+type RefundNotifier interface {
+	HandleRefundCallback(ctx context.Context, headers http.Header, payload []byte) (RefundCallbackResult, error)
+}
+
+// Registry 按名称登记可用的支付方式。
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry 构建支付方式注册表，名称重复时返回错误。
+func NewRegistry(providers ...Provider) (*Registry, error) {
+	r := &Registry{providers: make(map[string]Provider, len(providers))}
+	for _, provider := range providers {
+		name := provider.Name()
+		if _, exists := r.providers[name]; exists {
+			return nil, fmt.Errorf("payment provider %s registered twice", name)
+		}
+		r.providers[name] = provider
+	}
+	return r, nil
+}
+
+// Get 返回指定名称的支付方式。
+func (r *Registry) Get(name string) (Provider, bool) {
+	if r == nil {
+		return nil, false
+	}
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names 按字典序返回已登记的支付方式名称。
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
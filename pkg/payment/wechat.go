@@ -1,81 +1,472 @@
 package payment
 
 import (
+	"bytes"
 	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
-// Config 定义接入微信支付所需的凭据。
+// DefaultWeChatBaseURL 是微信支付 v3 接口的正式环境地址。
+const DefaultWeChatBaseURL = "https://api.mch.weixin.qq.com"
+
+// 交易状态，与微信支付查单接口与回调中的 trade_state 一致。
+const (
+	TradeStateSuccess    = "SUCCESS"
+	TradeStateRefund     = "REFUND"
+	TradeStateNotPay     = "NOTPAY"
+	TradeStateClosed     = "CLOSED"
+	TradeStateRevoked    = "REVOKED"
+	TradeStateUserPaying = "USERPAYING"
+	TradeStatePayError   = "PAYERROR"
+)
+
+// Config 定义接入各支付方式所需的凭据，顶层字段为微信支付参数。
 type Config struct {
 	AppID     string `mapstructure:"app_id"`
 	MchID     string `mapstructure:"mch_id"`
 	APIKey    string `mapstructure:"api_key"`
 	NotifyURL string `mapstructure:"notify_url"`
+	// RefundNotifyURL 接收退款结果通知，为空时退款请求不携带 notify_url，以商户平台配置为准。
+	RefundNotifyURL string `mapstructure:"refund_notify_url"`
+	PrivateKeyPath  string `mapstructure:"private_key_path"`
+	CertSerialNo    string `mapstructure:"cert_serial_no"`
+	APIv3Key        string `mapstructure:"api_v3_key"`
+	BaseURL         string `mapstructure:"base_url"`
+	// PlatformCertPaths 为微信支付平台证书文件，用于校验回调签名，轮换期间可同时配置新旧证书。
+	PlatformCertPaths []string `mapstructure:"platform_cert_paths"`
+	// Mode 为 mock 时微信支付改由进程内的模拟网关承接，仅用于开发与测试，默认 live。
+	Mode string     `mapstructure:"mode"`
+	Mock MockConfig `mapstructure:"mock"`
+
+	Alipay  AlipayConfig  `mapstructure:"alipay"`
+	Offline OfflineConfig `mapstructure:"offline"`
 }
 
 // OrderRequest 描述创建支付订单所需的最小请求载荷。
 type OrderRequest struct {
-	OrderID string
-	Amount  int64
-	Subject string
+	OrderID     string
+	Amount      int64
+	Subject     string
+	PayerOpenID string
+	ExpireAt    time.Time
 }
 
 // OrderResponse 封装支付服务方返回的、用于客户端的字段。
 type OrderResponse struct {
 	PrepayID  string
-	NonceStr  string
+	AppID     string
 	Timestamp string
-	Signature string
+	NonceStr  string
+	Package   string
+	SignType  string
+	PaySign   string
 }
 
-// ClientConfig 将网关响应转换为前端易用的键值对。
+// ClientConfig 将网关响应转换为小程序 wx.requestPayment 所需的参数。
 func (r OrderResponse) ClientConfig() map[string]string {
 	return map[string]string{
 		"prepay_id": r.PrepayID,
-		"nonce_str": r.NonceStr,
-		"timestamp": r.Timestamp,
-		"signature": r.Signature,
+		"appId":     r.AppID,
+		"timeStamp": r.Timestamp,
+		"nonceStr":  r.NonceStr,
+		"package":   r.Package,
+		"signType":  r.SignType,
+		"paySign":   r.PaySign,
 	}
 }
 
-// CallbackResult 汇总支付服务回调的核心字段。
+// CallbackResult 汇总支付服务回调或主动查单的核心字段，金额单位为分。
+// 查单结果不含 NotificationID 与 EventType。
 type CallbackResult struct {
-	OrderID string
-	Success bool
+	NotificationID string
+	EventType      string
+	OrderID        string
+	TransactionID  string
+	TradeState     string
+	Amount         int64
+	PayerTotal     int64
+	PayerOpenID    string
+	SuccessTime    time.Time
+	Success        bool
+}
+
+// APIError 表示微信支付接口返回的业务错误。
+type APIError struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
 }
 
-// WeChatClient 抽象出对微信支付的调用接口。
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wechat pay api error status=%d code=%s message=%s", e.StatusCode, e.Code, e.Message)
+}
+
+// Rejected 报告请求是否被明确拒绝：4xx 应答为确定的业务错误，
+// 5xx、频率限制与 SYSTEM_ERROR 表示渠道可能已受理，结果需查询确认。
+func (e *APIError) Rejected() bool {
+	if e.Code == "SYSTEM_ERROR" || e.Code == "FREQUENCY_LIMITED" || e.StatusCode == http.StatusTooManyRequests {
+		return false
+	}
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// WeChatClient 抽象出对微信支付的调用接口，同时作为 Provider 登记到注册表。
 type WeChatClient interface {
+	Provider
+	RefundNotifier
+	BillDownloader
 	CreateOrder(ctx context.Context, request OrderRequest) (OrderResponse, error)
-	HandleCallback(ctx context.Context, payload []byte) (CallbackResult, error)
+	PayParams(prepayID string) (OrderResponse, error)
+}
+
+// Option 用于定制微信支付客户端，便于测试时替换网络与密钥。
+type Option func(*weChatClient)
+
+// WithHTTPClient 替换底层 HTTP 客户端。
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *weChatClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithPrivateKey 直接注入商户私钥，优先于 Config.PrivateKeyPath。
+func WithPrivateKey(key *rsa.PrivateKey) Option {
+	return func(c *weChatClient) {
+		c.privateKey = key
+	}
+}
+
+// WithPlatformKey 注册用于校验回调签名的平台公钥，serial 对应 Wechatpay-Serial 请求头。
+func WithPlatformKey(serial string, key *rsa.PublicKey) Option {
+	return func(c *weChatClient) {
+		c.platformKeys[serial] = key
+	}
+}
+
+// WithNonceStore 设置记录回调随机串的存储，用于拒绝重放的回调请求。
+func WithNonceStore(store NonceStore) Option {
+	return func(c *weChatClient) {
+		c.nonces = store
+	}
 }
 
 type weChatClient struct {
-	cfg    Config
-	logger *log.Logger
+	cfg          Config
+	logger       *log.Logger
+	httpClient   *http.Client
+	baseURL      string
+	privateKey   *rsa.PrivateKey
+	platformKeys map[string]*rsa.PublicKey
+	nonces       NonceStore
 }
 
-// NewWeChatClient 提供适合早期开发使用的桩实现。
-func NewWeChatClient(cfg Config, logger *log.Logger) WeChatClient {
-	return &weChatClient{cfg: cfg, logger: logger}
+// NewWeChatClient 创建基于微信支付 v3 接口的 JSAPI 支付客户端。
+// Config.BaseURL 可指向本地的 httptest 服务以便联调与测试。
+func NewWeChatClient(cfg Config, logger *log.Logger, opts ...Option) (WeChatClient, error) {
+	c := &weChatClient{
+		cfg:          cfg,
+		logger:       logger,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		baseURL:      cfg.BaseURL,
+		platformKeys: make(map[string]*rsa.PublicKey),
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultWeChatBaseURL
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if cfg.AppID == "" || cfg.MchID == "" {
+		return nil, errors.New("wechat pay app_id and mch_id are required")
+	}
+	if cfg.CertSerialNo == "" {
+		return nil, errors.New("wechat pay cert_serial_no is required")
+	}
+	if c.privateKey == nil {
+		if cfg.PrivateKeyPath == "" {
+			return nil, errors.New("wechat pay private_key_path is required")
+		}
+		key, err := LoadPrivateKey(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		c.privateKey = key
+	}
+	if len(cfg.APIv3Key) != 32 {
+		return nil, errors.New("wechat pay api_v3_key must be 32 bytes")
+	}
+	for _, path := range cfg.PlatformCertPaths {
+		serial, key, err := LoadPlatformCertificate(path)
+		if err != nil {
+			return nil, err
+		}
+		c.platformKeys[serial] = key
+	}
+	if len(c.platformKeys) == 0 {
+		return nil, errors.New("wechat pay platform_cert_paths is required to verify callbacks")
+	}
+	if c.nonces == nil {
+		return nil, errors.New("wechat pay nonce store is required to reject replayed callbacks")
+	}
+
+	return c, nil
+}
+
+type jsapiAmount struct {
+	Total    int64  `json:"total"`
+	Currency string `json:"currency"`
+}
+
+type jsapiPayer struct {
+	OpenID string `json:"openid"`
+}
+
+type jsapiPrepayRequest struct {
+	AppID       string      `json:"appid"`
+	MchID       string      `json:"mchid"`
+	Description string      `json:"description"`
+	OutTradeNo  string      `json:"out_trade_no"`
+	TimeExpire  string      `json:"time_expire,omitempty"`
+	NotifyURL   string      `json:"notify_url"`
+	Amount      jsapiAmount `json:"amount"`
+	Payer       jsapiPayer  `json:"payer"`
 }
 
 func (c *weChatClient) CreateOrder(ctx context.Context, request OrderRequest) (OrderResponse, error) {
-	c.logger.Printf("wechat create order order_id=%s amount=%d", request.OrderID, request.Amount)
-	return OrderResponse{
-		PrepayID:  fmt.Sprintf("wx_prepay_%s", request.OrderID),
-		NonceStr:  "nonce-demo",
-		Timestamp: fmt.Sprintf("%d", time.Now().Unix()),
-		Signature: "signature-demo",
-	}, nil
-}
-
-func (c *weChatClient) HandleCallback(ctx context.Context, payload []byte) (CallbackResult, error) {
-	c.logger.Printf("wechat callback received bytes=%d", len(payload))
-	return CallbackResult{
-		OrderID: "order-demo",
-		Success: true,
-	}, nil
+	if request.OrderID == "" {
+		return OrderResponse{}, errors.New("order id is required")
+	}
+	if request.Amount <= 0 {
+		return OrderResponse{}, errors.New("payment amount must be positive")
+	}
+	if request.PayerOpenID == "" {
+		return OrderResponse{}, errors.New("payer openid is required for jsapi payment")
+	}
+
+	body := jsapiPrepayRequest{
+		AppID:       c.cfg.AppID,
+		MchID:       c.cfg.MchID,
+		Description: request.Subject,
+		OutTradeNo:  request.OrderID,
+		NotifyURL:   c.cfg.NotifyURL,
+		Amount:      jsapiAmount{Total: request.Amount, Currency: "CNY"},
+		Payer:       jsapiPayer{OpenID: request.PayerOpenID},
+	}
+	if !request.ExpireAt.IsZero() {
+		body.TimeExpire = request.ExpireAt.Format(time.RFC3339)
+	}
+
+	var resp struct {
+		PrepayID string `json:"prepay_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v3/pay/transactions/jsapi", body, &resp); err != nil {
+		return OrderResponse{}, err
+	}
+	if resp.PrepayID == "" {
+		return OrderResponse{}, errors.New("wechat pay returned empty prepay_id")
+	}
+
+	c.logger.Printf("wechat prepay created order_id=%s amount=%d", request.OrderID, request.Amount)
+	return c.PayParams(resp.PrepayID)
+}
+
+func (c *weChatClient) Name() string {
+	return ProviderWeChat
+}
+
+// Prepare 创建 JSAPI 预支付交易，Reference 为 prepay_id。
+func (c *weChatClient) Prepare(ctx context.Context, request OrderRequest) (Checkout, error) {
+	resp, err := c.CreateOrder(ctx, request)
+	if err != nil {
+		return Checkout{}, err
+	}
+	return Checkout{Reference: resp.PrepayID, Credentials: resp.ClientConfig(), ExpiresAt: request.ExpireAt}, nil
+}
+
+// Resume 为仍有效的 prepay_id 重新签名。
+func (c *weChatClient) Resume(ctx context.Context, reference string) (Checkout, error) {
+	resp, err := c.PayParams(reference)
+	if err != nil {
+		return Checkout{}, err
+	}
+	return Checkout{Reference: reference, Credentials: resp.ClientConfig()}, nil
+}
+
+// PayParams 基于 prepay_id 重新生成小程序调起支付所需的参数与 paySign 签名，
+// 可用于复用仍在有效期内的预支付交易。
+func (c *weChatClient) PayParams(prepayID string) (OrderResponse, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return OrderResponse{}, err
+	}
+
+	resp := OrderResponse{
+		PrepayID:  prepayID,
+		AppID:     c.cfg.AppID,
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		NonceStr:  nonce,
+		Package:   "prepay_id=" + prepayID,
+		SignType:  "RSA",
+	}
+	resp.PaySign, err = signSHA256WithRSA(c.privateKey, buildMessage(resp.AppID, resp.Timestamp, resp.NonceStr, resp.Package))
+	if err != nil {
+		return OrderResponse{}, err
+	}
+	return resp, nil
+}
+
+// HandleCallback 校验并解密支付结果通知，签名或时间戳不合法时返回
+// ErrInvalidSignature / ErrReplayedNotification，调用方应拒绝该请求。
+func (c *weChatClient) HandleCallback(ctx context.Context, headers http.Header, payload []byte) (CallbackResult, error) {
+	n, plaintext, err := c.openNotification(ctx, headers, payload)
+	if err != nil {
+		return CallbackResult{}, err
+	}
+
+	var tx transactionResource
+	if err := json.Unmarshal(plaintext, &tx); err != nil {
+		return CallbackResult{}, fmt.Errorf("decode wechat transaction: %w", err)
+	}
+	if tx.MchID != "" && tx.MchID != c.cfg.MchID {
+		return CallbackResult{}, fmt.Errorf("wechat notification mchid %s does not match merchant", tx.MchID)
+	}
+
+	result := tx.result()
+	result.NotificationID = n.ID
+	result.EventType = n.EventType
+
+	c.logger.Printf("wechat callback verified order_id=%s transaction_id=%s state=%s", result.OrderID, result.TransactionID, result.TradeState)
+	return result, nil
+}
+
+// QueryOrder 以商户订单号主动查询交易状态，用于补偿丢失的支付回调。
+// 微信支付侧不存在该订单时返回 TradeState 为 NOTPAY 的结果。
+func (c *weChatClient) QueryOrder(ctx context.Context, orderID string) (CallbackResult, error) {
+	if orderID == "" {
+		return CallbackResult{}, errors.New("order id is required")
+	}
+
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(orderID) + "?mchid=" + url.QueryEscape(c.cfg.MchID)
+	var tx transactionResource
+	if err := c.do(ctx, http.MethodGet, path, nil, &tx); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Code == "ORDER_NOT_EXIST" {
+			return CallbackResult{OrderID: orderID, TradeState: TradeStateNotPay}, nil
+		}
+		return CallbackResult{}, err
+	}
+	if tx.MchID != "" && tx.MchID != c.cfg.MchID {
+		return CallbackResult{}, fmt.Errorf("wechat transaction mchid %s does not match merchant", tx.MchID)
+	}
+
+	return tx.result(), nil
+}
+
+// CloseOrder 关闭未支付的交易，关闭后顾客无法再使用原 prepay_id 支付。
+func (c *weChatClient) CloseOrder(ctx context.Context, orderID string) error {
+	if orderID == "" {
+		return errors.New("order id is required")
+	}
+
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(orderID) + "/close"
+	if err := c.do(ctx, http.MethodPost, path, map[string]string{"mchid": c.cfg.MchID}, nil); err != nil {
+		return err
+	}
+
+	c.logger.Printf("wechat transaction closed order_id=%s", orderID)
+	return nil
+}
+
+// openNotification 校验回调签名并解密通知资源，返回外层通知与资源明文。
+func (c *weChatClient) openNotification(ctx context.Context, headers http.Header, payload []byte) (notification, []byte, error) {
+	if err := c.verifyNotification(ctx, headers, payload); err != nil {
+		c.logger.Printf("wechat callback rejected serial=%s: %v", headers.Get(headerSerial), err)
+		return notification{}, nil, err
+	}
+
+	var n notification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return notification{}, nil, fmt.Errorf("decode wechat notification: %w", err)
+	}
+	plaintext, err := c.decryptResource(n)
+	if err != nil {
+		return notification{}, nil, err
+	}
+	return n, plaintext, nil
+}
+
+// do 发送带商户签名的 v3 接口请求，并将 2xx 响应体解码到 out。
+func (c *weChatClient) do(ctx context.Context, method, path string, body any, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	authorization, err := c.authorization(method, req.URL.RequestURI(), payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "convenienceStore-wechatpay/1.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(respBody, apiErr)
+		return apiErr
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// authorization 按 v3 规范构造 Authorization 请求头。
+func (c *weChatClient) authorization(method, uri string, body []byte) (string, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	signature, err := signSHA256WithRSA(c.privateKey, buildMessage(method, uri, timestamp, nonce, string(body)))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		authSchema, c.cfg.MchID, nonce, signature, timestamp, c.cfg.CertSerialNo), nil
 }
@@ -0,0 +1,198 @@
+package payment
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BillLocation 是微信支付账单使用的时区，账单日按北京时间划分。
+var BillLocation = time.FixedZone("CST", 8*60*60)
+
+// billSummaryHeader 是账单末尾汇总段的首列标题，之后的内容不再是交易明细。
+const billSummaryHeader = "总交易单数"
+
+// BillRecord 是交易账单中的一行明细，金额单位为分。
+// 退款行的 TradeState 为 REFUND，并携带退款单号与退款金额。
+type BillRecord struct {
+	TradeTime        time.Time
+	TransactionID    string
+	OrderID          string
+	TradeState       string
+	Amount           int64
+	RefundID         string
+	ProviderRefundID string
+	RefundAmount     int64
+	RefundStatus     string
+}
+
+// BillDownloader 由能够提供日交易账单的支付方式实现，返回的内容可交给 ParseTradeBill 解析。
+type BillDownloader interface {
+	DownloadTradeBill(ctx context.Context, billDate time.Time) ([]byte, error)
+}
+
+// DownloadTradeBill 申请并下载指定日期的全部交易账单（bill_type=ALL），并按返回的摘要校验文件完整性。
+// 当天没有交易时微信支付不生成账单，此时返回空内容。
+func (c *weChatClient) DownloadTradeBill(ctx context.Context, billDate time.Time) ([]byte, error) {
+	query := url.Values{
+		"bill_date": {billDate.Format(time.DateOnly)},
+		"bill_type": {"ALL"},
+	}
+	var bill struct {
+		HashType    string `json:"hash_type"`
+		HashValue   string `json:"hash_value"`
+		DownloadURL string `json:"download_url"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v3/bill/tradebill?"+query.Encode(), nil, &bill); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Code == "NO_STATEMENT_EXIST" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if bill.DownloadURL == "" {
+		return nil, errors.New("wechat pay returned empty bill download_url")
+	}
+
+	data, err := c.download(ctx, bill.DownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(bill.HashType, "SHA1") {
+		sum := sha1.Sum(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), bill.HashValue) {
+			return nil, errors.New("wechat pay bill hash mismatch")
+		}
+	}
+
+	c.logger.Printf("wechat trade bill downloaded bill_date=%s bytes=%d", query.Get("bill_date"), len(data))
+	return data, nil
+}
+
+// download 以商户签名请求账单下载地址，下载地址为完整 URL，不拼接 BaseURL。
+func (c *weChatClient) download(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	authorization, err := c.authorization(http.MethodGet, req.URL.RequestURI(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("User-Agent", "convenienceStore-wechatpay/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	return data, nil
+}
+
+// ParseTradeBill 解析微信支付交易账单 CSV。字段按表头名称定位，各值去掉防止科学计数法的反引号前缀，
+// 遇到汇总段即停止。空内容返回空明细。
+func ParseTradeBill(r io.Reader) ([]BillRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read bill header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range []string{"交易时间", "微信订单号", "商户订单号", "交易状态"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("bill header is missing column %s", name)
+		}
+	}
+	amountColumn := "订单金额"
+	if _, ok := columns[amountColumn]; !ok {
+		amountColumn = "应结订单金额"
+	}
+
+	var records []BillRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read bill line %d: %w", line, err)
+		}
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		if strings.TrimSpace(row[0]) == billSummaryHeader {
+			break
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(row[i]), "`"))
+		}
+		amount := func(name string) (int64, error) {
+			value := field(name)
+			if value == "" {
+				return 0, nil
+			}
+			fen, err := parseYuan(value)
+			if err != nil {
+				return 0, fmt.Errorf("bill line %d column %s: %w", line, name, err)
+			}
+			return fen, nil
+		}
+
+		record := BillRecord{
+			TransactionID:    field("微信订单号"),
+			OrderID:          field("商户订单号"),
+			TradeState:       field("交易状态"),
+			RefundID:         field("商户退款单号"),
+			ProviderRefundID: field("微信退款单号"),
+			RefundStatus:     field("退款状态"),
+		}
+		if record.RefundID == "0" {
+			record.RefundID = ""
+		}
+		if record.ProviderRefundID == "0" {
+			record.ProviderRefundID = ""
+		}
+		if record.TradeTime, err = time.ParseInLocation(time.DateTime, field("交易时间"), BillLocation); err != nil {
+			return nil, fmt.Errorf("bill line %d: invalid trade time: %w", line, err)
+		}
+		if record.Amount, err = amount(amountColumn); err != nil {
+			return nil, err
+		}
+		if record.RefundAmount, err = amount("退款金额"); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,192 @@
+package payment
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// notificationMaxSkew 是回调时间戳与本地时间允许的最大偏差，超出即视为重放。
+	notificationMaxSkew = 5 * time.Minute
+
+	headerTimestamp = "Wechatpay-Timestamp"
+	headerNonce     = "Wechatpay-Nonce"
+	headerSignature = "Wechatpay-Signature"
+	headerSerial    = "Wechatpay-Serial"
+)
+
+var (
+	// ErrInvalidSignature 表示回调签名缺失或校验失败。
+	ErrInvalidSignature = errors.New("wechat pay notification signature is invalid")
+	// ErrReplayedNotification 表示回调时间戳超出允许偏差或随机串已被使用。
+	ErrReplayedNotification = errors.New("wechat pay notification is expired or replayed")
+)
+
+// NonceStore 登记已验签的回调随机串。多实例部署时须使用共享存储，
+// 否则同一请求重放到另一实例时无法识别。
+type NonceStore interface {
+	// Remember 登记随机串并至少保留到 expiresAt，有效期内已登记过时返回 false。
+	Remember(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// LoadPlatformCertificate 读取微信支付平台证书，返回证书序列号与公钥。
+func LoadPlatformCertificate(path string) (string, *rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("read wechat pay platform certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", nil, fmt.Errorf("platform certificate %s is not valid PEM", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse platform certificate %s: %w", path, err)
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return "", nil, fmt.Errorf("platform certificate %s does not hold an RSA key", path)
+	}
+
+	return fmt.Sprintf("%X", cert.SerialNumber), key, nil
+}
+
+// notification 是微信支付回调通知的外层结构。
+type notification struct {
+	ID           string `json:"id"`
+	CreateTime   string `json:"create_time"`
+	EventType    string `json:"event_type"`
+	ResourceType string `json:"resource_type"`
+	Resource     struct {
+		Algorithm      string `json:"algorithm"`
+		Ciphertext     string `json:"ciphertext"`
+		AssociatedData string `json:"associated_data"`
+		OriginalType   string `json:"original_type"`
+		Nonce          string `json:"nonce"`
+	} `json:"resource"`
+}
+
+// transactionResource 是支付通知解密后或查单接口返回的交易详情。
+type transactionResource struct {
+	AppID          string `json:"appid"`
+	MchID          string `json:"mchid"`
+	OutTradeNo     string `json:"out_trade_no"`
+	TransactionID  string `json:"transaction_id"`
+	TradeType      string `json:"trade_type"`
+	TradeState     string `json:"trade_state"`
+	TradeStateDesc string `json:"trade_state_desc"`
+	SuccessTime    string `json:"success_time"`
+	Payer          struct {
+		OpenID string `json:"openid"`
+	} `json:"payer"`
+	Amount struct {
+		Total      int64  `json:"total"`
+		PayerTotal int64  `json:"payer_total"`
+		Currency   string `json:"currency"`
+	} `json:"amount"`
+}
+
+// verifyNotification 校验回调的时间戳、平台签名与随机串。渠道重发处理失败的通知时会重新生成随机串并签名，
+// 因此随机串重复只可能来自重放；内容相同的重发由入账流程按交易号、退款单号幂等处理。
+func (c *weChatClient) verifyNotification(ctx context.Context, headers http.Header, body []byte) error {
+	timestamp := headers.Get(headerTimestamp)
+	nonce := headers.Get(headerNonce)
+	signature := headers.Get(headerSignature)
+	serial := headers.Get(headerSerial)
+	if timestamp == "" || nonce == "" || signature == "" || serial == "" {
+		return fmt.Errorf("%w: missing Wechatpay headers", ErrInvalidSignature)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	now := time.Now()
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew > notificationMaxSkew || skew < -notificationMaxSkew {
+		return ErrReplayedNotification
+	}
+
+	key, ok := c.platformKeys[serial]
+	if !ok {
+		return fmt.Errorf("%w: unknown platform certificate serial %s", ErrInvalidSignature, serial)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	digest := sha256.Sum256([]byte(buildMessage(timestamp, nonce, string(body))))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], decoded); err != nil {
+		return ErrInvalidSignature
+	}
+
+	// 时间戳超出偏差的请求已被拒绝，随机串只需保留到偏差窗口结束。
+	fresh, err := c.nonces.Remember(ctx, nonce, now.Add(2*notificationMaxSkew))
+	if err != nil {
+		return fmt.Errorf("remember wechat notification nonce: %w", err)
+	}
+	if !fresh {
+		return ErrReplayedNotification
+	}
+	return nil
+}
+
+// decryptResource 使用 APIv3 密钥以 AEAD_AES_256_GCM 解密通知资源。
+func (c *weChatClient) decryptResource(n notification) ([]byte, error) {
+	if n.Resource.Algorithm != "AEAD_AES_256_GCM" {
+		return nil, fmt.Errorf("unsupported notification algorithm %s", n.Resource.Algorithm)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(n.Resource.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decode notification ciphertext: %w", err)
+	}
+
+	block, err := aes.NewCipher([]byte(c.cfg.APIv3Key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(n.Resource.Nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, []byte(n.Resource.Nonce), ciphertext, []byte(n.Resource.AssociatedData))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt notification resource: %w", err)
+	}
+	return plaintext, nil
+}
+
+// result 将交易详情转换为 CallbackResult。
+func (tx transactionResource) result() CallbackResult {
+	result := CallbackResult{
+		OrderID:       tx.OutTradeNo,
+		TransactionID: tx.TransactionID,
+		TradeState:    tx.TradeState,
+		Amount:        tx.Amount.Total,
+		PayerTotal:    tx.Amount.PayerTotal,
+		PayerOpenID:   tx.Payer.OpenID,
+		Success:       tx.TradeState == TradeStateSuccess,
+	}
+	if tx.SuccessTime != "" {
+		if successTime, err := time.Parse(time.RFC3339, tx.SuccessTime); err == nil {
+			result.SuccessTime = successTime
+		}
+	}
+	return result
+}
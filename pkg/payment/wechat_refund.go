@@ -0,0 +1,187 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// 退款单状态，与微信支付退款接口返回的 status / refund_status 一致。
+const (
+	RefundStatusSuccess    = "SUCCESS"
+	RefundStatusClosed     = "CLOSED"
+	RefundStatusProcessing = "PROCESSING"
+	RefundStatusAbnormal   = "ABNORMAL"
+)
+
+// RefundRequest 描述一次退款申请，金额单位为分。
+// RefundID 为商户侧退款单号，重复提交同一单号不会产生重复退款。
+type RefundRequest struct {
+	OrderID  string
+	RefundID string
+	Amount   int64
+	Total    int64
+	Reason   string
+}
+
+// RefundResponse 汇总退款申请受理后的结果。
+type RefundResponse struct {
+	RefundID         string
+	ProviderRefundID string
+	Status           string
+	SuccessTime      time.Time
+}
+
+// RefundCallbackResult 汇总退款结果通知的核心字段，金额单位为分。
+type RefundCallbackResult struct {
+	NotificationID   string
+	EventType        string
+	OrderID          string
+	TransactionID    string
+	RefundID         string
+	ProviderRefundID string
+	RefundStatus     string
+	Amount           int64
+	SuccessTime      time.Time
+	Success          bool
+}
+
+type refundAmount struct {
+	Refund   int64  `json:"refund"`
+	Total    int64  `json:"total"`
+	Currency string `json:"currency"`
+}
+
+type refundCreateRequest struct {
+	OutTradeNo  string       `json:"out_trade_no"`
+	OutRefundNo string       `json:"out_refund_no"`
+	Reason      string       `json:"reason,omitempty"`
+	NotifyURL   string       `json:"notify_url,omitempty"`
+	Amount      refundAmount `json:"amount"`
+}
+
+type refundCreateResponse struct {
+	RefundID    string `json:"refund_id"`
+	OutRefundNo string `json:"out_refund_no"`
+	Status      string `json:"status"`
+	SuccessTime string `json:"success_time"`
+}
+
+// refundResource 是退款结果通知解密后的退款详情。
+type refundResource struct {
+	MchID         string `json:"mchid"`
+	OutTradeNo    string `json:"out_trade_no"`
+	TransactionID string `json:"transaction_id"`
+	OutRefundNo   string `json:"out_refund_no"`
+	RefundID      string `json:"refund_id"`
+	RefundStatus  string `json:"refund_status"`
+	SuccessTime   string `json:"success_time"`
+	Amount        struct {
+		Total       int64 `json:"total"`
+		Refund      int64 `json:"refund"`
+		PayerTotal  int64 `json:"payer_total"`
+		PayerRefund int64 `json:"payer_refund"`
+	} `json:"amount"`
+}
+
+// Refund 以商户订单号申请退款，支持对同一订单多次部分退款。
+func (c *weChatClient) Refund(ctx context.Context, request RefundRequest) (RefundResponse, error) {
+	if request.OrderID == "" || request.RefundID == "" {
+		return RefundResponse{}, errors.New("order id and refund id are required")
+	}
+	if request.Amount <= 0 || request.Amount > request.Total {
+		return RefundResponse{}, fmt.Errorf("refund amount %d must be positive and not exceed total %d", request.Amount, request.Total)
+	}
+
+	body := refundCreateRequest{
+		OutTradeNo:  request.OrderID,
+		OutRefundNo: request.RefundID,
+		Reason:      request.Reason,
+		NotifyURL:   c.cfg.RefundNotifyURL,
+		Amount:      refundAmount{Refund: request.Amount, Total: request.Total, Currency: "CNY"},
+	}
+
+	var resp refundCreateResponse
+	if err := c.do(ctx, http.MethodPost, "/v3/refund/domestic/refunds", body, &resp); err != nil {
+		return RefundResponse{}, err
+	}
+
+	result := resp.result(request.RefundID)
+	c.logger.Printf("wechat refund requested order_id=%s refund_id=%s amount=%d status=%s", request.OrderID, request.RefundID, request.Amount, result.Status)
+	return result, nil
+}
+
+// QueryRefund 按商户退款单号查询退款，退款单不存在时返回 ErrRefundNotFound。
+func (c *weChatClient) QueryRefund(ctx context.Context, orderID, refundID string) (RefundResponse, error) {
+	if refundID == "" {
+		return RefundResponse{}, errors.New("refund id is required")
+	}
+
+	var resp refundCreateResponse
+	if err := c.do(ctx, http.MethodGet, "/v3/refund/domestic/refunds/"+url.PathEscape(refundID), nil, &resp); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Code == "RESOURCE_NOT_EXISTS" {
+			return RefundResponse{}, ErrRefundNotFound
+		}
+		return RefundResponse{}, err
+	}
+	return resp.result(refundID), nil
+}
+
+// result 将退款接口的应答转换为 RefundResponse，应答缺少商户退款单号时沿用 refundID。
+func (resp refundCreateResponse) result(refundID string) RefundResponse {
+	result := RefundResponse{
+		RefundID:         resp.OutRefundNo,
+		ProviderRefundID: resp.RefundID,
+		Status:           resp.Status,
+	}
+	if result.RefundID == "" {
+		result.RefundID = refundID
+	}
+	if resp.SuccessTime != "" {
+		if successTime, err := time.Parse(time.RFC3339, resp.SuccessTime); err == nil {
+			result.SuccessTime = successTime
+		}
+	}
+	return result
+}
+
+// HandleRefundCallback 校验并解密退款结果通知，错误语义与 HandleCallback 一致。
+func (c *weChatClient) HandleRefundCallback(ctx context.Context, headers http.Header, payload []byte) (RefundCallbackResult, error) {
+	n, plaintext, err := c.openNotification(ctx, headers, payload)
+	if err != nil {
+		return RefundCallbackResult{}, err
+	}
+
+	var refund refundResource
+	if err := json.Unmarshal(plaintext, &refund); err != nil {
+		return RefundCallbackResult{}, fmt.Errorf("decode wechat refund: %w", err)
+	}
+	if refund.MchID != "" && refund.MchID != c.cfg.MchID {
+		return RefundCallbackResult{}, fmt.Errorf("wechat notification mchid %s does not match merchant", refund.MchID)
+	}
+
+	result := RefundCallbackResult{
+		NotificationID:   n.ID,
+		EventType:        n.EventType,
+		OrderID:          refund.OutTradeNo,
+		TransactionID:    refund.TransactionID,
+		RefundID:         refund.OutRefundNo,
+		ProviderRefundID: refund.RefundID,
+		RefundStatus:     refund.RefundStatus,
+		Amount:           refund.Amount.Refund,
+		Success:          refund.RefundStatus == RefundStatusSuccess,
+	}
+	if refund.SuccessTime != "" {
+		if successTime, err := time.Parse(time.RFC3339, refund.SuccessTime); err == nil {
+			result.SuccessTime = successTime
+		}
+	}
+
+	c.logger.Printf("wechat refund callback verified order_id=%s refund_id=%s status=%s", result.OrderID, result.RefundID, result.RefundStatus)
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package payment
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// authSchema 是微信支付 v3 接口要求的 Authorization 认证类型。
+const authSchema = "WECHATPAY2-SHA256-RSA2048"
+
+// LoadPrivateKey 读取商户 API 私钥（apiclient_key.pem），兼容 PKCS#8 与 PKCS#1 格式。
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read merchant private key: %w", err)
+	}
+	return ParsePrivateKey(data)
+}
+
+// ParsePrivateKey 解析 PEM 编码的 RSA 私钥。
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("merchant private key is not valid PEM")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("merchant private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse merchant private key: %w", err)
+	}
+	return key, nil
+}
+
+// signSHA256WithRSA 对消息做 SHA256-RSA 签名并以 Base64 输出。
+func signSHA256WithRSA(key *rsa.PrivateKey, message string) (string, error) {
+	digest := sha256.Sum256([]byte(message))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// buildMessage 按微信支付签名规范将各字段以换行符连接，末尾同样带换行符。
+func buildMessage(parts ...string) string {
+	return strings.Join(parts, "\n") + "\n"
+}
+
+// newNonce 生成 32 位十六进制随机串。
+func newNonce() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,541 @@
+package payment
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAppID          = "wx0123456789abcdef"
+	testMchID          = "1900000001"
+	testCertSerial     = "MERCHANTSERIAL"
+	testPlatformSerial = "PLATFORMSERIAL"
+	testAPIv3Key       = "0123456789abcdef0123456789abcdef"
+)
+
+// weChatFixture 持有一组测试用的商户与平台密钥。
+type weChatFixture struct {
+	merchantKey *rsa.PrivateKey
+	platformKey *rsa.PrivateKey
+}
+
+func newWeChatFixture(t *testing.T) weChatFixture {
+	t.Helper()
+	return weChatFixture{merchantKey: generateKey(t), platformKey: generateKey(t)}
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	return key
+}
+
+// memoryNonceStore 是测试用的进程内随机串存储。
+type memoryNonceStore struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	err     error
+}
+
+func newMemoryNonceStore() *memoryNonceStore {
+	return &memoryNonceStore{entries: make(map[string]time.Time)}
+}
+
+func (s *memoryNonceStore) Remember(_ context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if expireAt, seen := s.entries[nonce]; seen && time.Now().Before(expireAt) {
+		return false, nil
+	}
+	s.entries[nonce] = expiresAt
+	return true, nil
+}
+
+func (f weChatFixture) client(t *testing.T, baseURL string, httpClient *http.Client, extra ...Option) WeChatClient {
+	t.Helper()
+	cfg := Config{
+		AppID:        testAppID,
+		MchID:        testMchID,
+		NotifyURL:    "https://example.com/api/payments/wechat/callback",
+		CertSerialNo: testCertSerial,
+		APIv3Key:     testAPIv3Key,
+		BaseURL:      baseURL,
+	}
+	opts := []Option{
+		WithPrivateKey(f.merchantKey),
+		WithPlatformKey(testPlatformSerial, &f.platformKey.PublicKey),
+		WithNonceStore(newMemoryNonceStore()),
+	}
+	if httpClient != nil {
+		opts = append(opts, WithHTTPClient(httpClient))
+	}
+	opts = append(opts, extra...)
+	client, err := NewWeChatClient(cfg, log.New(io.Discard, "", 0), opts...)
+	if err != nil {
+		t.Fatalf("NewWeChatClient: %v", err)
+	}
+	return client
+}
+
+// notify 按微信支付的格式加密交易资源并以平台私钥签名，返回回调请求头与请求体。
+func (f weChatFixture) notify(t *testing.T, tx map[string]any, timestamp time.Time) (http.Header, []byte) {
+	t.Helper()
+	plaintext, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const nonce, associatedData = "0123456789ab", "transaction"
+	block, err := aes.NewCipher([]byte(testAPIv3Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := gcm.Seal(nil, []byte(nonce), plaintext, []byte(associatedData))
+
+	body, err := json.Marshal(map[string]any{
+		"id":            "EV-2018022511223320873",
+		"create_time":   timestamp.Format(time.RFC3339),
+		"event_type":    "TRANSACTION.SUCCESS",
+		"resource_type": "encrypt-resource",
+		"resource": map[string]string{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      base64.StdEncoding.EncodeToString(ciphertext),
+			"associated_data": associatedData,
+			"original_type":   "transaction",
+			"nonce":           nonce,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.sign(t, f.platformKey, body, timestamp), body
+}
+
+func (f weChatFixture) sign(t *testing.T, key *rsa.PrivateKey, body []byte, timestamp time.Time) http.Header {
+	t.Helper()
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	nonce, err := newNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := signSHA256WithRSA(key, buildMessage(ts, nonce, string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := http.Header{}
+	headers.Set(headerTimestamp, ts)
+	headers.Set(headerNonce, nonce)
+	headers.Set(headerSignature, signature)
+	headers.Set(headerSerial, testPlatformSerial)
+	return headers
+}
+
+func successTransaction() map[string]any {
+	return map[string]any{
+		"appid":          testAppID,
+		"mchid":          testMchID,
+		"out_trade_no":   "ord_123",
+		"transaction_id": "4200000000000000001",
+		"trade_type":     "JSAPI",
+		"trade_state":    TradeStateSuccess,
+		"success_time":   "2024-05-01T10:00:00+08:00",
+		"payer":          map[string]string{"openid": "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+		"amount":         map[string]any{"total": 1250, "payer_total": 1250, "currency": "CNY"},
+	}
+}
+
+// parseAuthorization 解析 v3 Authorization 请求头中的各字段。
+func parseAuthorization(t *testing.T, header string) map[string]string {
+	t.Helper()
+	schema, params, ok := strings.Cut(header, " ")
+	if !ok || schema != authSchema {
+		t.Fatalf("authorization schema = %q, want %s", header, authSchema)
+	}
+	fields := make(map[string]string)
+	for _, pair := range strings.Split(params, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			t.Fatalf("malformed authorization field %q", pair)
+		}
+		fields[key] = strings.Trim(value, `"`)
+	}
+	return fields
+}
+
+func verifySignature(t *testing.T, key *rsa.PublicKey, message, signature string) {
+	t.Helper()
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		t.Fatalf("decode signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], decoded); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+}
+
+func TestWeChatCreateOrderSignsRequestAndReturnsPayParams(t *testing.T) {
+	f := newWeChatFixture(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/pay/transactions/jsapi" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fields := parseAuthorization(t, r.Header.Get("Authorization"))
+		if fields["mchid"] != testMchID || fields["serial_no"] != testCertSerial {
+			t.Errorf("authorization mchid=%s serial_no=%s", fields["mchid"], fields["serial_no"])
+		}
+		verifySignature(t, &f.merchantKey.PublicKey,
+			buildMessage(r.Method, r.URL.RequestURI(), fields["timestamp"], fields["nonce_str"], string(body)), fields["signature"])
+
+		var req jsapiPrepayRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("decode prepay request: %v", err)
+		}
+		if req.AppID != testAppID || req.MchID != testMchID || req.OutTradeNo != "ord_123" ||
+			req.Amount.Total != 1250 || req.Amount.Currency != "CNY" || req.Payer.OpenID != "openid-1" {
+			t.Errorf("unexpected prepay request %+v", req)
+		}
+		if req.TimeExpire != "2024-05-01T10:15:00+08:00" {
+			t.Errorf("time_expire = %q", req.TimeExpire)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"prepay_id":"wx201410272009395522657a690389285100"}`)
+	}))
+	defer server.Close()
+
+	client := f.client(t, server.URL, server.Client())
+	expireAt := time.Date(2024, 5, 1, 10, 15, 0, 0, time.FixedZone("CST", 8*3600))
+	resp, err := client.CreateOrder(context.Background(), OrderRequest{
+		OrderID:     "ord_123",
+		Amount:      1250,
+		Subject:     "Convenience Store Order",
+		PayerOpenID: "openid-1",
+		ExpireAt:    expireAt,
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	if resp.PrepayID != "wx201410272009395522657a690389285100" || resp.Package != "prepay_id="+resp.PrepayID {
+		t.Errorf("unexpected prepay response %+v", resp)
+	}
+	if resp.AppID != testAppID || resp.SignType != "RSA" {
+		t.Errorf("appId=%s signType=%s", resp.AppID, resp.SignType)
+	}
+	verifySignature(t, &f.merchantKey.PublicKey, buildMessage(resp.AppID, resp.Timestamp, resp.NonceStr, resp.Package), resp.PaySign)
+}
+
+func TestWeChatCreateOrderReturnsAPIError(t *testing.T) {
+	f := newWeChatFixture(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"code":"PARAM_ERROR","message":"invalid openid"}`)
+	}))
+	defer server.Close()
+
+	client := f.client(t, server.URL, server.Client())
+	_, err := client.CreateOrder(context.Background(), OrderRequest{OrderID: "ord_123", Amount: 1, PayerOpenID: "openid-1"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreateOrder error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "PARAM_ERROR" {
+		t.Errorf("unexpected api error %+v", apiErr)
+	}
+}
+
+func TestWeChatQueryOrderMissingTradeIsNotPaid(t *testing.T) {
+	f := newWeChatFixture(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/pay/transactions/out-trade-no/ord_123" || r.URL.Query().Get("mchid") != testMchID {
+			t.Errorf("unexpected query %s", r.URL.RequestURI())
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"code":"ORDER_NOT_EXIST","message":"order not exist"}`)
+	}))
+	defer server.Close()
+
+	result, err := f.client(t, server.URL, server.Client()).QueryOrder(context.Background(), "ord_123")
+	if err != nil {
+		t.Fatalf("QueryOrder: %v", err)
+	}
+	if result.TradeState != TradeStateNotPay || result.Success {
+		t.Errorf("unexpected query result %+v", result)
+	}
+}
+
+func TestWeChatQueryRefund(t *testing.T) {
+	f := newWeChatFixture(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v3/refund/domestic/refunds/rf_1":
+			_, _ = io.WriteString(w, `{"refund_id":"50000000382019052709732678859","out_refund_no":"rf_1","status":"SUCCESS","success_time":"2024-05-01T10:20:00+08:00"}`)
+		case "/v3/refund/domestic/refunds/rf_missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"code":"RESOURCE_NOT_EXISTS","message":"refund not exist"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.RequestURI())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := f.client(t, server.URL, server.Client())
+
+	resp, err := client.QueryRefund(context.Background(), "ord_123", "rf_1")
+	if err != nil {
+		t.Fatalf("QueryRefund: %v", err)
+	}
+	if resp.Status != RefundStatusSuccess || resp.RefundID != "rf_1" || resp.ProviderRefundID != "50000000382019052709732678859" || resp.SuccessTime.IsZero() {
+		t.Errorf("unexpected refund %+v", resp)
+	}
+
+	if _, err := client.QueryRefund(context.Background(), "ord_123", "rf_missing"); !errors.Is(err, ErrRefundNotFound) {
+		t.Errorf("QueryRefund error = %v, want ErrRefundNotFound", err)
+	}
+}
+
+func TestAPIErrorRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		rejected bool
+	}{
+		{name: "business error", err: &APIError{StatusCode: http.StatusForbidden, Code: "NOT_ENOUGH"}, rejected: true},
+		{name: "invalid request", err: &APIError{StatusCode: http.StatusBadRequest, Code: "INVALID_REQUEST"}, rejected: true},
+		{name: "wrapped", err: fmt.Errorf("refund: %w", &APIError{StatusCode: http.StatusBadRequest, Code: "PARAM_ERROR"}), rejected: true},
+		{name: "system error", err: &APIError{StatusCode: http.StatusInternalServerError, Code: "SYSTEM_ERROR"}},
+		{name: "rate limited", err: &APIError{StatusCode: http.StatusTooManyRequests, Code: "FREQUENCY_LIMITED"}},
+		{name: "network error", err: errors.New("dial tcp: i/o timeout")},
+		{name: "nil", err: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Rejected(tt.err); got != tt.rejected {
+				t.Errorf("Rejected(%v) = %v, want %v", tt.err, got, tt.rejected)
+			}
+		})
+	}
+}
+
+func TestWeChatHandleCallbackDecryptsVerifiedNotification(t *testing.T) {
+	f := newWeChatFixture(t)
+	client := f.client(t, "", nil)
+
+	headers, body := f.notify(t, successTransaction(), time.Now())
+	result, err := client.HandleCallback(context.Background(), headers, body)
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+
+	if !result.Success || result.OrderID != "ord_123" || result.TransactionID != "4200000000000000001" {
+		t.Errorf("unexpected callback result %+v", result)
+	}
+	if result.Amount != 1250 || result.PayerOpenID != "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o" || result.EventType != "TRANSACTION.SUCCESS" {
+		t.Errorf("unexpected callback result %+v", result)
+	}
+	if want := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC); !result.SuccessTime.Equal(want) {
+		t.Errorf("success time = %s, want %s", result.SuccessTime, want)
+	}
+
+	// 原样重放的请求随机串重复，应被拒绝。
+	if _, err := client.HandleCallback(context.Background(), headers, body); !errors.Is(err, ErrReplayedNotification) {
+		t.Errorf("replayed notification error = %v, want %v", err, ErrReplayedNotification)
+	}
+
+	// 渠道重发时重新生成随机串并签名，内容相同的通知仍应通过校验，由入账流程负责幂等。
+	if _, err := client.HandleCallback(context.Background(), f.sign(t, f.platformKey, body, time.Now()), body); err != nil {
+		t.Errorf("redelivered notification rejected: %v", err)
+	}
+}
+
+func TestWeChatHandleCallbackRejectsReplayAcrossInstances(t *testing.T) {
+	f := newWeChatFixture(t)
+	store := newMemoryNonceStore()
+	first := f.client(t, "", nil, WithNonceStore(store))
+	second := f.client(t, "", nil, WithNonceStore(store))
+
+	headers, body := f.notify(t, successTransaction(), time.Now())
+	if _, err := first.HandleCallback(context.Background(), headers, body); err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if _, err := second.HandleCallback(context.Background(), headers, body); !errors.Is(err, ErrReplayedNotification) {
+		t.Errorf("notification replayed to another instance: error = %v, want %v", err, ErrReplayedNotification)
+	}
+}
+
+func TestWeChatHandleCallbackFailsWhenNonceStoreFails(t *testing.T) {
+	f := newWeChatFixture(t)
+	store := newMemoryNonceStore()
+	store.err = errors.New("database is down")
+	client := f.client(t, "", nil, WithNonceStore(store))
+
+	headers, body := f.notify(t, successTransaction(), time.Now())
+	_, err := client.HandleCallback(context.Background(), headers, body)
+	if err == nil || errors.Is(err, ErrReplayedNotification) || errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("HandleCallback error = %v, want a retryable storage error", err)
+	}
+}
+
+func TestWeChatHandleCallbackRejectsInvalidNotifications(t *testing.T) {
+	f := newWeChatFixture(t)
+	client := f.client(t, "", nil)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		prepare func() (http.Header, []byte)
+		wantErr error
+	}{
+		{
+			name: "signed by another key",
+			prepare: func() (http.Header, []byte) {
+				_, body := f.notify(t, successTransaction(), now)
+				return f.sign(t, generateKey(t), body, now), body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered body",
+			prepare: func() (http.Header, []byte) {
+				headers, body := f.notify(t, successTransaction(), now)
+				return headers, []byte(strings.Replace(string(body), "TRANSACTION.SUCCESS", "TRANSACTION.FAILED", 1))
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "unknown platform serial",
+			prepare: func() (http.Header, []byte) {
+				headers, body := f.notify(t, successTransaction(), now)
+				headers.Set(headerSerial, "UNKNOWN")
+				return headers, body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "missing signature",
+			prepare: func() (http.Header, []byte) {
+				headers, body := f.notify(t, successTransaction(), now)
+				headers.Del(headerSignature)
+				return headers, body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "stale timestamp",
+			prepare: func() (http.Header, []byte) {
+				return f.notify(t, successTransaction(), now.Add(-notificationMaxSkew-time.Minute))
+			},
+			wantErr: ErrReplayedNotification,
+		},
+		{
+			name: "future timestamp",
+			prepare: func() (http.Header, []byte) {
+				return f.notify(t, successTransaction(), now.Add(notificationMaxSkew+time.Minute))
+			},
+			wantErr: ErrReplayedNotification,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, body := tt.prepare()
+			_, err := client.HandleCallback(context.Background(), headers, body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("HandleCallback error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWeChatHandleCallbackRejectsUndecryptableResource(t *testing.T) {
+	f := newWeChatFixture(t)
+	client := f.client(t, "", nil)
+
+	_, body := f.notify(t, successTransaction(), time.Now())
+	var n map[string]any
+	if err := json.Unmarshal(body, &n); err != nil {
+		t.Fatal(err)
+	}
+	n["resource"].(map[string]any)["associated_data"] = "refund"
+	body, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := f.sign(t, f.platformKey, body, time.Now())
+
+	if _, err := client.HandleCallback(context.Background(), headers, body); err == nil || !strings.Contains(err.Error(), "decrypt notification resource") {
+		t.Fatalf("HandleCallback error = %v, want decryption failure", err)
+	}
+}
+
+func TestWeChatHandleCallbackRejectsForeignMerchant(t *testing.T) {
+	f := newWeChatFixture(t)
+	client := f.client(t, "", nil)
+
+	tx := successTransaction()
+	tx["mchid"] = "1900000099"
+	headers, body := f.notify(t, tx, time.Now())
+	if _, err := client.HandleCallback(context.Background(), headers, body); err == nil {
+		t.Fatal("HandleCallback accepted a notification for another merchant")
+	}
+}
+
+func TestNewWeChatClientRequiresCredentials(t *testing.T) {
+	f := newWeChatFixture(t)
+	logger := log.New(io.Discard, "", 0)
+	store := newMemoryNonceStore()
+
+	base := Config{AppID: testAppID, MchID: testMchID, CertSerialNo: testCertSerial, APIv3Key: testAPIv3Key}
+	tests := []struct {
+		name string
+		cfg  func(Config) Config
+		opts []Option
+	}{
+		{name: "missing mch_id", cfg: func(c Config) Config { c.MchID = ""; return c }, opts: []Option{WithPrivateKey(f.merchantKey), WithPlatformKey(testPlatformSerial, &f.platformKey.PublicKey), WithNonceStore(store)}},
+		{name: "short api_v3_key", cfg: func(c Config) Config { c.APIv3Key = "short"; return c }, opts: []Option{WithPrivateKey(f.merchantKey), WithPlatformKey(testPlatformSerial, &f.platformKey.PublicKey), WithNonceStore(store)}},
+		{name: "missing private key", cfg: func(c Config) Config { return c }, opts: []Option{WithPlatformKey(testPlatformSerial, &f.platformKey.PublicKey), WithNonceStore(store)}},
+		{name: "missing platform key", cfg: func(c Config) Config { return c }, opts: []Option{WithPrivateKey(f.merchantKey), WithNonceStore(store)}},
+		{name: "missing nonce store", cfg: func(c Config) Config { return c }, opts: []Option{WithPrivateKey(f.merchantKey), WithPlatformKey(testPlatformSerial, &f.platformKey.PublicKey)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWeChatClient(tt.cfg(base), logger, tt.opts...); err == nil {
+				t.Fatal("NewWeChatClient succeeded with incomplete credentials")
+			}
+		})
+	}
+}
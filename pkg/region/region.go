@@ -0,0 +1,180 @@
+package region
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// embeddedData 是随程序发布的行政区划数据，按 2023 年版统计用区划代码收录全部省、地、县三级。
+// 东莞、中山、儋州、嘉峪关及省直辖县级行政区不设区县，作为无下级的地级节点收录。
+//
+//go:embed regions.json
+var embeddedData []byte
+
+// minPrefixRunes 为按名称前缀匹配时要求的最少字符数，避免“南”之类的输入误匹配。
+const minPrefixRunes = 2
+
+// Config 描述行政区划数据来源。
+type Config struct {
+	// DataFile 为外部行政区划 JSON 文件路径（格式同内置 regions.json），用于在区划调整后加载更新的数据；为空时使用内置数据。
+	DataFile string `mapstructure:"data_file"`
+}
+
+// Region 表示一个行政区划节点，Code 为国家统计局六位区划代码。
+type Region struct {
+	Code     string    `json:"code"`
+	Name     string    `json:"name"`
+	Children []*Region `json:"children,omitempty"`
+}
+
+// HasChildren 报告数据集中是否收录了下级区划。
+func (r *Region) HasChildren() bool {
+	return len(r.Children) > 0
+}
+
+// Dataset 是加载后的只读行政区划数据，可在多个 goroutine 间共享。
+type Dataset struct {
+	provinces []*Region
+	byCode    map[string]*Region
+}
+
+// New 根据配置加载行政区划数据。
+func New(cfg Config) (*Dataset, error) {
+	data := embeddedData
+	if cfg.DataFile != "" {
+		raw, err := os.ReadFile(cfg.DataFile)
+		if err != nil {
+			return nil, fmt.Errorf("read region data file: %w", err)
+		}
+		data = raw
+	}
+	return Parse(data)
+}
+
+// Parse 解析 JSON 格式的行政区划树并校验代码唯一。
+func Parse(data []byte) (*Dataset, error) {
+	var provinces []*Region
+	if err := json.Unmarshal(data, &provinces); err != nil {
+		return nil, fmt.Errorf("parse region data: %w", err)
+	}
+	if len(provinces) == 0 {
+		return nil, errors.New("region data is empty")
+	}
+
+	d := &Dataset{provinces: provinces, byCode: make(map[string]*Region)}
+	var index func(nodes []*Region) error
+	index = func(nodes []*Region) error {
+		for _, node := range nodes {
+			if node.Code == "" || node.Name == "" {
+				return fmt.Errorf("region data has node without code or name: %+v", *node)
+			}
+			if _, ok := d.byCode[node.Code]; ok {
+				return fmt.Errorf("region data has duplicate code %s", node.Code)
+			}
+			d.byCode[node.Code] = node
+			if err := index(node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := index(provinces); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Provinces 返回全部省级行政区。
+func (d *Dataset) Provinces() []*Region {
+	return d.provinces
+}
+
+// Lookup 按区划代码查找节点。
+func (d *Dataset) Lookup(code string) (*Region, bool) {
+	r, ok := d.byCode[code]
+	return r, ok
+}
+
+// Children 返回指定代码的下级区划，parentCode 为空时返回省级行政区。
+func (d *Dataset) Children(parentCode string) ([]*Region, bool) {
+	if parentCode == "" {
+		return d.provinces, true
+	}
+	r, ok := d.byCode[parentCode]
+	if !ok {
+		return nil, false
+	}
+	return r.Children, true
+}
+
+// Match 在同级区划中查找与输入对应的节点：依次尝试代码、完整名称，
+// 以及唯一的名称前缀（如“广东”匹配“广东省”，“恩施”匹配“恩施土家族苗族自治州”）。
+func Match(nodes []*Region, input string) (*Region, bool) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, false
+	}
+	for _, node := range nodes {
+		if node.Code == input || node.Name == input {
+			return node, true
+		}
+	}
+	if utf8.RuneCountInString(input) < minPrefixRunes {
+		return nil, false
+	}
+	var found *Region
+	for _, node := range nodes {
+		if strings.HasPrefix(node.Name, input) {
+			if found != nil {
+				return nil, false
+			}
+			found = node
+		}
+	}
+	return found, found != nil
+}
+
+// Resolved 是规范化后的省市区名称与代码，城市不设区县时 DistrictCode 为空。
+type Resolved struct {
+	Province     string
+	ProvinceCode string
+	City         string
+	CityCode     string
+	District     string
+	DistrictCode string
+}
+
+// Resolve 将用户输入的省、市、区县校验并规范化为数据集中的标准名称与代码。
+// 输入可以是代码、完整名称或唯一前缀。城市下设区县时必须匹配其中之一；
+// 仅东莞等不设区县的城市将 district 视为镇街，校验非空后原样保留。
+func (d *Dataset) Resolve(province, city, district string) (Resolved, error) {
+	p, ok := Match(d.provinces, province)
+	if !ok {
+		return Resolved{}, fmt.Errorf("unknown province %q", province)
+	}
+	c, ok := Match(p.Children, city)
+	if !ok {
+		return Resolved{}, fmt.Errorf("unknown city %q in %s", city, p.Name)
+	}
+
+	resolved := Resolved{Province: p.Name, ProvinceCode: p.Code, City: c.Name, CityCode: c.Code}
+	if !c.HasChildren() {
+		resolved.District = strings.TrimSpace(district)
+		if resolved.District == "" {
+			return Resolved{}, errors.New("district is required")
+		}
+		return resolved, nil
+	}
+	dist, ok := Match(c.Children, district)
+	if !ok {
+		return Resolved{}, fmt.Errorf("unknown district %q in %s%s", district, p.Name, c.Name)
+	}
+	resolved.District = dist.Name
+	resolved.DistrictCode = dist.Code
+	return resolved, nil
+}
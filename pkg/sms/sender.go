@@ -0,0 +1,52 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ProviderLog 仅将短信内容写入日志，不实际发送，用于开发与测试环境。
+const ProviderLog = "log"
+
+// Config 描述短信通道配置。
+type Config struct {
+	// Provider 为短信服务商，目前仅支持 log。
+	Provider string `mapstructure:"provider"`
+}
+
+// Sender 抽象短信发送通道，便于接入不同服务商。
+type Sender interface {
+	// Send 向手机号发送一条短信，返回 nil 仅表示服务商已受理。
+	Send(ctx context.Context, phone, content string) error
+}
+
+// New 根据配置创建短信通道，未配置服务商时使用日志通道。
+func New(cfg Config, logger *log.Logger) (Sender, error) {
+	switch cfg.Provider {
+	case "", ProviderLog:
+		return NewLogSender(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown sms provider %q", cfg.Provider)
+	}
+}
+
+// LogSender 将短信写入日志而不实际发送。
+type LogSender struct {
+	logger *log.Logger
+}
+
+// NewLogSender 创建日志短信通道。
+func NewLogSender(logger *log.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+// Send 记录短信内容。
+func (s *LogSender) Send(ctx context.Context, phone, content string) error {
+	if phone == "" {
+		return errors.New("sms phone is required")
+	}
+	s.logger.Printf("sms (log provider) to=%s content=%q", phone, content)
+	return nil
+}
@@ -0,0 +1,165 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// 接口调用凭证相关的错误码，出现时需重新获取 access_token。
+const (
+	// ErrCodeInvalidToken 表示 access_token 无效，例如已在其他服务中被刷新。
+	ErrCodeInvalidToken = 40001
+	// ErrCodeTokenExpired 表示 access_token 已过期。
+	ErrCodeTokenExpired = 42001
+)
+
+// accessTokenRefreshMargin 使缓存的 access_token 在到期前提前刷新，避免请求途中失效。
+const accessTokenRefreshMargin = 5 * time.Minute
+
+// tokenCache 缓存接口调用凭证，多个请求共享同一个 access_token。
+type tokenCache struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// accessToken 返回缓存的 access_token，即将过期或已被作废时重新获取。
+// 获取期间持有锁，并发请求只会触发一次刷新。
+func (c *client) accessToken(ctx context.Context) (string, error) {
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+
+	now := c.now()
+	if c.tokens.token != "" && now.Add(accessTokenRefreshMargin).Before(c.tokens.expiresAt) {
+		return c.tokens.token, nil
+	}
+
+	query := url.Values{
+		"grant_type": {"client_credential"},
+		"appid":      {c.cfg.AppID},
+		"secret":     {c.cfg.AppSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/cgi-bin/token?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		APIError
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := c.send(req, &result); err != nil {
+		return "", fmt.Errorf("wechat access token: %w", err)
+	}
+	if result.ErrCode != 0 {
+		c.logger.Printf("wechat access token failed errcode=%d errmsg=%s", result.ErrCode, result.ErrMsg)
+		apiErr := result.APIError
+		return "", &apiErr
+	}
+	if result.AccessToken == "" || result.ExpiresIn <= 0 {
+		return "", errors.New("wechat returned empty access_token")
+	}
+
+	c.tokens.token = result.AccessToken
+	c.tokens.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return c.tokens.token, nil
+}
+
+// invalidateToken 作废指定的 access_token，已被其他请求刷新时不做处理。
+func (c *client) invalidateToken(token string) {
+	c.tokens.mu.Lock()
+	defer c.tokens.mu.Unlock()
+	if c.tokens.token == token {
+		c.tokens.token = ""
+	}
+}
+
+// tokenRejected 判断错误是否由 access_token 失效引起。
+func tokenRejected(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrCode == ErrCodeInvalidToken || apiErr.ErrCode == ErrCodeTokenExpired)
+}
+
+// GetPhoneNumber 用 getPhoneNumber 返回的动态令牌换取手机号，access_token 失效时刷新后重试一次。
+func (c *client) GetPhoneNumber(ctx context.Context, code string) (PhoneNumber, error) {
+	if code == "" {
+		return PhoneNumber{}, errors.New("wechat phone code is required")
+	}
+
+	phone, token, err := c.getPhoneNumber(ctx, code)
+	if tokenRejected(err) {
+		c.invalidateToken(token)
+		phone, _, err = c.getPhoneNumber(ctx, code)
+	}
+	if err != nil {
+		return PhoneNumber{}, err
+	}
+	if phone.Watermark.AppID != c.cfg.AppID {
+		return PhoneNumber{}, fmt.Errorf("wechat phone watermark appid %q does not match", phone.Watermark.AppID)
+	}
+	if phone.PurePhoneNumber == "" {
+		return PhoneNumber{}, errors.New("wechat returned no phone number")
+	}
+	return phone, nil
+}
+
+func (c *client) getPhoneNumber(ctx context.Context, code string) (PhoneNumber, string, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return PhoneNumber{}, "", err
+	}
+
+	payload, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		return PhoneNumber{}, token, err
+	}
+	endpoint := c.baseURL + "/wxa/business/getuserphonenumber?" + url.Values{"access_token": {token}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return PhoneNumber{}, token, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		APIError
+		PhoneInfo PhoneNumber `json:"phone_info"`
+	}
+	if err := c.send(req, &result); err != nil {
+		return PhoneNumber{}, token, fmt.Errorf("wechat getuserphonenumber: %w", err)
+	}
+	if result.ErrCode != 0 {
+		c.logger.Printf("wechat getuserphonenumber failed errcode=%d errmsg=%s", result.ErrCode, result.ErrMsg)
+		apiErr := result.APIError
+		return PhoneNumber{}, token, &apiErr
+	}
+	return result.PhoneInfo, token, nil
+}
+
+// send 发送请求并将 200 响应体解码到 out，业务错误由调用方按 errcode 判断。
+func (c *client) send(req *http.Request, out any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
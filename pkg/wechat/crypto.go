@@ -0,0 +1,74 @@
+package wechat
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrDecryptFailed 表示小程序加密数据无法用当前 session_key 解密，通常是 session_key 已过期需重新登录。
+var ErrDecryptFailed = errors.New("wechat encrypted data decrypt failed")
+
+// PhoneNumber 是 getPhoneNumber 返回的手机号信息。
+type PhoneNumber struct {
+	// PhoneNumber 为带区号的手机号，境外号码含国家码前缀。
+	PhoneNumber string `json:"phoneNumber"`
+	// PurePhoneNumber 为不带区号的手机号。
+	PurePhoneNumber string `json:"purePhoneNumber"`
+	CountryCode     string `json:"countryCode"`
+	Watermark       struct {
+		AppID     string `json:"appid"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"watermark"`
+}
+
+func (c *client) DecryptPhoneNumber(sessionKey, encryptedData, iv string) (PhoneNumber, error) {
+	var phone PhoneNumber
+	if err := decryptData(sessionKey, encryptedData, iv, &phone); err != nil {
+		return PhoneNumber{}, err
+	}
+	// 校验水印，防止使用其他小程序的加密数据。
+	if phone.Watermark.AppID != c.cfg.AppID {
+		return PhoneNumber{}, fmt.Errorf("wechat encrypted data watermark appid %q does not match", phone.Watermark.AppID)
+	}
+	if phone.PurePhoneNumber == "" {
+		return PhoneNumber{}, errors.New("wechat encrypted data has no phone number")
+	}
+	return phone, nil
+}
+
+// decryptData 按小程序开放数据规范以 AES-128-CBC（PKCS#7 填充）解密，密钥与向量均为 base64 编码。
+func decryptData(sessionKey, encryptedData, iv string, v any) error {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil || len(key) != aes.BlockSize {
+		return ErrDecryptFailed
+	}
+	nonce, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil || len(nonce) != aes.BlockSize {
+		return ErrDecryptFailed
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return ErrDecryptFailed
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return ErrDecryptFailed
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, nonce).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return ErrDecryptFailed
+	}
+	if err := json.Unmarshal(plaintext[:len(plaintext)-padding], v); err != nil {
+		return ErrDecryptFailed
+	}
+	return nil
+}
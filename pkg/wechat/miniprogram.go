@@ -0,0 +1,161 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL 是微信开放接口的正式地址。
+const DefaultBaseURL = "https://api.weixin.qq.com"
+
+// 小程序登录接口常见的错误码。
+const (
+	// ErrCodeInvalidCode 表示 js_code 无效或已过期。
+	ErrCodeInvalidCode = 40029
+	// ErrCodeCodeUsed 表示 js_code 已被使用。
+	ErrCodeCodeUsed = 40163
+	// ErrCodeRateLimited 表示调用过于频繁。
+	ErrCodeRateLimited = 45011
+)
+
+// Config 描述小程序的身份凭据。
+type Config struct {
+	AppID     string `mapstructure:"app_id"`
+	AppSecret string `mapstructure:"app_secret"`
+	// BaseURL 为开放接口地址，留空使用正式环境，联调与测试时可指向本地替身服务。
+	BaseURL string `mapstructure:"base_url"`
+}
+
+// Session 是 code2session 返回的登录态。SessionKey 仅用于解密小程序加密数据，不得下发给客户端。
+type Session struct {
+	OpenID     string
+	UnionID    string
+	SessionKey string
+}
+
+// APIError 表示微信开放接口返回的业务错误。
+type APIError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wechat api error errcode=%d errmsg=%s", e.ErrCode, e.ErrMsg)
+}
+
+// InvalidCode 判断错误是否由客户端提交的登录凭证无效引起。
+func InvalidCode(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrCode == ErrCodeInvalidCode || apiErr.ErrCode == ErrCodeCodeUsed)
+}
+
+// Client 抽象小程序服务端接口。
+type Client interface {
+	// Code2Session 用 wx.login 获得的临时登录凭证换取 openid、unionid 与 session_key。
+	Code2Session(ctx context.Context, code string) (Session, error)
+	// DecryptPhoneNumber 用 session_key 解密 getPhoneNumber 返回的 encryptedData 并校验水印。
+	DecryptPhoneNumber(sessionKey, encryptedData, iv string) (PhoneNumber, error)
+	// GetPhoneNumber 用新版 getPhoneNumber 返回的 code 换取手机号，无需 session_key。
+	GetPhoneNumber(ctx context.Context, code string) (PhoneNumber, error)
+}
+
+// Option 用于定制客户端，便于测试时替换网络。
+type Option func(*client)
+
+// WithHTTPClient 替换默认的 HTTP 客户端。
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *client) {
+		c.httpClient = httpClient
+	}
+}
+
+type client struct {
+	cfg        Config
+	logger     *log.Logger
+	httpClient *http.Client
+	baseURL    string
+	tokens     tokenCache
+	now        func() time.Time
+}
+
+// NewClient 创建小程序服务端接口客户端。
+func NewClient(cfg Config, logger *log.Logger, opts ...Option) (Client, error) {
+	if cfg.AppID == "" || cfg.AppSecret == "" {
+		return nil, errors.New("wechat app_id and app_secret are required")
+	}
+
+	c := &client{
+		cfg:        cfg,
+		logger:     logger,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		now:        time.Now,
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+func (c *client) Code2Session(ctx context.Context, code string) (Session, error) {
+	if code == "" {
+		return Session{}, errors.New("wechat login code is required")
+	}
+
+	query := url.Values{
+		"appid":      {c.cfg.AppID},
+		"secret":     {c.cfg.AppSecret},
+		"js_code":    {code},
+		"grant_type": {"authorization_code"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/sns/jscode2session?"+query.Encode(), nil)
+	if err != nil {
+		return Session{}, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Session{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Session{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Session{}, fmt.Errorf("wechat jscode2session returned status %d", resp.StatusCode)
+	}
+
+	// 接口出错时同样返回 200，以 errcode 区分。
+	var result struct {
+		APIError
+		OpenID     string `json:"openid"`
+		UnionID    string `json:"unionid"`
+		SessionKey string `json:"session_key"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return Session{}, fmt.Errorf("decode jscode2session response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		c.logger.Printf("wechat jscode2session failed errcode=%d errmsg=%s", result.ErrCode, result.ErrMsg)
+		apiErr := result.APIError
+		return Session{}, &apiErr
+	}
+	if result.OpenID == "" || result.SessionKey == "" {
+		return Session{}, errors.New("wechat jscode2session returned empty openid or session_key")
+	}
+
+	return Session{OpenID: result.OpenID, UnionID: result.UnionID, SessionKey: result.SessionKey}, nil
+}
@@ -0,0 +1,257 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAppID     = "wx0123456789abcdef"
+	testAppSecret = "secret-0123456789"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) *client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(Config{AppID: testAppID, AppSecret: testAppSecret, BaseURL: server.URL + "/"}, log.New(io.Discard, "", 0), WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c.(*client)
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("encode response: %v", err)
+	}
+}
+
+func TestCode2SessionExchangesCode(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sns/jscode2session" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("appid") != testAppID || q.Get("secret") != testAppSecret || q.Get("js_code") != "code-1" || q.Get("grant_type") != "authorization_code" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		writeJSON(t, w, map[string]string{"openid": "openid-1", "unionid": "unionid-1", "session_key": "c2Vzc2lvbi1rZXk="})
+	}))
+
+	session, err := c.Code2Session(context.Background(), "code-1")
+	if err != nil {
+		t.Fatalf("Code2Session: %v", err)
+	}
+	if session.OpenID != "openid-1" || session.UnionID != "unionid-1" || session.SessionKey != "c2Vzc2lvbi1rZXk=" {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
+
+func TestCode2SessionMapsErrorCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		errCode     int
+		invalidCode bool
+	}{
+		{name: "invalid code", errCode: ErrCodeInvalidCode, invalidCode: true},
+		{name: "code used", errCode: ErrCodeCodeUsed, invalidCode: true},
+		{name: "rate limited", errCode: ErrCodeRateLimited},
+		{name: "system busy", errCode: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// 接口出错时同样返回 200。
+				writeJSON(t, w, map[string]any{"errcode": tt.errCode, "errmsg": tt.name})
+			}))
+
+			_, err := c.Code2Session(context.Background(), "code-1")
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.ErrCode != tt.errCode {
+				t.Fatalf("Code2Session error = %v, want errcode %d", err, tt.errCode)
+			}
+			if got := InvalidCode(err); got != tt.invalidCode {
+				t.Errorf("InvalidCode = %v, want %v", got, tt.invalidCode)
+			}
+		})
+	}
+}
+
+func TestCode2SessionRejectsIncompleteResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "http error", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}},
+		{name: "malformed body", handler: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "<html>")
+		}},
+		{name: "missing session key", handler: func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(t, w, map[string]string{"openid": "openid-1"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, tt.handler)
+			if _, err := c.Code2Session(context.Background(), "code-1"); err == nil {
+				t.Fatal("Code2Session succeeded")
+			}
+		})
+	}
+}
+
+// phoneServer 模拟 access_token 与手机号接口，记录获取凭证的次数。
+type phoneServer struct {
+	t *testing.T
+
+	mu          sync.Mutex
+	tokenCalls  int
+	validToken  string
+	phoneTokens []string
+}
+
+func (s *phoneServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/cgi-bin/token":
+		q := r.URL.Query()
+		if q.Get("grant_type") != "client_credential" || q.Get("appid") != testAppID || q.Get("secret") != testAppSecret {
+			s.t.Errorf("unexpected token query %s", r.URL.RawQuery)
+		}
+		s.tokenCalls++
+		s.validToken = "token-" + string(rune('0'+s.tokenCalls))
+		writeJSON(s.t, w, map[string]any{"access_token": s.validToken, "expires_in": 7200})
+	case "/wxa/business/getuserphonenumber":
+		token := r.URL.Query().Get("access_token")
+		s.phoneTokens = append(s.phoneTokens, token)
+		if token != s.validToken {
+			writeJSON(s.t, w, map[string]any{"errcode": ErrCodeInvalidToken, "errmsg": "invalid credential"})
+			return
+		}
+		var body struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Code != "phone-code" {
+			writeJSON(s.t, w, map[string]any{"errcode": ErrCodeInvalidCode, "errmsg": "invalid code"})
+			return
+		}
+		writeJSON(s.t, w, map[string]any{
+			"errcode": 0,
+			"errmsg":  "ok",
+			"phone_info": map[string]any{
+				"phoneNumber":     "13800138000",
+				"purePhoneNumber": "13800138000",
+				"countryCode":     "86",
+				"watermark":       map[string]any{"appid": testAppID, "timestamp": 1714550400},
+			},
+		})
+	default:
+		s.t.Errorf("unexpected path %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGetPhoneNumberCachesAccessToken(t *testing.T) {
+	server := &phoneServer{t: t}
+	c := newTestClient(t, server)
+
+	for i := 0; i < 3; i++ {
+		phone, err := c.GetPhoneNumber(context.Background(), "phone-code")
+		if err != nil {
+			t.Fatalf("GetPhoneNumber: %v", err)
+		}
+		if phone.PurePhoneNumber != "13800138000" || phone.CountryCode != "86" {
+			t.Errorf("unexpected phone %+v", phone)
+		}
+	}
+	if server.tokenCalls != 1 {
+		t.Errorf("access token fetched %d times, want 1", server.tokenCalls)
+	}
+}
+
+func TestAccessTokenRefreshesBeforeExpiry(t *testing.T) {
+	server := &phoneServer{t: t}
+	c := newTestClient(t, server)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	first, err := c.accessToken(context.Background())
+	if err != nil {
+		t.Fatalf("accessToken: %v", err)
+	}
+
+	now = now.Add(2*time.Hour - accessTokenRefreshMargin - time.Second)
+	if token, _ := c.accessToken(context.Background()); token != first {
+		t.Errorf("token refreshed too early: %s", token)
+	}
+
+	now = now.Add(time.Second)
+	second, err := c.accessToken(context.Background())
+	if err != nil {
+		t.Fatalf("accessToken: %v", err)
+	}
+	if second == first || server.tokenCalls != 2 {
+		t.Errorf("token not refreshed near expiry: first=%s second=%s calls=%d", first, second, server.tokenCalls)
+	}
+}
+
+func TestGetPhoneNumberRetriesWithFreshTokenWhenRejected(t *testing.T) {
+	server := &phoneServer{t: t}
+	c := newTestClient(t, server)
+
+	if _, err := c.GetPhoneNumber(context.Background(), "phone-code"); err != nil {
+		t.Fatalf("GetPhoneNumber: %v", err)
+	}
+	// 凭证在其他服务中被刷新，缓存的 access_token 随之失效。
+	server.validToken = "rotated-elsewhere"
+
+	if _, err := c.GetPhoneNumber(context.Background(), "phone-code"); err != nil {
+		t.Fatalf("GetPhoneNumber after rotation: %v", err)
+	}
+	if server.tokenCalls != 2 {
+		t.Errorf("access token fetched %d times, want 2", server.tokenCalls)
+	}
+	if got := len(server.phoneTokens); got != 3 {
+		t.Errorf("phone endpoint called %d times, want 3", got)
+	}
+}
+
+func TestGetPhoneNumberMapsInvalidCode(t *testing.T) {
+	c := newTestClient(t, &phoneServer{t: t})
+
+	_, err := c.GetPhoneNumber(context.Background(), "stale-code")
+	if !InvalidCode(err) {
+		t.Fatalf("GetPhoneNumber error = %v, want invalid code", err)
+	}
+}
+
+func TestAccessTokenErrorIsReturned(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{"errcode": 40125, "errmsg": "invalid appsecret"})
+	}))
+
+	_, err := c.GetPhoneNumber(context.Background(), "phone-code")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrCode != 40125 {
+		t.Fatalf("GetPhoneNumber error = %v, want errcode 40125", err)
+	}
+	if c.tokens.token != "" {
+		t.Errorf("failed token request was cached: %q", c.tokens.token)
+	}
+}
@@ -1,21 +1,35 @@
 package routes
 
 import (
+	"net/http"
+
 	"convenienceStore/internal/handler"
+	"convenienceStore/internal/model"
+	"convenienceStore/pkg/payment"
 
 	"github.com/gin-gonic/gin"
 )
 
 // HandlerSet 汇总应用所需的全量 HTTP 处理器。
 type HandlerSet struct {
-	User         *handler.UserHandler
-	Product      *handler.ProductHandler
-	AdminProduct *handler.AdminProductHandler
-	Upload       *handler.UploadHandler
-	Cart         *handler.CartHandler
-	Order        *handler.OrderHandler
-	Payment      *handler.PaymentHandler
-	Delivery     *handler.DeliveryHandler
+	Auth           *handler.AuthHandler
+	User           *handler.UserHandler
+	Account        *handler.AccountHandler
+	Phone          *handler.PhoneHandler
+	Loyalty        *handler.LoyaltyHandler
+	Region         *handler.RegionHandler
+	Staff          *handler.StaffHandler
+	Product        *handler.ProductHandler
+	AdminProduct   *handler.AdminProductHandler
+	Upload         *handler.UploadHandler
+	Cart           *handler.CartHandler
+	Order          *handler.OrderHandler
+	AdminOrder     *handler.AdminOrderHandler
+	Payment        *handler.PaymentHandler
+	AdminPayment   *handler.AdminPaymentHandler
+	Refund         *handler.RefundHandler
+	Delivery       *handler.DeliveryHandler
+	Reconciliation *handler.AdminReconciliationHandler
 }
 
 // RegisterRoutes 将各领域的路由绑定到对应处理器。
@@ -24,11 +38,26 @@ func RegisterRoutes(engine *gin.Engine, handlers HandlerSet) {
 
 	userGroup := api.Group("/users")
 	userGroup.POST("/wechat/login", handlers.User.WeChatLogin)
-	userGroup.POST("/bind", handlers.User.BindUser)
-	userGroup.GET("/addresses", handlers.User.ListAddresses)
-	userGroup.POST("/addresses", handlers.User.CreateAddress)
-	userGroup.PUT("/addresses/:id", handlers.User.UpdateAddress)
-	userGroup.DELETE("/addresses/:id", handlers.User.DeleteAddress)
+	userGroup.POST("/token/refresh", handlers.Auth.RefreshToken)
+	userGroup.GET("/member-tiers", handlers.Loyalty.ListTiers)
+
+	// 以下顾客接口均需携带访问令牌，用户身份取自令牌而非请求参数。
+	accountGroup := userGroup.Group("", handlers.Auth.RequireUser)
+	accountGroup.POST("/bind", handlers.User.BindUser)
+	accountGroup.POST("/phone/code", handlers.Phone.SendCode)
+	accountGroup.POST("/phone/bind", handlers.Phone.BindPhone)
+	accountGroup.POST("/phone/wechat", handlers.Phone.BindWeChatPhone)
+	accountGroup.GET("/me/export", handlers.Account.ExportData)
+	accountGroup.DELETE("/me", handlers.Account.DeleteAccount)
+	accountGroup.GET("/me/points", handlers.Loyalty.GetAccount)
+	accountGroup.GET("/me/points/ledger", handlers.Loyalty.ListLedger)
+	accountGroup.GET("/addresses", handlers.User.ListAddresses)
+	accountGroup.POST("/addresses", handlers.User.CreateAddress)
+	accountGroup.PUT("/addresses/:id", handlers.User.UpdateAddress)
+	accountGroup.DELETE("/addresses/:id", handlers.User.DeleteAddress)
+
+	// 行政区划为公开数据，供小程序级联选择省市区。
+	api.GET("/regions", handlers.Region.ListRegions)
 
 	productGroup := api.Group("/products")
 	productGroup.GET("", handlers.Product.ListProducts)
@@ -36,34 +65,90 @@ func RegisterRoutes(engine *gin.Engine, handlers HandlerSet) {
 	productGroup.POST(":id/validate", handlers.Product.ValidateInventory)
 
 	adminGroup := api.Group("/admin")
-	adminProducts := adminGroup.Group("/products")
-	adminProducts.GET("", handlers.AdminProduct.ListProducts)
-	adminProducts.GET("/:id", handlers.AdminProduct.GetProduct)
-	adminProducts.POST("", handlers.AdminProduct.CreateProduct)
-	adminProducts.PUT("/:id", handlers.AdminProduct.UpdateProduct)
-	adminProducts.DELETE("/:id", handlers.AdminProduct.DeleteProduct)
-	adminProducts.PATCH("/:id/status", handlers.AdminProduct.SetProductStatus)
+	adminGroup.POST("/auth/login", handlers.Staff.Login)
+	adminGroup.POST("/auth/refresh", handlers.Staff.RefreshToken)
+
+	// 后台与履约接口仅对员工开放，每个路由声明所需权限。
+	staffGroup := adminGroup.Group("", handlers.Staff.RequireStaff)
+	can := handlers.Staff.RequirePermission
+	staffGroup.GET("/me", handlers.Staff.Me)
+
+	adminProducts := staffGroup.Group("/products")
+	adminProducts.GET("", can(model.PermissionProductsRead), handlers.AdminProduct.ListProducts)
+	adminProducts.GET("/:id", can(model.PermissionProductsRead), handlers.AdminProduct.GetProduct)
+	adminProducts.POST("", can(model.PermissionProductsWrite), handlers.AdminProduct.CreateProduct)
+	adminProducts.PUT("/:id", can(model.PermissionProductsWrite), handlers.AdminProduct.UpdateProduct)
+	adminProducts.DELETE("/:id", can(model.PermissionProductsWrite), handlers.AdminProduct.DeleteProduct)
+	adminProducts.PATCH("/:id/status", can(model.PermissionProductsWrite), handlers.AdminProduct.SetProductStatus)
+
+	staffGroup.POST("/uploads", can(model.PermissionUploadsWrite), handlers.Upload.UploadFile)
 
-	adminGroup.POST("/uploads", handlers.Upload.UploadFile)
+	adminOrders := staffGroup.Group("/orders")
+	adminOrders.GET("", can(model.PermissionOrdersRead), handlers.AdminOrder.ListOrders)
+	// 订单详情、时间线、支付与退款记录与顾客接口共用处理器，员工可查看任意订单以处理纠纷。
+	adminOrders.GET("/:id", can(model.PermissionOrdersRead), handlers.Order.GetOrder)
+	adminOrders.GET("/:id/timeline", can(model.PermissionOrdersRead), handlers.Order.GetTimeline)
+	adminOrders.GET("/:id/payments", can(model.PermissionPaymentsRead), handlers.Payment.ListOrderPayments)
+	adminOrders.GET("/:id/refunds", can(model.PermissionOrdersRead), handlers.Refund.ListRefunds)
+	adminOrders.POST("/:id/ship", can(model.PermissionOrdersShip), handlers.Order.ShipOrder)
+	adminOrders.POST("/:id/refunds", can(model.PermissionOrdersRefund), handlers.AdminOrder.RefundOrder)
+	adminOrders.POST("/:id/payments/confirm", can(model.PermissionPaymentsConfirm), handlers.AdminPayment.ConfirmOfflinePayment)
 
-	cartGroup := api.Group("/cart")
+	adminRefunds := staffGroup.Group("/refunds", can(model.PermissionOrdersRefund))
+	adminRefunds.GET("", handlers.AdminOrder.ListPendingRefunds)
+	adminRefunds.POST("/:id/approve", handlers.AdminOrder.ApproveRefund)
+	adminRefunds.POST("/:id/reject", handlers.AdminOrder.RejectRefund)
+
+	staffGroup.GET("/payments", can(model.PermissionPaymentsRead), handlers.AdminPayment.SearchPayments)
+
+	adminReconciliations := staffGroup.Group("/reconciliations", can(model.PermissionReconciliation))
+	adminReconciliations.GET("", handlers.Reconciliation.ListReports)
+	adminReconciliations.POST("", handlers.Reconciliation.Reconcile)
+	adminReconciliations.POST("/import", handlers.Reconciliation.ImportBill)
+	adminReconciliations.GET("/:id", handlers.Reconciliation.GetReport)
+	adminReconciliations.GET("/:id/export", handlers.Reconciliation.ExportReport)
+
+	adminStaff := staffGroup.Group("/staff", can(model.PermissionStaffManage))
+	adminStaff.GET("", handlers.Staff.ListStaff)
+	adminStaff.POST("", handlers.Staff.CreateStaff)
+	adminStaff.PUT("/:id", handlers.Staff.UpdateStaff)
+	adminStaff.PUT("/:id/password", handlers.Staff.ResetPassword)
+	adminStaff.GET("/:id/api-keys", handlers.Staff.ListAPIKeys)
+	adminStaff.POST("/:id/api-keys", handlers.Staff.CreateAPIKey)
+	adminStaff.DELETE("/:id/api-keys/:key_id", handlers.Staff.RevokeAPIKey)
+
+	cartGroup := api.Group("/cart", handlers.Auth.RequireUser)
 	cartGroup.GET("", handlers.Cart.ListItems)
 	cartGroup.POST("", handlers.Cart.AddItem)
 	cartGroup.PUT(":id", handlers.Cart.UpdateItem)
 	cartGroup.DELETE(":id", handlers.Cart.RemoveItem)
 
-	orderGroup := api.Group("/orders")
+	orderGroup := api.Group("/orders", handlers.Auth.RequireUser, handlers.Order.RequireOwnership)
+	orderGroup.GET("", handlers.Order.ListOrders)
 	orderGroup.POST("", handlers.Order.CreateOrder)
+	orderGroup.POST("/checkout", handlers.Order.Checkout)
 	orderGroup.GET(":id", handlers.Order.GetOrder)
+	orderGroup.GET(":id/timeline", handlers.Order.GetTimeline)
+	orderGroup.GET(":id/payments", handlers.Payment.ListOrderPayments)
+	orderGroup.POST(":id/payment/sync", handlers.Payment.SyncOrderPayment)
+	orderGroup.GET(":id/refunds", handlers.Refund.ListRefunds)
+	// 顾客只能提交退款申请，已发货订单须员工审核，直接退款仅限具备 orders:refund 权限的后台接口。
+	orderGroup.POST(":id/refunds", handlers.Refund.RequestRefund)
 	orderGroup.POST(":id/pay", handlers.Order.PayOrder)
 	orderGroup.POST(":id/cancel", handlers.Order.CancelOrder)
-	orderGroup.POST(":id/ship", handlers.Order.ShipOrder)
 	orderGroup.POST(":id/complete", handlers.Order.CompleteOrder)
 
 	paymentGroup := api.Group("/payments")
 	paymentGroup.POST("/wechat/callback", handlers.Payment.HandleWeChatCallback)
+	paymentGroup.POST("/wechat/refund-callback", handlers.Refund.HandleWeChatRefundCallback)
+	paymentGroup.POST("/alipay/callback", handlers.Payment.HandleAlipayCallback)
 
-	deliveryGroup := api.Group("/delivery")
+	deliveryGroup := api.Group("/delivery", handlers.Staff.RequireStaff, handlers.Staff.RequirePermission(model.PermissionDelivery))
 	deliveryGroup.POST("/bind-address", handlers.Delivery.BindAddress)
 	deliveryGroup.POST("/ship-order", handlers.Delivery.ShipOrder)
 }
+
+// RegisterMockPayment 挂载模拟支付网关，仅在 payment.mode 为 mock 时调用。
+func RegisterMockPayment(engine *gin.Engine, gateway http.Handler) {
+	engine.Any(payment.MockGatewayPath+"/*path", gin.WrapH(http.StripPrefix(payment.MockGatewayPath, gateway)))
+}